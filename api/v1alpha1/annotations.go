@@ -0,0 +1,80 @@
+/*
+BSD 2-Clause License
+
+Copyright (c) 2019, Tarantool
+All rights reserved.
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+1. Redistributions of source code must retain the above copyright notice, this
+   list of conditions and the following disclaimer.
+
+2. Redistributions in binary form must reproduce the above copyright notice,
+   this list of conditions and the following disclaimer in the documentation
+   and/or other materials provided with the distribution.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+*/
+
+package v1alpha1
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// Annotations of Roles, ReplicasetTemplates and the objects created from them
+const (
+	// RolesToAssignAnnotation holds the Cartridge roles of the replicasets, either a JSON string or a JSON array
+	RolesToAssignAnnotation = "tarantool.io/rolesToAssign"
+	// ReplicasetWeightAnnotation holds the vshard weight of the replicasets
+	ReplicasetWeightAnnotation = "tarantool.io/replicaset-weight"
+)
+
+// ParseRoles returns the Cartridge roles from the rolesToAssign annotation,
+// or from the label of the same name with roles separated by dots
+func ParseRoles(labels map[string]string, annotations map[string]string) ([]string, error) {
+	rolesFromAnnotations, ok := annotations[RolesToAssignAnnotation]
+	if !ok {
+		rolesFromLabels, ok := labels[RolesToAssignAnnotation]
+		if !ok {
+			return nil, errors.New("role undefined")
+		}
+
+		return strings.Split(rolesFromLabels, "."), nil
+	}
+
+	var singleRole string
+	if err := json.Unmarshal([]byte(rolesFromAnnotations), &singleRole); err == nil {
+		return []string{singleRole}, nil
+	}
+
+	var roleArray []string
+	if err := json.Unmarshal([]byte(rolesFromAnnotations), &roleArray); err == nil {
+		return roleArray, nil
+	}
+
+	return nil, errors.New("failed to parse roles from annotations")
+}
+
+// ParseReplicasetWeight returns the weight from the replicaset-weight annotation
+func ParseReplicasetWeight(weight string) (int, error) {
+	value, err := strconv.Atoi(weight)
+	if err != nil || value < 0 {
+		return 0, errors.New("must be a non-negative integer")
+	}
+
+	return value, nil
+}
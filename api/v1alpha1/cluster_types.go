@@ -29,12 +29,280 @@ OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
 package v1alpha1
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 // EDIT THIS FILE!  THIS IS SCAFFOLDING FOR YOU TO OWN!
 // NOTE: json tags are required.  Any new fields you add must have json tags for the fields to be serialized.
 
+const (
+	// DefaultClusterDomainName is the Kubernetes cluster domain used when none is specified
+	DefaultClusterDomainName = "cluster.local"
+	// DefaultBinaryPort is the Tarantool iproto port used when none is specified
+	DefaultBinaryPort int32 = 3301
+	// DefaultHTTPPort is the Cartridge HTTP (admin API) port used when none is specified
+	DefaultHTTPPort int32 = 8081
+	// DefaultBucketCount is the number of vshard buckets used when none is specified
+	DefaultBucketCount int32 = 30000
+	// DefaultDisbalanceThreshold is the percentage of misplaced buckets tolerated when none is specified
+	DefaultDisbalanceThreshold int32 = 1
+	// DefaultStateboardPort is the iproto port of a managed stateboard used when none is specified
+	DefaultStateboardPort int32 = 4401
+)
+
+// FailoverMode is a Cartridge failover mode
+// +kubebuilder:validation:Enum=disabled;eventual;stateful
+type FailoverMode string
+
+const (
+	// FailoverModeDisabled turns Cartridge failover off
+	FailoverModeDisabled FailoverMode = "disabled"
+	// FailoverModeEventual enables Cartridge eventual failover
+	FailoverModeEventual FailoverMode = "eventual"
+	// FailoverModeStateful enables Cartridge stateful failover, masters are appointed through a state provider
+	FailoverModeStateful FailoverMode = "stateful"
+)
+
+// FailoverStateProviderType is where the stateful failover keeps the appointed masters
+// +kubebuilder:validation:Enum=stateboard;etcd2
+type FailoverStateProviderType string
+
+const (
+	// FailoverStateProviderStateboard is a tarantool-stateboard instance
+	FailoverStateProviderStateboard FailoverStateProviderType = "stateboard"
+	// FailoverStateProviderEtcd2 is an etcd cluster talking the v2 API
+	FailoverStateProviderEtcd2 FailoverStateProviderType = "etcd2"
+)
+
+// Keys of the Secret referenced by Etcd2StateProviderSpec.CredentialsSecret
+const (
+	// Etcd2UsernameKey holds the name of an etcd user
+	Etcd2UsernameKey = "username"
+	// Etcd2PasswordKey holds the password of the etcd user
+	Etcd2PasswordKey = "password"
+)
+
+// FailoverSpec defines the failover configuration of the Cartridge cluster
+type FailoverSpec struct {
+	// Mode is a Cartridge failover mode
+	// +kubebuilder:default:=eventual
+	// +optional
+	Mode FailoverMode `json:"mode,omitempty"`
+
+	// TimeoutSeconds is how long an instance may be unreachable before failover treats it as dead,
+	// the Cartridge default is kept when zero
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	TimeoutSeconds int32 `json:"timeoutSeconds,omitempty"`
+
+	// Fencing makes a stateful master step down once it loses both the state provider and its replicas
+	// +optional
+	Fencing *FencingSpec `json:"fencing,omitempty"`
+
+	// StateProvider keeps the appointed masters of the stateful failover, required with the stateful mode
+	// +optional
+	StateProvider *FailoverStateProviderSpec `json:"stateProvider,omitempty"`
+}
+
+// FencingSpec defines the fencing of the stateful failover
+type FencingSpec struct {
+	// Enabled turns fencing on
+	// +optional
+	Enabled bool `json:"enabled,omitempty"`
+
+	// TimeoutSeconds is how long a master may stay without quorum before it steps down,
+	// the Cartridge default is kept when zero
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	TimeoutSeconds int32 `json:"timeoutSeconds,omitempty"`
+
+	// PauseSeconds is the period of the quorum checks, the Cartridge default is kept when zero
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	PauseSeconds int32 `json:"pauseSeconds,omitempty"`
+}
+
+// FailoverStateProviderSpec defines the state provider of the stateful failover
+type FailoverStateProviderSpec struct {
+	// Type is the kind of the state provider, its settings are taken from the field of the same name
+	Type FailoverStateProviderType `json:"type"`
+
+	// Stateboard is a tarantool-stateboard instance
+	// +optional
+	Stateboard *StateboardSpec `json:"stateboard,omitempty"`
+
+	// Etcd2 is an etcd cluster
+	// +optional
+	Etcd2 *Etcd2StateProviderSpec `json:"etcd2,omitempty"`
+}
+
+// StateboardSpec defines how instances reach the stateboard
+type StateboardSpec struct {
+	// URI is the iproto address of the stateboard, e.g. stateboard.tarantool.svc.cluster.local:4401
+	// +optional
+	URI string `json:"uri,omitempty"`
+
+	// PasswordSecret selects the stateboard password in a Secret of the Cluster namespace,
+	// a managed stateboard gets a generated password when it is not set
+	// +optional
+	PasswordSecret *corev1.SecretKeySelector `json:"passwordSecret,omitempty"`
+
+	// Managed makes the operator run the stateboard next to the Cluster, the URI is then its Service address
+	// +optional
+	Managed *ManagedStateboardSpec `json:"managed,omitempty"`
+}
+
+// ManagedStateboardSpec defines the stateboard Deployment created by the operator
+type ManagedStateboardSpec struct {
+	// Image is an image of the Cartridge application, the stateboard is run from it
+	Image string `json:"image"`
+
+	// Command starts the stateboard, tarantool stateboard.init.lua by default
+	// +optional
+	Command []string `json:"command,omitempty"`
+
+	// Port is the iproto port of the stateboard
+	// +kubebuilder:default:=4401
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	// +optional
+	Port int32 `json:"port,omitempty"`
+
+	// Resources of the stateboard container
+	// +optional
+	Resources corev1.ResourceRequirements `json:"resources,omitempty"`
+}
+
+// Etcd2StateProviderSpec defines how instances reach etcd
+type Etcd2StateProviderSpec struct {
+	// Endpoints are the URLs of the etcd cluster members
+	// +kubebuilder:validation:MinItems=1
+	Endpoints []string `json:"endpoints"`
+
+	// Prefix is the etcd key prefix of the cluster, the Cartridge default is kept when empty
+	// +optional
+	Prefix string `json:"prefix,omitempty"`
+
+	// LockDelaySeconds is how long the lock of a lost failover coordinator is kept,
+	// the Cartridge default is kept when zero
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	LockDelaySeconds int32 `json:"lockDelaySeconds,omitempty"`
+
+	// CredentialsSecret references a Secret in the Cluster namespace holding username and password keys
+	// +optional
+	CredentialsSecret *corev1.LocalObjectReference `json:"credentialsSecret,omitempty"`
+}
+
+// AdminAPIScheme is the protocol the Cartridge admin API is served over
+// +kubebuilder:validation:Enum=http;https
+type AdminAPIScheme string
+
+const (
+	// AdminAPISchemeHTTP is plain http
+	AdminAPISchemeHTTP AdminAPIScheme = "http"
+	// AdminAPISchemeHTTPS is http over TLS
+	AdminAPISchemeHTTPS AdminAPIScheme = "https"
+)
+
+// Keys of the Secret referenced by AdminAPISpec.CredentialsSecret
+const (
+	// AdminAPIUsernameKey holds the name of a Cartridge user
+	AdminAPIUsernameKey = "username"
+	// AdminAPIPasswordKey holds the password of the Cartridge user
+	AdminAPIPasswordKey = "password"
+	// AdminAPICookieKey holds a session cookie sent instead of username and password, e.g. lsid=...
+	AdminAPICookieKey = "cookie"
+)
+
+// AdminAPISpec defines how the operator connects to the Cartridge admin API
+type AdminAPISpec struct {
+	// Scheme is the protocol the admin API is served over
+	// +kubebuilder:default:=http
+	// +optional
+	Scheme AdminAPIScheme `json:"scheme,omitempty"`
+
+	// CredentialsSecret references a Secret in the Cluster namespace holding either
+	// username and password keys or a cookie key, required when Cartridge auth is enabled
+	// +optional
+	CredentialsSecret *corev1.LocalObjectReference `json:"credentialsSecret,omitempty"`
+
+	// TLS configures https connections to the admin API
+	// +optional
+	TLS *AdminAPITLSSpec `json:"tls,omitempty"`
+}
+
+// AdminAPITLSSpec defines how the operator verifies instances and authenticates itself over TLS
+type AdminAPITLSSpec struct {
+	// CASecret selects a CA bundle in a Secret of the Cluster namespace, system roots are used when no CA is set
+	// +optional
+	CASecret *corev1.SecretKeySelector `json:"caSecret,omitempty"`
+
+	// CAConfigMap selects a CA bundle in a ConfigMap of the Cluster namespace
+	// +optional
+	CAConfigMap *corev1.ConfigMapKeySelector `json:"caConfigMap,omitempty"`
+
+	// ClientCertSecret references a kubernetes.io/tls Secret with the client certificate and key used for mTLS
+	// +optional
+	ClientCertSecret *corev1.LocalObjectReference `json:"clientCertSecret,omitempty"`
+
+	// ServerName is verified against instance certificates, instances are dialed by pod IP
+	// +optional
+	ServerName string `json:"serverName,omitempty"`
+
+	// InsecureSkipVerify disables verification of instance certificates
+	// +optional
+	InsecureSkipVerify bool `json:"insecureSkipVerify,omitempty"`
+}
+
+// RebalancingSpec defines how the operator changes replicaset weights
+type RebalancingSpec struct {
+	// WeightStep limits how much a replicaset weight changes at once, the next step is taken when buckets reach
+	// the distribution implied by the current weights. 0 applies the desired weight right away.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	WeightStep int32 `json:"weightStep,omitempty"`
+
+	// DisbalanceThreshold is the percentage of misplaced buckets at which the cluster is considered balanced
+	// +kubebuilder:default:=1
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=100
+	// +optional
+	DisbalanceThreshold int32 `json:"disbalanceThreshold,omitempty"`
+}
+
+// DeletionPolicy decides what happens to the instances and the data of a deleted Cluster
+// +kubebuilder:validation:Enum=Delete;Retain;SnapshotThenDelete
+type DeletionPolicy string
+
+const (
+	// DeletionPolicyDelete removes the Roles and StatefulSets in order, volumes are removed with DeleteVolumes
+	DeletionPolicyDelete DeletionPolicy = "Delete"
+	// DeletionPolicyRetain orphans the data volumes labeled with the cluster, replicaset and instance they belonged to,
+	// so a Cluster created again with the same name adopts them
+	DeletionPolicyRetain DeletionPolicy = "Retain"
+	// DeletionPolicySnapshotThenDelete takes a VolumeSnapshot of every data volume before the volumes are removed
+	DeletionPolicySnapshotThenDelete DeletionPolicy = "SnapshotThenDelete"
+)
+
+// DeletionSpec defines how a deleted Cluster is torn down
+type DeletionSpec struct {
+	// Policy decides what happens to the instances and the data of the Cluster
+	// +kubebuilder:default:=Delete
+	// +optional
+	Policy DeletionPolicy `json:"policy,omitempty"`
+
+	// DeleteVolumes removes the data volumes with the Delete policy, they are kept by default
+	// +optional
+	DeleteVolumes bool `json:"deleteVolumes,omitempty"`
+
+	// VolumeSnapshotClassName is the class of the snapshots taken with the SnapshotThenDelete policy,
+	// the default class of the CSI driver is used when empty
+	// +optional
+	VolumeSnapshotClassName string `json:"volumeSnapshotClassName,omitempty"`
+}
+
 // ClusterSpec defines the desired state of Cluster
 // +k8s:openapi-gen=true
 type ClusterSpec struct {
@@ -42,6 +310,207 @@ type ClusterSpec struct {
 	// Important: Run "operator-sdk generate k8s" to regenerate code after modifying this file
 	// Add custom validation using kubebuilder tags: https://book-v1.book.kubebuilder.io/beyond_basics/generating_crd.html
 	Selector *metav1.LabelSelector `json:"selector,omitempty"`
+
+	// ClusterDomainName is the Kubernetes cluster domain used to build instance advertise URIs.
+	// It cannot be changed after the Cluster is created.
+	// +kubebuilder:default:="cluster.local"
+	// +kubebuilder:validation:MinLength=1
+	// +optional
+	ClusterDomainName string `json:"clusterDomainName,omitempty"`
+
+	// BinaryPort is the Tarantool iproto port instances listen on.
+	// It cannot be changed after the Cluster is created.
+	// +kubebuilder:default:=3301
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	// +optional
+	BinaryPort int32 `json:"binaryPort,omitempty"`
+
+	// HTTPPort is the Cartridge HTTP port serving the admin API
+	// +kubebuilder:default:=8081
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	// +optional
+	HTTPPort int32 `json:"httpPort,omitempty"`
+
+	// AdminAPI configures connections to the Cartridge admin API
+	// +optional
+	AdminAPI *AdminAPISpec `json:"adminAPI,omitempty"`
+
+	// Failover is the Cartridge failover configuration
+	// +optional
+	Failover *FailoverSpec `json:"failover,omitempty"`
+
+	// VshardGroups is a list of vshard group names, instances are joined to the group named after their Role.
+	// When empty, all storages belong to the single "default" group. It cannot be changed after the Cluster is created.
+	// +optional
+	VshardGroups []string `json:"vshardGroups,omitempty"`
+
+	// BucketCount is the number of vshard buckets passed to instances as TARANTOOL_BUCKET_COUNT.
+	// It cannot be changed after the Cluster is created.
+	// +kubebuilder:default:=30000
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	BucketCount int32 `json:"bucketCount,omitempty"`
+
+	// Rebalancing configures how vshard buckets are moved between replicasets
+	// +optional
+	Rebalancing *RebalancingSpec `json:"rebalancing,omitempty"`
+
+	// Deletion configures the teardown of the Cluster once it is deleted
+	// +optional
+	Deletion *DeletionSpec `json:"deletion,omitempty"`
+
+	// Import adopts a Cartridge cluster bootstrapped without the operator: the uuids of its replicasets and
+	// instances are read from the topology and put on the StatefulSets and pods instead of being generated,
+	// so nothing is joined or bootstrapped again
+	// +optional
+	Import bool `json:"import,omitempty"`
+
+	// ServerLabels are the names of pod labels copied to the Cartridge labels of the instances.
+	// Instances also get the zone of the node their pod runs on (the topology.kubernetes.io/zone node label),
+	// so vshard routers prefer the nearest replicas.
+	// +optional
+	ServerLabels []string `json:"serverLabels,omitempty"`
+}
+
+// Cluster condition types
+const (
+	// ClusterTopologyReachable means the Cartridge admin API of the cluster leader answers
+	ClusterTopologyReachable = "TopologyReachable"
+	// ClusterTopologyError means Cartridge refused the last topology operation, retrying it will not help
+	ClusterTopologyError = "TopologyError"
+	// ClusterAllInstancesJoined means every pod of every replicaset is joined to the topology
+	ClusterAllInstancesJoined = "AllInstancesJoined"
+	// ClusterVshardBootstrapped means vshard was bootstrapped on the cluster
+	ClusterVshardBootstrapped = "VshardBootstrapped"
+	// ClusterFailoverConfigured means the Cartridge failover settings match the requested ones
+	ClusterFailoverConfigured = "FailoverConfigured"
+	// ClusterDegraded means some part of the cluster is not healthy
+	ClusterDegraded = "Degraded"
+	// ClusterRebalancing means vshard buckets are being moved to reach the distribution implied by weights
+	ClusterRebalancing = "Rebalancing"
+	// ClusterRollingUpdate means some instances run an outdated pod template and are being restarted
+	ClusterRollingUpdate = "RollingUpdate"
+	// ClusterRollingUpdatePaused means the rolling update was stopped after an instance failed to become healthy
+	ClusterRollingUpdatePaused = "RollingUpdatePaused"
+	// ClusterStateboardReady means the stateboard run by the operator is available
+	ClusterStateboardReady = "StateboardReady"
+	// ClusterTopologyImported means every replicaset of an adopted cluster was matched against its topology
+	ClusterTopologyImported = "TopologyImported"
+)
+
+// Cluster states
+const (
+	ClusterStatePending  = "Pending"
+	ClusterStateReady    = "Ready"
+	ClusterStateDegraded = "Degraded"
+	ClusterStateDeleting = "Deleting"
+)
+
+// DeletionPhase is a step of the Cluster teardown
+type DeletionPhase string
+
+// Cluster teardown phases, in order
+const (
+	// DeletionPhaseReleasingInstances labels the data volumes and releases the instance pods
+	DeletionPhaseReleasingInstances DeletionPhase = "ReleasingInstances"
+	// DeletionPhaseDeletingReplicasets removes the Roles and StatefulSets and waits for the pods to stop
+	DeletionPhaseDeletingReplicasets DeletionPhase = "DeletingReplicasets"
+	// DeletionPhaseSnapshottingVolumes waits for the snapshots of the data volumes to be ready
+	DeletionPhaseSnapshottingVolumes DeletionPhase = "SnapshottingVolumes"
+	// DeletionPhaseDeletingVolumes removes the data volumes
+	DeletionPhaseDeletingVolumes DeletionPhase = "DeletingVolumes"
+	// DeletionPhaseCompleted means nothing is left to tear down, the Cluster is about to disappear
+	DeletionPhaseCompleted DeletionPhase = "Completed"
+)
+
+// ReplicasetStatus is the observed state of a single Tarantool replicaset
+type ReplicasetStatus struct {
+	// Name is the name of the StatefulSet backing the replicaset
+	Name string `json:"name"`
+	// UUID is the Cartridge replicaset uuid
+	UUID string `json:"uuid"`
+	// Roles are the Cartridge roles enabled on the replicaset
+	// +optional
+	Roles []string `json:"roles,omitempty"`
+	// Weight is the vshard weight of the replicaset, absent for non-storage replicasets
+	// +optional
+	Weight *int32 `json:"weight,omitempty"`
+	// BucketsCount is the number of vshard buckets stored on the replicaset
+	BucketsCount int32 `json:"bucketsCount"`
+	// TargetBucketsCount is the number of buckets the replicaset will store once rebalancing is over
+	// +optional
+	TargetBucketsCount int32 `json:"targetBucketsCount,omitempty"`
+	// JoinedInstances is the number of instances joined to the topology
+	JoinedInstances int32 `json:"joinedInstances"`
+	// TotalInstances is the number of instances the replicaset is expected to have
+	TotalInstances int32 `json:"totalInstances"`
+	// UpdatedInstances is the number of instances running the latest pod template
+	UpdatedInstances int32 `json:"updatedInstances"`
+	// ExpellingInstances is the number of instances being expelled before the replicaset is scaled down
+	// +optional
+	ExpellingInstances int32 `json:"expellingInstances,omitempty"`
+	// Master is the pod currently acting as the replicaset master
+	// +optional
+	Master string `json:"master,omitempty"`
+	// LastSwitchover is the outcome of the last master switch requested with the tarantool.io/switchover annotation
+	// +optional
+	LastSwitchover *SwitchoverStatus `json:"lastSwitchover,omitempty"`
+}
+
+// SwitchoverStatus is the outcome of a requested master switch
+type SwitchoverStatus struct {
+	// Target is the pod requested to become the master
+	Target string `json:"target"`
+	// Result is either Succeeded or Failed
+	Result string `json:"result"`
+	// Message explains the result
+	// +optional
+	Message string `json:"message,omitempty"`
+	// Finished is when the switchover succeeded or failed
+	Finished metav1.Time `json:"finished"`
+}
+
+// RebalancingStatus is the progress of moving vshard buckets between replicasets
+type RebalancingStatus struct {
+	// TotalBuckets is the number of buckets in the cluster
+	TotalBuckets int32 `json:"totalBuckets"`
+	// MisplacedBuckets is the number of buckets to be moved to reach the distribution implied by weights
+	MisplacedBuckets int32 `json:"misplacedBuckets"`
+	// Progress is the percentage of buckets stored on their target replicasets
+	Progress int32 `json:"progress"`
+}
+
+// LeaderStatus is the instance the operator sends topology requests to
+type LeaderStatus struct {
+	// Address is the host:port of the leader admin API
+	Address string `json:"address"`
+	// Pod is the name of the leader pod
+	// +optional
+	Pod string `json:"pod,omitempty"`
+	// Reason explains why the instance was chosen
+	Reason string `json:"reason"`
+	// LastTransitionTime is when the leader last changed
+	// +optional
+	LastTransitionTime metav1.Time `json:"lastTransitionTime,omitempty"`
+}
+
+// DeletionStatus is the progress of the Cluster teardown
+type DeletionStatus struct {
+	// Policy is the deletion policy the teardown follows
+	Policy DeletionPolicy `json:"policy"`
+	// Phase is the current step of the teardown
+	Phase DeletionPhase `json:"phase"`
+	// Message describes what the teardown is waiting for
+	// +optional
+	Message string `json:"message,omitempty"`
+	// Volumes are the data volumes of the Cluster
+	// +optional
+	Volumes []string `json:"volumes,omitempty"`
+	// Snapshots are the VolumeSnapshots taken of the data volumes
+	// +optional
+	Snapshots []string `json:"snapshots,omitempty"`
 }
 
 // ClusterStatus defines the observed state of Cluster
@@ -51,6 +520,47 @@ type ClusterStatus struct {
 	// Important: Run "operator-sdk generate k8s" to regenerate code after modifying this file
 	// Add custom validation using kubebuilder tags: https://book-v1.book.kubebuilder.io/beyond_basics/generating_crd.html
 	State string `json:"state,omitempty"`
+
+	// ObservedGeneration is the most recent Cluster generation observed by the operator
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Conditions describe the current health of the cluster
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+
+	// Leader is the instance serving topology requests, absent while no instance answers
+	// +optional
+	Leader *LeaderStatus `json:"leader,omitempty"`
+
+	// Replicasets is the observed state of every replicaset of the cluster
+	// +optional
+	Replicasets []ReplicasetStatus `json:"replicasets,omitempty"`
+
+	// Rebalancing is the progress of moving vshard buckets, absent until vshard is bootstrapped
+	// +optional
+	Rebalancing *RebalancingStatus `json:"rebalancing,omitempty"`
+
+	// Deletion is the progress of the teardown, absent until the Cluster is deleted
+	// +optional
+	Deletion *DeletionStatus `json:"deletion,omitempty"`
+
+	// Stateboard is the observed state of the managed stateboard, absent when the operator runs none
+	// +optional
+	Stateboard *StateboardStatus `json:"stateboard,omitempty"`
+}
+
+// StateboardStatus is the observed state of the managed stateboard
+type StateboardStatus struct {
+	// URI is the address instances reach the stateboard at
+	URI string `json:"uri"`
+	// Ready means the stateboard pod is available
+	Ready bool `json:"ready"`
+	// Message explains why the stateboard is not ready
+	// +optional
+	Message string `json:"message,omitempty"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -59,6 +569,7 @@ type ClusterStatus struct {
 //+kubebuilder:object:root=true
 //+kubebuilder:subresource:status
 // +kubebuilder:printcolumn:name="State",type="string",JSONPath=".status.state",priority=0
+// +kubebuilder:printcolumn:name="Leader",type="string",JSONPath=".status.leader.pod",priority=1
 // +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
 type Cluster struct {
 	metav1.TypeMeta   `json:",inline"`
@@ -0,0 +1,366 @@
+/*
+BSD 2-Clause License
+
+Copyright (c) 2019, Tarantool
+All rights reserved.
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+1. Redistributions of source code must retain the above copyright notice, this
+   list of conditions and the following disclaimer.
+
+2. Redistributions in binary form must reproduce the above copyright notice,
+   this list of conditions and the following disclaimer in the documentation
+   and/or other materials provided with the distribution.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strconv"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+// log is for logging in this package.
+var clusterlog = logf.Log.WithName("cluster-resource")
+
+// clusterReader is used to look up other Clusters when validating selectors
+var clusterReader client.Reader
+
+// SetupWebhookWithManager registers the Cluster webhooks in the manager
+func (c *Cluster) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	clusterReader = mgr.GetClient()
+
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(c).
+		Complete()
+}
+
+//+kubebuilder:webhook:path=/mutate-tarantool-io-v1alpha1-cluster,mutating=true,failurePolicy=fail,sideEffects=None,groups=tarantool.io,resources=clusters,verbs=create;update,versions=v1alpha1,name=mcluster.kb.io,admissionReviewVersions=v1
+
+var _ webhook.Defaulter = &Cluster{}
+
+// Default fills in the spec fields omitted by the user
+func (c *Cluster) Default() {
+	if c.Spec.Selector == nil {
+		c.Spec.Selector = &metav1.LabelSelector{
+			MatchLabels: map[string]string{
+				"tarantool.io/cluster-id": c.GetName(),
+			},
+		}
+	}
+	if c.Spec.ClusterDomainName == "" {
+		c.Spec.ClusterDomainName = DefaultClusterDomainName
+	}
+	if c.Spec.BinaryPort == 0 {
+		c.Spec.BinaryPort = DefaultBinaryPort
+	}
+	if c.Spec.HTTPPort == 0 {
+		c.Spec.HTTPPort = DefaultHTTPPort
+	}
+	if c.Spec.BucketCount == 0 {
+		c.Spec.BucketCount = DefaultBucketCount
+	}
+	if c.Spec.AdminAPI == nil {
+		c.Spec.AdminAPI = &AdminAPISpec{}
+	}
+	if c.Spec.AdminAPI.Scheme == "" {
+		c.Spec.AdminAPI.Scheme = AdminAPISchemeHTTP
+	}
+	if c.Spec.Failover == nil {
+		c.Spec.Failover = &FailoverSpec{}
+	}
+	if c.Spec.Failover.Mode == "" {
+		c.Spec.Failover.Mode = FailoverModeEventual
+	}
+	if provider := c.Spec.Failover.StateProvider; provider != nil && provider.Stateboard != nil && provider.Stateboard.Managed != nil {
+		if provider.Stateboard.Managed.Port == 0 {
+			provider.Stateboard.Managed.Port = DefaultStateboardPort
+		}
+	}
+	if c.Spec.Rebalancing == nil {
+		c.Spec.Rebalancing = &RebalancingSpec{DisbalanceThreshold: DefaultDisbalanceThreshold}
+	}
+	if c.Spec.Deletion == nil {
+		c.Spec.Deletion = &DeletionSpec{}
+	}
+	if c.Spec.Deletion.Policy == "" {
+		c.Spec.Deletion.Policy = DeletionPolicyDelete
+	}
+}
+
+//+kubebuilder:webhook:path=/validate-tarantool-io-v1alpha1-cluster,mutating=false,failurePolicy=fail,sideEffects=None,groups=tarantool.io,resources=clusters,verbs=create;update,versions=v1alpha1,name=vcluster.kb.io,admissionReviewVersions=v1
+
+var _ webhook.Validator = &Cluster{}
+
+// ValidateCreate implements webhook.Validator so a webhook will be registered for the type
+func (c *Cluster) ValidateCreate() error {
+	clusterlog.Info("validate create", "name", c.GetName())
+
+	return c.validateCluster(nil)
+}
+
+// ValidateUpdate implements webhook.Validator so a webhook will be registered for the type
+func (c *Cluster) ValidateUpdate(old runtime.Object) error {
+	clusterlog.Info("validate update", "name", c.GetName())
+
+	oldCluster, ok := old.(*Cluster)
+	if !ok {
+		return fmt.Errorf("expected a Cluster but got a %T", old)
+	}
+
+	return c.validateCluster(c.validateImmutable(oldCluster))
+}
+
+// ValidateDelete implements webhook.Validator so a webhook will be registered for the type
+func (c *Cluster) ValidateDelete() error {
+	return nil
+}
+
+func (c *Cluster) validateCluster(allErrs field.ErrorList) error {
+	allErrs = append(allErrs, c.validateSelector()...)
+	allErrs = append(allErrs, c.validateAdminAPI()...)
+	allErrs = append(allErrs, c.validateFailover()...)
+	allErrs = append(allErrs, c.validateDeletion()...)
+	if len(allErrs) == 0 {
+		return nil
+	}
+
+	return apierrors.NewInvalid(GroupVersion.WithKind("Cluster").GroupKind(), c.GetName(), allErrs)
+}
+
+// validateImmutable checks that the fields Cartridge and vshard keep since bootstrap are not changed.
+// They are fixed from the creation of the Cluster: instances may be joined and vshard bootstrapped
+// before the status reports it.
+func (c *Cluster) validateImmutable(old *Cluster) field.ErrorList {
+	allErrs := field.ErrorList{}
+	specPath := field.NewPath("spec")
+
+	if c.Spec.BucketCount != old.Spec.BucketCount {
+		allErrs = append(allErrs, field.Forbidden(specPath.Child("bucketCount"), "bucketCount cannot be changed after the Cluster is created"))
+	}
+	if c.Spec.BinaryPort != old.Spec.BinaryPort {
+		allErrs = append(allErrs, field.Forbidden(specPath.Child("binaryPort"), "binaryPort cannot be changed after the Cluster is created, instances are joined by their advertise URIs"))
+	}
+	if c.Spec.ClusterDomainName != old.Spec.ClusterDomainName {
+		allErrs = append(allErrs, field.Forbidden(specPath.Child("clusterDomainName"), "clusterDomainName cannot be changed after the Cluster is created, instances are joined by their advertise URIs"))
+	}
+	if !reflect.DeepEqual(c.Spec.VshardGroups, old.Spec.VshardGroups) {
+		allErrs = append(allErrs, field.Forbidden(specPath.Child("vshardGroups"), "vshardGroups cannot be changed after the Cluster is created"))
+	}
+
+	return allErrs
+}
+
+// validateSelector checks that the Cluster selects its Roles without taking over Roles of other Clusters
+func (c *Cluster) validateSelector() field.ErrorList {
+	selectorPath := field.NewPath("spec", "selector")
+
+	if c.Spec.Selector == nil {
+		return field.ErrorList{field.Required(selectorPath, "")}
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(c.Spec.Selector)
+	if err != nil {
+		return field.ErrorList{field.Invalid(selectorPath, c.Spec.Selector, err.Error())}
+	}
+	if selector.Empty() {
+		return field.ErrorList{field.Invalid(selectorPath, c.Spec.Selector, "selector must not match every Role")}
+	}
+
+	if clusterReader == nil {
+		return nil
+	}
+
+	clusterList := &ClusterList{}
+	if err := clusterReader.List(context.TODO(), clusterList, client.InNamespace(c.GetNamespace())); err != nil {
+		return field.ErrorList{field.InternalError(selectorPath, err)}
+	}
+
+	allErrs := field.ErrorList{}
+	for _, other := range clusterList.Items {
+		if other.GetName() == c.GetName() || other.Spec.Selector == nil {
+			continue
+		}
+
+		otherSelector, err := metav1.LabelSelectorAsSelector(other.Spec.Selector)
+		if err != nil {
+			continue
+		}
+
+		if selectorsOverlap(selector, otherSelector) {
+			allErrs = append(allErrs, field.Invalid(selectorPath, c.Spec.Selector,
+				fmt.Sprintf("selector overlaps with Cluster %s", other.GetName())))
+		}
+	}
+
+	return allErrs
+}
+
+// selectorsOverlap reports whether some set of labels is matched by both selectors.
+// Requirements on different keys are independent, so it is enough to find a value satisfying
+// the requirements of both selectors for every key, the absent label included.
+func selectorsOverlap(a, b labels.Selector) bool {
+	aRequirements, _ := a.Requirements()
+	bRequirements, _ := b.Requirements()
+
+	byKey := make(map[string][]labels.Requirement)
+	for _, requirement := range append(aRequirements, bRequirements...) {
+		byKey[requirement.Key()] = append(byKey[requirement.Key()], requirement)
+	}
+
+	for key, requirements := range byKey {
+		if !requirementsSatisfiable(key, requirements) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// requirementsSatisfiable reports whether one value of the label, or its absence, satisfies all requirements
+func requirementsSatisfiable(key string, requirements []labels.Requirement) bool {
+	// candidates are the absent label, a value no requirement mentions (label values never contain NUL),
+	// the values the requirements mention and integers around them, enough to skip every excluded value
+	candidates := []labels.Set{{}, {key: "\x00"}}
+	for _, requirement := range requirements {
+		for _, value := range requirement.Values().List() {
+			candidates = append(candidates, labels.Set{key: value})
+
+			bound, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				continue
+			}
+			for i := int64(0); i <= int64(len(requirements)); i++ {
+				candidates = append(candidates,
+					labels.Set{key: strconv.FormatInt(bound+1+i, 10)},
+					labels.Set{key: strconv.FormatInt(bound-1-i, 10)})
+			}
+		}
+	}
+
+	for _, candidate := range candidates {
+		matched := true
+		for _, requirement := range requirements {
+			if !requirement.Matches(candidate) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+
+	return false
+}
+
+// validateAdminAPI checks that TLS settings are only given for https and the CA comes from a single source
+func (c *Cluster) validateAdminAPI() field.ErrorList {
+	adminAPI := c.Spec.AdminAPI
+	if adminAPI == nil || adminAPI.TLS == nil {
+		return nil
+	}
+
+	allErrs := field.ErrorList{}
+	tlsPath := field.NewPath("spec", "adminAPI", "tls")
+
+	if adminAPI.Scheme != AdminAPISchemeHTTPS {
+		allErrs = append(allErrs, field.Invalid(field.NewPath("spec", "adminAPI", "scheme"), adminAPI.Scheme,
+			"tls requires the https scheme"))
+	}
+	if adminAPI.TLS.CASecret != nil && adminAPI.TLS.CAConfigMap != nil {
+		allErrs = append(allErrs, field.Forbidden(tlsPath.Child("caConfigMap"), "caSecret and caConfigMap are mutually exclusive"))
+	}
+
+	return allErrs
+}
+
+// validateFailover checks that the stateful failover names a configured state provider and fencing is only
+// enabled for it
+func (c *Cluster) validateFailover() field.ErrorList {
+	failover := c.Spec.Failover
+	if failover == nil {
+		return nil
+	}
+
+	allErrs := field.ErrorList{}
+	failoverPath := field.NewPath("spec", "failover")
+
+	if failover.Fencing != nil && failover.Fencing.Enabled && failover.Mode != FailoverModeStateful {
+		allErrs = append(allErrs, field.Invalid(failoverPath.Child("fencing", "enabled"), failover.Fencing.Enabled,
+			"fencing is only supported by the stateful failover"))
+	}
+
+	provider := failover.StateProvider
+	providerPath := failoverPath.Child("stateProvider")
+	if provider == nil {
+		if failover.Mode == FailoverModeStateful {
+			allErrs = append(allErrs, field.Required(providerPath, "stateful failover requires a state provider"))
+		}
+		return allErrs
+	}
+
+	switch provider.Type {
+	case FailoverStateProviderStateboard:
+		stateboardPath := providerPath.Child("stateboard")
+		switch {
+		case provider.Stateboard == nil || (provider.Stateboard.URI == "" && provider.Stateboard.Managed == nil):
+			allErrs = append(allErrs, field.Required(stateboardPath.Child("uri"), "the stateboard state provider needs its URI or a managed stateboard"))
+		case provider.Stateboard.URI != "" && provider.Stateboard.Managed != nil:
+			allErrs = append(allErrs, field.Forbidden(stateboardPath.Child("uri"), "the URI of a managed stateboard is set by the operator"))
+		}
+	case FailoverStateProviderEtcd2:
+		if provider.Etcd2 == nil || len(provider.Etcd2.Endpoints) == 0 {
+			allErrs = append(allErrs, field.Required(providerPath.Child("etcd2", "endpoints"), "the etcd2 state provider needs its endpoints"))
+		}
+	}
+
+	return allErrs
+}
+
+// validateDeletion checks that the teardown settings match the deletion policy
+func (c *Cluster) validateDeletion() field.ErrorList {
+	deletion := c.Spec.Deletion
+	if deletion == nil {
+		return nil
+	}
+
+	allErrs := field.ErrorList{}
+	deletionPath := field.NewPath("spec", "deletion")
+
+	if deletion.DeleteVolumes && deletion.Policy != DeletionPolicyDelete {
+		allErrs = append(allErrs, field.Invalid(deletionPath.Child("deleteVolumes"), deletion.DeleteVolumes,
+			"volumes are only deleted on demand with the Delete policy"))
+	}
+	if deletion.VolumeSnapshotClassName != "" && deletion.Policy != DeletionPolicySnapshotThenDelete {
+		allErrs = append(allErrs, field.Invalid(deletionPath.Child("volumeSnapshotClassName"), deletion.VolumeSnapshotClassName,
+			"snapshots are only taken with the SnapshotThenDelete policy"))
+	}
+
+	return allErrs
+}
@@ -42,14 +42,37 @@ type ReplicasetTemplateSpec struct {
 	// Important: Run "make" to regenerate code after modifying this file
 }
 
+// ReplicasetTemplateValid is a condition type set when the template passes validation
+const ReplicasetTemplateValid = "Valid"
+
 // ReplicasetTemplateStatus defines the observed state of ReplicasetTemplate
 type ReplicasetTemplateStatus struct {
 	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
 	// Important: Run "make" to regenerate code after modifying this file
+
+	// ObservedGeneration is the most recent template generation observed by the operator
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Valid is true when StatefulSets can be created from the template
+	Valid bool `json:"valid"`
+
+	// UsedBy is a list of Roles selecting the template
+	// +optional
+	UsedBy []string `json:"usedBy,omitempty"`
+
+	// Conditions describe the validation result
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
 }
 
 //+kubebuilder:object:root=true
 //+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Valid",type="boolean",JSONPath=".status.valid"
+//+kubebuilder:printcolumn:name="Used By",type="string",JSONPath=".status.usedBy"
+//+kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
 
 // ReplicasetTemplate is the Schema for the replicasettemplates API
 type ReplicasetTemplate struct {
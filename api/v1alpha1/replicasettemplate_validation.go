@@ -0,0 +1,94 @@
+/*
+BSD 2-Clause License
+
+Copyright (c) 2019, Tarantool
+All rights reserved.
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+1. Redistributions of source code must retain the above copyright notice, this
+   list of conditions and the following disclaimer.
+
+2. Redistributions in binary form must reproduce the above copyright notice,
+   this list of conditions and the following disclaimer in the documentation
+   and/or other materials provided with the distribution.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+*/
+
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// ValidateTemplate checks that StatefulSets can be created from the template
+func (t *ReplicasetTemplate) ValidateTemplate() field.ErrorList {
+	allErrs := field.ErrorList{}
+
+	if _, err := ParseRoles(t.GetLabels(), t.GetAnnotations()); err != nil {
+		allErrs = append(allErrs, field.Invalid(
+			field.NewPath("metadata", "annotations").Key(RolesToAssignAnnotation),
+			t.GetAnnotations()[RolesToAssignAnnotation],
+			err.Error(),
+		))
+	}
+
+	specPath := field.NewPath("spec")
+	if t.Spec == nil {
+		return append(allErrs, field.Required(specPath, "template must have a StatefulSet spec"))
+	}
+
+	// the labels of the Role and the replicaset are added to the pod template labels
+	if len(t.Spec.Template.GetLabels()) == 0 {
+		allErrs = append(allErrs, field.Required(specPath.Child("template", "metadata", "labels"), "pod template must have labels"))
+	}
+
+	containersPath := specPath.Child("template", "spec", "containers")
+	if len(t.Spec.Template.Spec.Containers) == 0 {
+		allErrs = append(allErrs, field.Required(containersPath, "at least one container is required"))
+	}
+
+	claimNames := make(map[string]bool)
+	for i, claim := range t.Spec.VolumeClaimTemplates {
+		claimPath := specPath.Child("volumeClaimTemplates").Index(i)
+
+		name := claim.GetName()
+		if name == "" {
+			allErrs = append(allErrs, field.Required(claimPath.Child("metadata", "name"), ""))
+		} else {
+			for _, msg := range validation.IsDNS1123Label(name) {
+				allErrs = append(allErrs, field.Invalid(claimPath.Child("metadata", "name"), name, msg))
+			}
+			if claimNames[name] {
+				allErrs = append(allErrs, field.Duplicate(claimPath.Child("metadata", "name"), name))
+			}
+			claimNames[name] = true
+		}
+
+		if len(claim.Spec.AccessModes) == 0 {
+			allErrs = append(allErrs, field.Required(claimPath.Child("spec", "accessModes"), ""))
+		}
+
+		storage, ok := claim.Spec.Resources.Requests[corev1.ResourceStorage]
+		if !ok {
+			allErrs = append(allErrs, field.Required(claimPath.Child("spec", "resources", "requests", "storage"), ""))
+		} else if storage.Sign() <= 0 {
+			allErrs = append(allErrs, field.Invalid(claimPath.Child("spec", "resources", "requests", "storage"), storage.String(), "must be greater than zero"))
+		}
+	}
+
+	return allErrs
+}
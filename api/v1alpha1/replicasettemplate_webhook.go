@@ -0,0 +1,94 @@
+/*
+BSD 2-Clause License
+
+Copyright (c) 2019, Tarantool
+All rights reserved.
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+1. Redistributions of source code must retain the above copyright notice, this
+   list of conditions and the following disclaimer.
+
+2. Redistributions in binary form must reproduce the above copyright notice,
+   this list of conditions and the following disclaimer in the documentation
+   and/or other materials provided with the distribution.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+*/
+
+package v1alpha1
+
+import (
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+// log is for logging in this package.
+var replicasettemplatelog = logf.Log.WithName("replicasettemplate-resource")
+
+// SetupWebhookWithManager registers the ReplicasetTemplate webhooks in the manager
+func (t *ReplicasetTemplate) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(t).
+		Complete()
+}
+
+//+kubebuilder:webhook:path=/mutate-tarantool-io-v1alpha1-replicasettemplate,mutating=true,failurePolicy=fail,sideEffects=None,groups=tarantool.io,resources=replicasettemplates,verbs=create;update,versions=v1alpha1,name=mreplicasettemplate.kb.io,admissionReviewVersions=v1
+
+var _ webhook.Defaulter = &ReplicasetTemplate{}
+
+// Default fills in the spec fields omitted by the user
+func (t *ReplicasetTemplate) Default() {
+	if t.Spec == nil {
+		return
+	}
+	if t.Spec.Replicas == nil {
+		replicas := int32(1)
+		t.Spec.Replicas = &replicas
+	}
+}
+
+//+kubebuilder:webhook:path=/validate-tarantool-io-v1alpha1-replicasettemplate,mutating=false,failurePolicy=fail,sideEffects=None,groups=tarantool.io,resources=replicasettemplates,verbs=create;update,versions=v1alpha1,name=vreplicasettemplate.kb.io,admissionReviewVersions=v1
+
+var _ webhook.Validator = &ReplicasetTemplate{}
+
+// ValidateCreate implements webhook.Validator so a webhook will be registered for the type
+func (t *ReplicasetTemplate) ValidateCreate() error {
+	replicasettemplatelog.Info("validate create", "name", t.GetName())
+
+	return t.validateReplicasetTemplate()
+}
+
+// ValidateUpdate implements webhook.Validator so a webhook will be registered for the type
+func (t *ReplicasetTemplate) ValidateUpdate(old runtime.Object) error {
+	replicasettemplatelog.Info("validate update", "name", t.GetName())
+
+	return t.validateReplicasetTemplate()
+}
+
+// ValidateDelete implements webhook.Validator so a webhook will be registered for the type
+func (t *ReplicasetTemplate) ValidateDelete() error {
+	return nil
+}
+
+func (t *ReplicasetTemplate) validateReplicasetTemplate() error {
+	allErrs := t.ValidateTemplate()
+	if len(allErrs) == 0 {
+		return nil
+	}
+
+	return apierrors.NewInvalid(GroupVersion.WithKind("ReplicasetTemplate").GroupKind(), t.GetName(), allErrs)
+}
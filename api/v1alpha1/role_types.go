@@ -45,16 +45,85 @@ type RoleSpec struct {
 	StorageTemplate *ReplicasetTemplate `json:"storageTemplate,omitempty"`
 	// Selector is a LabelSelector to find ReplicasetTemplate resources from which StatefulSet created
 	Selector *metav1.LabelSelector `json:"selector,omitempty"`
+	// PreferredZone is the node zone (the topology.kubernetes.io/zone label) the masters of the replicasets are kept in,
+	// instances in other zones come later in the failover priority
+	// +optional
+	PreferredZone string `json:"preferredZone,omitempty"`
+}
+
+// Role condition types
+const (
+	// RoleTemplateSelected means a ReplicasetTemplate matching the Role selector was found
+	RoleTemplateSelected = "TemplateSelected"
+	// RoleReady means all desired replicasets exist and all of their pods are ready
+	RoleReady = "Ready"
+	// RoleProgressing means replicasets are being created, removed or updated
+	RoleProgressing = "Progressing"
+)
+
+// RoleStatefulSetStatus is the observed state of a StatefulSet managed by the Role
+type RoleStatefulSetStatus struct {
+	// Name is the name of the StatefulSet
+	Name string `json:"name"`
+	// Replicas is the desired number of pods
+	Replicas int32 `json:"replicas"`
+	// ReadyReplicas is the number of ready pods
+	ReadyReplicas int32 `json:"readyReplicas"`
+	// Image is the tarantool container image of the StatefulSet pod template
+	Image string `json:"image,omitempty"`
+	// PendingDeletion is set when the replicaset is being drained before removal
+	PendingDeletion bool `json:"pendingDeletion,omitempty"`
 }
 
 // RoleStatus defines the observed state of Role
 type RoleStatus struct {
 	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
 	// Important: Run "make" to regenerate code after modifying this file
+
+	// ObservedGeneration is the most recent Role generation observed by the operator
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// DesiredReplicasets is the requested number of replicasets
+	DesiredReplicasets int32 `json:"desiredReplicasets"`
+
+	// Replicasets is the number of replicasets that are not scheduled for deletion
+	Replicasets int32 `json:"replicasets"`
+
+	// ReadyReplicasets is the number of replicasets with all pods ready
+	ReadyReplicasets int32 `json:"readyReplicasets"`
+
+	// Template is the name of the ReplicasetTemplate selected by the Role
+	// +optional
+	Template string `json:"template,omitempty"`
+
+	// Image is the tarantool container image requested by the template
+	// +optional
+	Image string `json:"image,omitempty"`
+
+	// StatefulSets is the observed state of every StatefulSet of the Role
+	// +optional
+	StatefulSets []RoleStatefulSetStatus `json:"statefulSets,omitempty"`
+
+	// PendingDeletions are the StatefulSets drained before removal
+	// +optional
+	PendingDeletions []string `json:"pendingDeletions,omitempty"`
+
+	// Conditions describe the rollout state of the Role
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
 }
 
 //+kubebuilder:object:root=true
 //+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Desired",type="integer",JSONPath=".status.desiredReplicasets"
+//+kubebuilder:printcolumn:name="Current",type="integer",JSONPath=".status.replicasets"
+//+kubebuilder:printcolumn:name="Ready",type="integer",JSONPath=".status.readyReplicasets"
+//+kubebuilder:printcolumn:name="Template",type="string",JSONPath=".status.template"
+//+kubebuilder:printcolumn:name="Image",type="string",JSONPath=".status.image",priority=1
+//+kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
 
 // Role is the Schema for the roles API
 type Role struct {
@@ -0,0 +1,122 @@
+/*
+BSD 2-Clause License
+
+Copyright (c) 2019, Tarantool
+All rights reserved.
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+1. Redistributions of source code must retain the above copyright notice, this
+   list of conditions and the following disclaimer.
+
+2. Redistributions in binary form must reproduce the above copyright notice,
+   this list of conditions and the following disclaimer in the documentation
+   and/or other materials provided with the distribution.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+*/
+
+package v1alpha1
+
+import (
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+// log is for logging in this package.
+var rolelog = logf.Log.WithName("role-resource")
+
+// SetupWebhookWithManager registers the Role webhooks in the manager
+func (r *Role) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		Complete()
+}
+
+//+kubebuilder:webhook:path=/mutate-tarantool-io-v1alpha1-role,mutating=true,failurePolicy=fail,sideEffects=None,groups=tarantool.io,resources=roles,verbs=create;update,versions=v1alpha1,name=mrole.kb.io,admissionReviewVersions=v1
+
+var _ webhook.Defaulter = &Role{}
+
+// Default fills in the spec fields omitted by the user
+func (r *Role) Default() {
+	if r.Spec.NumReplicasets == nil {
+		numReplicasets := int32(1)
+		r.Spec.NumReplicasets = &numReplicasets
+	}
+}
+
+//+kubebuilder:webhook:path=/validate-tarantool-io-v1alpha1-role,mutating=false,failurePolicy=fail,sideEffects=None,groups=tarantool.io,resources=roles,verbs=create;update,versions=v1alpha1,name=vrole.kb.io,admissionReviewVersions=v1
+
+var _ webhook.Validator = &Role{}
+
+// ValidateCreate implements webhook.Validator so a webhook will be registered for the type
+func (r *Role) ValidateCreate() error {
+	rolelog.Info("validate create", "name", r.GetName())
+
+	return r.validateRole()
+}
+
+// ValidateUpdate implements webhook.Validator so a webhook will be registered for the type
+func (r *Role) ValidateUpdate(old runtime.Object) error {
+	rolelog.Info("validate update", "name", r.GetName())
+
+	return r.validateRole()
+}
+
+// ValidateDelete implements webhook.Validator so a webhook will be registered for the type
+func (r *Role) ValidateDelete() error {
+	return nil
+}
+
+func (r *Role) validateRole() error {
+	allErrs := field.ErrorList{}
+	annotations := r.GetAnnotations()
+	annotationsPath := field.NewPath("metadata", "annotations")
+
+	if _, ok := annotations[RolesToAssignAnnotation]; ok {
+		if _, err := ParseRoles(r.GetLabels(), annotations); err != nil {
+			allErrs = append(allErrs, field.Invalid(annotationsPath.Key(RolesToAssignAnnotation),
+				annotations[RolesToAssignAnnotation], err.Error()))
+		}
+	}
+
+	if weight, ok := annotations[ReplicasetWeightAnnotation]; ok {
+		if _, err := ParseReplicasetWeight(weight); err != nil {
+			allErrs = append(allErrs, field.Invalid(annotationsPath.Key(ReplicasetWeightAnnotation), weight, err.Error()))
+		}
+	}
+
+	specPath := field.NewPath("spec")
+	if r.Spec.NumReplicasets == nil {
+		allErrs = append(allErrs, field.Required(specPath.Child("numReplicasets"), ""))
+	} else if *r.Spec.NumReplicasets < 0 {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("numReplicasets"), *r.Spec.NumReplicasets, "must be non-negative"))
+	}
+
+	if r.Spec.Selector == nil {
+		allErrs = append(allErrs, field.Required(specPath.Child("selector"), "selector of the ReplicasetTemplate is required"))
+	} else if _, err := metav1.LabelSelectorAsSelector(r.Spec.Selector); err != nil {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("selector"), r.Spec.Selector, err.Error()))
+	}
+
+	if len(allErrs) == 0 {
+		return nil
+	}
+
+	return apierrors.NewInvalid(GroupVersion.WithKind("Role").GroupKind(), r.GetName(), allErrs)
+}
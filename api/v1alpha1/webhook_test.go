@@ -0,0 +1,353 @@
+package v1alpha1
+
+import (
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func newTestCluster(name string, matchLabels map[string]string) *Cluster {
+	return &Cluster{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Spec: ClusterSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: matchLabels},
+		},
+	}
+}
+
+func newTestTemplate() *ReplicasetTemplate {
+	return &ReplicasetTemplate{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "storage-template",
+			Namespace: "default",
+			Annotations: map[string]string{
+				"tarantool.io/rolesToAssign": "[\"vshard-storage\"]",
+			},
+		},
+		Spec: &appsv1.StatefulSetSpec{
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{"tarantool.io/pod-template": "storage-pod-template"},
+				},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{Name: "cartridge", Image: "tarantool/cartridge"}},
+				},
+			},
+			VolumeClaimTemplates: []corev1.PersistentVolumeClaim{
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "www"},
+					Spec: corev1.PersistentVolumeClaimSpec{
+						AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+						Resources: corev1.ResourceRequirements{
+							Requests: corev1.ResourceList{
+								corev1.ResourceStorage: resource.MustParse("1Gi"),
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestClusterDefaultSelector(t *testing.T) {
+	cluster := &Cluster{ObjectMeta: metav1.ObjectMeta{Name: "examples-kv-cluster"}}
+	cluster.Default()
+
+	if cluster.Spec.Selector == nil {
+		t.Fatal("selector was not defaulted")
+	}
+	if got := cluster.Spec.Selector.MatchLabels["tarantool.io/cluster-id"]; got != "examples-kv-cluster" {
+		t.Errorf("unexpected cluster-id selector: %s", got)
+	}
+}
+
+func TestClusterValidateOverlappingSelector(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	existing := newTestCluster("first", map[string]string{"tarantool.io/cluster-id": "kv"})
+	clusterReader = fake.NewClientBuilder().WithScheme(scheme).WithObjects(existing).Build()
+	defer func() { clusterReader = nil }()
+
+	overlapping := newTestCluster("second", map[string]string{"tarantool.io/cluster-id": "kv", "env": "dev"})
+	if err := overlapping.ValidateCreate(); err == nil {
+		t.Error("expected overlapping selector to be rejected")
+	}
+
+	separate := newTestCluster("third", map[string]string{"tarantool.io/cluster-id": "other"})
+	if err := separate.ValidateCreate(); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	if err := existing.ValidateUpdate(existing); err != nil {
+		t.Errorf("cluster must not overlap with itself: %s", err)
+	}
+}
+
+func TestSelectorsOverlap(t *testing.T) {
+	cases := []struct {
+		a, b     metav1.LabelSelector
+		overlaps bool
+	}{
+		{
+			a:        metav1.LabelSelector{MatchLabels: map[string]string{"app": "kv"}},
+			b:        metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "app", Operator: metav1.LabelSelectorOpIn, Values: []string{"kv", "cache"}}}},
+			overlaps: true,
+		},
+		{
+			a:        metav1.LabelSelector{MatchLabels: map[string]string{"app": "kv"}},
+			b:        metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "app", Operator: metav1.LabelSelectorOpNotIn, Values: []string{"kv"}}}},
+			overlaps: false,
+		},
+		{
+			a:        metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "app", Operator: metav1.LabelSelectorOpExists}}},
+			b:        metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "app", Operator: metav1.LabelSelectorOpNotIn, Values: []string{"kv"}}}},
+			overlaps: true,
+		},
+		{
+			a:        metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "tier", Operator: metav1.LabelSelectorOpExists}}},
+			b:        metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "tier", Operator: metav1.LabelSelectorOpDoesNotExist}}},
+			overlaps: false,
+		},
+		{
+			a:        metav1.LabelSelector{MatchLabels: map[string]string{"app": "kv"}},
+			b:        metav1.LabelSelector{MatchLabels: map[string]string{"env": "dev"}},
+			overlaps: true,
+		},
+	}
+
+	for i, c := range cases {
+		a, err := metav1.LabelSelectorAsSelector(&c.a)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := metav1.LabelSelectorAsSelector(&c.b)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got := selectorsOverlap(a, b); got != c.overlaps {
+			t.Errorf("%d: expected overlap %v, got %v", i, c.overlaps, got)
+		}
+		if got := selectorsOverlap(b, a); got != c.overlaps {
+			t.Errorf("%d: expected symmetric overlap %v, got %v", i, c.overlaps, got)
+		}
+	}
+}
+
+func TestClusterValidateImmutable(t *testing.T) {
+	old := newTestCluster("kv", map[string]string{"tarantool.io/cluster-id": "kv"})
+	old.Default()
+
+	changed := old.DeepCopy()
+	changed.Spec.BucketCount = 100
+	changed.Spec.BinaryPort = 3302
+	changed.Spec.ClusterDomainName = "example.org"
+	changed.Spec.VshardGroups = []string{"hot", "cold"}
+	err := changed.ValidateUpdate(old)
+	if err == nil {
+		t.Fatal("expected changes of immutable fields to be rejected before bootstrap")
+	}
+	if causes := err.(*apierrors.StatusError).ErrStatus.Details.Causes; len(causes) != 4 {
+		t.Errorf("expected 4 rejected fields, got %v", causes)
+	}
+
+	meta.SetStatusCondition(&old.Status.Conditions, metav1.Condition{
+		Type:   ClusterVshardBootstrapped,
+		Status: metav1.ConditionTrue,
+		Reason: "Bootstrapped",
+	})
+	if err := changed.ValidateUpdate(old); err == nil {
+		t.Fatal("expected changes of immutable fields to be rejected after bootstrap")
+	}
+
+	moved := old.DeepCopy()
+	moved.Spec.HTTPPort = 8082
+	if err := moved.ValidateUpdate(old); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+}
+
+func TestClusterValidateDeletion(t *testing.T) {
+	cluster := newTestCluster("kv", map[string]string{"tarantool.io/cluster-id": "kv"})
+	cluster.Default()
+	if cluster.Spec.Deletion.Policy != DeletionPolicyDelete {
+		t.Fatalf("unexpected default deletion policy: %s", cluster.Spec.Deletion.Policy)
+	}
+
+	cluster.Spec.Deletion.DeleteVolumes = true
+	cluster.Spec.Deletion.VolumeSnapshotClassName = "csi-snapclass"
+	if errs := cluster.validateDeletion(); len(errs) != 1 {
+		t.Errorf("expected the snapshot class to be rejected with the Delete policy, got %v", errs)
+	}
+
+	cluster.Spec.Deletion.Policy = DeletionPolicySnapshotThenDelete
+	if errs := cluster.validateDeletion(); len(errs) != 1 {
+		t.Errorf("expected deleteVolumes to be rejected with the SnapshotThenDelete policy, got %v", errs)
+	}
+
+	cluster.Spec.Deletion.DeleteVolumes = false
+	if errs := cluster.validateDeletion(); len(errs) != 0 {
+		t.Errorf("unexpected errors: %v", errs)
+	}
+}
+
+func TestClusterValidateFailover(t *testing.T) {
+	cluster := newTestCluster("kv", map[string]string{"tarantool.io/cluster-id": "kv"})
+	cluster.Default()
+	if errs := cluster.validateFailover(); len(errs) != 0 {
+		t.Fatalf("unexpected errors for the default failover: %v", errs)
+	}
+
+	cluster.Spec.Failover.Fencing = &FencingSpec{Enabled: true}
+	if errs := cluster.validateFailover(); len(errs) != 1 {
+		t.Errorf("expected fencing to be rejected with the eventual failover, got %v", errs)
+	}
+
+	cluster.Spec.Failover.Mode = FailoverModeStateful
+	if errs := cluster.validateFailover(); len(errs) != 1 {
+		t.Errorf("expected the missing state provider to be rejected, got %v", errs)
+	}
+
+	cluster.Spec.Failover.StateProvider = &FailoverStateProviderSpec{Type: FailoverStateProviderEtcd2}
+	if errs := cluster.validateFailover(); len(errs) != 1 {
+		t.Errorf("expected the etcd2 state provider without endpoints to be rejected, got %v", errs)
+	}
+
+	cluster.Spec.Failover.StateProvider.Etcd2 = &Etcd2StateProviderSpec{Endpoints: []string{"http://etcd:2379"}}
+	if errs := cluster.validateFailover(); len(errs) != 0 {
+		t.Errorf("unexpected errors: %v", errs)
+	}
+
+	cluster.Spec.Failover.StateProvider.Type = FailoverStateProviderStateboard
+	if errs := cluster.validateFailover(); len(errs) != 1 {
+		t.Errorf("expected the stateboard state provider without URI to be rejected, got %v", errs)
+	}
+
+	cluster.Spec.Failover.StateProvider.Stateboard = &StateboardSpec{
+		URI:     "stateboard:4401",
+		Managed: &ManagedStateboardSpec{Image: "kv:latest"},
+	}
+	if errs := cluster.validateFailover(); len(errs) != 1 {
+		t.Errorf("expected the URI of a managed stateboard to be rejected, got %v", errs)
+	}
+
+	cluster.Spec.Failover.StateProvider.Stateboard.URI = ""
+	cluster.Default()
+	if errs := cluster.validateFailover(); len(errs) != 0 {
+		t.Errorf("unexpected errors: %v", errs)
+	}
+	if cluster.Spec.Failover.StateProvider.Stateboard.Managed.Port != DefaultStateboardPort {
+		t.Errorf("unexpected default stateboard port: %d", cluster.Spec.Failover.StateProvider.Stateboard.Managed.Port)
+	}
+}
+
+func TestClusterValidateAdminAPI(t *testing.T) {
+	cluster := newTestCluster("kv", map[string]string{"tarantool.io/cluster-id": "kv"})
+	cluster.Default()
+	if cluster.Spec.AdminAPI.Scheme != AdminAPISchemeHTTP {
+		t.Fatalf("unexpected default scheme: %s", cluster.Spec.AdminAPI.Scheme)
+	}
+
+	cluster.Spec.AdminAPI.TLS = &AdminAPITLSSpec{
+		CASecret:    &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "ca"}, Key: "ca.crt"},
+		CAConfigMap: &corev1.ConfigMapKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "ca"}, Key: "ca.crt"},
+	}
+	if errs := cluster.validateAdminAPI(); len(errs) != 2 {
+		t.Errorf("expected http scheme and both CA sources to be rejected, got %v", errs)
+	}
+
+	cluster.Spec.AdminAPI.Scheme = AdminAPISchemeHTTPS
+	cluster.Spec.AdminAPI.TLS.CAConfigMap = nil
+	if errs := cluster.validateAdminAPI(); len(errs) != 0 {
+		t.Errorf("unexpected errors: %v", errs)
+	}
+}
+
+func TestRoleDefaultAndValidate(t *testing.T) {
+	role := &Role{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "storage",
+			Annotations: map[string]string{
+				"tarantool.io/rolesToAssign": "[\"vshard-storage\"]",
+			},
+		},
+		Spec: RoleSpec{
+			Selector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{"tarantool.io/replicaset-template": "storage-template"},
+			},
+		},
+	}
+	role.Default()
+
+	if role.Spec.NumReplicasets == nil || *role.Spec.NumReplicasets != 1 {
+		t.Fatal("numReplicasets was not defaulted")
+	}
+	if err := role.ValidateCreate(); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	role.Annotations["tarantool.io/replicaset-weight"] = "-1"
+	if err := role.ValidateCreate(); err == nil {
+		t.Error("expected negative weight to be rejected")
+	}
+
+	role.Annotations["tarantool.io/replicaset-weight"] = "10"
+	role.Annotations["tarantool.io/rolesToAssign"] = "[\"vshard-storage\","
+	if err := role.ValidateCreate(); err == nil {
+		t.Error("expected unparsable rolesToAssign to be rejected")
+	}
+
+	role.Annotations["tarantool.io/rolesToAssign"] = "[\"vshard-storage\"]"
+	role.Spec.Selector = nil
+	if err := role.ValidateCreate(); err == nil {
+		t.Error("expected missing selector to be rejected")
+	}
+}
+
+func TestReplicasetTemplateValidate(t *testing.T) {
+	template := newTestTemplate()
+	template.Default()
+
+	if template.Spec.Replicas == nil || *template.Spec.Replicas != 1 {
+		t.Fatal("replicas was not defaulted")
+	}
+	if err := template.ValidateCreate(); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	noContainers := newTestTemplate()
+	noContainers.Spec.Template.Spec.Containers = nil
+	if err := noContainers.ValidateCreate(); err == nil {
+		t.Error("expected template without containers to be rejected")
+	}
+
+	noLabels := newTestTemplate()
+	noLabels.Spec.Template.Labels = nil
+	if err := noLabels.ValidateCreate(); err == nil {
+		t.Error("expected template without pod labels to be rejected")
+	}
+
+	noRoles := newTestTemplate()
+	noRoles.Annotations = nil
+	if err := noRoles.ValidateCreate(); err == nil {
+		t.Error("expected template without rolesToAssign to be rejected")
+	}
+
+	badClaim := newTestTemplate()
+	badClaim.Spec.VolumeClaimTemplates = append(badClaim.Spec.VolumeClaimTemplates, badClaim.Spec.VolumeClaimTemplates[0])
+	badClaim.Spec.VolumeClaimTemplates[1].Spec.Resources.Requests = nil
+	if err := badClaim.ValidateCreate(); err == nil {
+		t.Error("expected duplicate volume claim without storage request to be rejected")
+	}
+}
@@ -35,17 +35,73 @@ package v1alpha1
 
 import (
 	appsv1 "k8s.io/api/apps/v1"
-	"k8s.io/apimachinery/pkg/apis/meta/v1"
-	runtime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AdminAPISpec) DeepCopyInto(out *AdminAPISpec) {
+	*out = *in
+	if in.CredentialsSecret != nil {
+		in, out := &in.CredentialsSecret, &out.CredentialsSecret
+		*out = new(v1.LocalObjectReference)
+		**out = **in
+	}
+	if in.TLS != nil {
+		in, out := &in.TLS, &out.TLS
+		*out = new(AdminAPITLSSpec)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AdminAPISpec.
+func (in *AdminAPISpec) DeepCopy() *AdminAPISpec {
+	if in == nil {
+		return nil
+	}
+	out := new(AdminAPISpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AdminAPITLSSpec) DeepCopyInto(out *AdminAPITLSSpec) {
+	*out = *in
+	if in.CASecret != nil {
+		in, out := &in.CASecret, &out.CASecret
+		*out = new(v1.SecretKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.CAConfigMap != nil {
+		in, out := &in.CAConfigMap, &out.CAConfigMap
+		*out = new(v1.ConfigMapKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ClientCertSecret != nil {
+		in, out := &in.ClientCertSecret, &out.ClientCertSecret
+		*out = new(v1.LocalObjectReference)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AdminAPITLSSpec.
+func (in *AdminAPITLSSpec) DeepCopy() *AdminAPITLSSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(AdminAPITLSSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Cluster) DeepCopyInto(out *Cluster) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Cluster.
@@ -103,9 +159,39 @@ func (in *ClusterSpec) DeepCopyInto(out *ClusterSpec) {
 	*out = *in
 	if in.Selector != nil {
 		in, out := &in.Selector, &out.Selector
-		*out = new(v1.LabelSelector)
+		*out = new(metav1.LabelSelector)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.AdminAPI != nil {
+		in, out := &in.AdminAPI, &out.AdminAPI
+		*out = new(AdminAPISpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Failover != nil {
+		in, out := &in.Failover, &out.Failover
+		*out = new(FailoverSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.VshardGroups != nil {
+		in, out := &in.VshardGroups, &out.VshardGroups
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Rebalancing != nil {
+		in, out := &in.Rebalancing, &out.Rebalancing
+		*out = new(RebalancingSpec)
+		**out = **in
+	}
+	if in.Deletion != nil {
+		in, out := &in.Deletion, &out.Deletion
+		*out = new(DeletionSpec)
+		**out = **in
+	}
+	if in.ServerLabels != nil {
+		in, out := &in.ServerLabels, &out.ServerLabels
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterSpec.
@@ -121,6 +207,40 @@ func (in *ClusterSpec) DeepCopy() *ClusterSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ClusterStatus) DeepCopyInto(out *ClusterStatus) {
 	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Leader != nil {
+		in, out := &in.Leader, &out.Leader
+		*out = new(LeaderStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Replicasets != nil {
+		in, out := &in.Replicasets, &out.Replicasets
+		*out = make([]ReplicasetStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Rebalancing != nil {
+		in, out := &in.Rebalancing, &out.Rebalancing
+		*out = new(RebalancingStatus)
+		**out = **in
+	}
+	if in.Deletion != nil {
+		in, out := &in.Deletion, &out.Deletion
+		*out = new(DeletionStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Stateboard != nil {
+		in, out := &in.Stateboard, &out.Stateboard
+		*out = new(StateboardStatus)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterStatus.
@@ -133,6 +253,233 @@ func (in *ClusterStatus) DeepCopy() *ClusterStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DeletionSpec) DeepCopyInto(out *DeletionSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DeletionSpec.
+func (in *DeletionSpec) DeepCopy() *DeletionSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DeletionSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DeletionStatus) DeepCopyInto(out *DeletionStatus) {
+	*out = *in
+	if in.Volumes != nil {
+		in, out := &in.Volumes, &out.Volumes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Snapshots != nil {
+		in, out := &in.Snapshots, &out.Snapshots
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DeletionStatus.
+func (in *DeletionStatus) DeepCopy() *DeletionStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(DeletionStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Etcd2StateProviderSpec) DeepCopyInto(out *Etcd2StateProviderSpec) {
+	*out = *in
+	if in.Endpoints != nil {
+		in, out := &in.Endpoints, &out.Endpoints
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.CredentialsSecret != nil {
+		in, out := &in.CredentialsSecret, &out.CredentialsSecret
+		*out = new(v1.LocalObjectReference)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Etcd2StateProviderSpec.
+func (in *Etcd2StateProviderSpec) DeepCopy() *Etcd2StateProviderSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(Etcd2StateProviderSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FailoverSpec) DeepCopyInto(out *FailoverSpec) {
+	*out = *in
+	if in.Fencing != nil {
+		in, out := &in.Fencing, &out.Fencing
+		*out = new(FencingSpec)
+		**out = **in
+	}
+	if in.StateProvider != nil {
+		in, out := &in.StateProvider, &out.StateProvider
+		*out = new(FailoverStateProviderSpec)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FailoverSpec.
+func (in *FailoverSpec) DeepCopy() *FailoverSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(FailoverSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FailoverStateProviderSpec) DeepCopyInto(out *FailoverStateProviderSpec) {
+	*out = *in
+	if in.Stateboard != nil {
+		in, out := &in.Stateboard, &out.Stateboard
+		*out = new(StateboardSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Etcd2 != nil {
+		in, out := &in.Etcd2, &out.Etcd2
+		*out = new(Etcd2StateProviderSpec)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FailoverStateProviderSpec.
+func (in *FailoverStateProviderSpec) DeepCopy() *FailoverStateProviderSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(FailoverStateProviderSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FencingSpec) DeepCopyInto(out *FencingSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FencingSpec.
+func (in *FencingSpec) DeepCopy() *FencingSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(FencingSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LeaderStatus) DeepCopyInto(out *LeaderStatus) {
+	*out = *in
+	in.LastTransitionTime.DeepCopyInto(&out.LastTransitionTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LeaderStatus.
+func (in *LeaderStatus) DeepCopy() *LeaderStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(LeaderStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ManagedStateboardSpec) DeepCopyInto(out *ManagedStateboardSpec) {
+	*out = *in
+	if in.Command != nil {
+		in, out := &in.Command, &out.Command
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	in.Resources.DeepCopyInto(&out.Resources)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ManagedStateboardSpec.
+func (in *ManagedStateboardSpec) DeepCopy() *ManagedStateboardSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ManagedStateboardSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RebalancingSpec) DeepCopyInto(out *RebalancingSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RebalancingSpec.
+func (in *RebalancingSpec) DeepCopy() *RebalancingSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(RebalancingSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RebalancingStatus) DeepCopyInto(out *RebalancingStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RebalancingStatus.
+func (in *RebalancingStatus) DeepCopy() *RebalancingStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(RebalancingStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReplicasetStatus) DeepCopyInto(out *ReplicasetStatus) {
+	*out = *in
+	if in.Roles != nil {
+		in, out := &in.Roles, &out.Roles
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Weight != nil {
+		in, out := &in.Weight, &out.Weight
+		*out = new(int32)
+		**out = **in
+	}
+	if in.LastSwitchover != nil {
+		in, out := &in.LastSwitchover, &out.LastSwitchover
+		*out = new(SwitchoverStatus)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReplicasetStatus.
+func (in *ReplicasetStatus) DeepCopy() *ReplicasetStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ReplicasetStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ReplicasetTemplate) DeepCopyInto(out *ReplicasetTemplate) {
 	*out = *in
@@ -143,7 +490,7 @@ func (in *ReplicasetTemplate) DeepCopyInto(out *ReplicasetTemplate) {
 		*out = new(appsv1.StatefulSetSpec)
 		(*in).DeepCopyInto(*out)
 	}
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReplicasetTemplate.
@@ -214,6 +561,18 @@ func (in *ReplicasetTemplateSpec) DeepCopy() *ReplicasetTemplateSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ReplicasetTemplateStatus) DeepCopyInto(out *ReplicasetTemplateStatus) {
 	*out = *in
+	if in.UsedBy != nil {
+		in, out := &in.UsedBy, &out.UsedBy
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReplicasetTemplateStatus.
@@ -232,7 +591,7 @@ func (in *Role) DeepCopyInto(out *Role) {
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Role.
@@ -300,7 +659,7 @@ func (in *RoleSpec) DeepCopyInto(out *RoleSpec) {
 	}
 	if in.Selector != nil {
 		in, out := &in.Selector, &out.Selector
-		*out = new(v1.LabelSelector)
+		*out = new(metav1.LabelSelector)
 		(*in).DeepCopyInto(*out)
 	}
 }
@@ -315,9 +674,41 @@ func (in *RoleSpec) DeepCopy() *RoleSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RoleStatefulSetStatus) DeepCopyInto(out *RoleStatefulSetStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RoleStatefulSetStatus.
+func (in *RoleStatefulSetStatus) DeepCopy() *RoleStatefulSetStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(RoleStatefulSetStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *RoleStatus) DeepCopyInto(out *RoleStatus) {
 	*out = *in
+	if in.StatefulSets != nil {
+		in, out := &in.StatefulSets, &out.StatefulSets
+		*out = make([]RoleStatefulSetStatus, len(*in))
+		copy(*out, *in)
+	}
+	if in.PendingDeletions != nil {
+		in, out := &in.PendingDeletions, &out.PendingDeletions
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RoleStatus.
@@ -329,3 +720,59 @@ func (in *RoleStatus) DeepCopy() *RoleStatus {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StateboardSpec) DeepCopyInto(out *StateboardSpec) {
+	*out = *in
+	if in.PasswordSecret != nil {
+		in, out := &in.PasswordSecret, &out.PasswordSecret
+		*out = new(v1.SecretKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Managed != nil {
+		in, out := &in.Managed, &out.Managed
+		*out = new(ManagedStateboardSpec)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StateboardSpec.
+func (in *StateboardSpec) DeepCopy() *StateboardSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(StateboardSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StateboardStatus) DeepCopyInto(out *StateboardStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StateboardStatus.
+func (in *StateboardStatus) DeepCopy() *StateboardStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(StateboardStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SwitchoverStatus) DeepCopyInto(out *SwitchoverStatus) {
+	*out = *in
+	in.Finished.DeepCopyInto(&out.Finished)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SwitchoverStatus.
+func (in *SwitchoverStatus) DeepCopy() *SwitchoverStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(SwitchoverStatus)
+	in.DeepCopyInto(out)
+	return out
+}
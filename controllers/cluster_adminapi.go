@@ -0,0 +1,120 @@
+package controllers
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	tarantooliov1alpha1 "github.com/tarantool/tarantool-operator/api/v1alpha1"
+	"github.com/tarantool/tarantool-operator/controllers/topology"
+)
+
+//+kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch
+
+// adminAPIOptions resolves the Secrets and ConfigMaps referenced by the Cluster into admin API client options
+func (r *ClusterReconciler) adminAPIOptions(cluster *tarantooliov1alpha1.Cluster) ([]topology.Option, error) {
+	adminAPI := cluster.Spec.AdminAPI
+	if adminAPI == nil {
+		return nil, nil
+	}
+
+	opts := []topology.Option{}
+
+	if adminAPI.CredentialsSecret != nil {
+		secret, err := r.getSecret(cluster.GetNamespace(), adminAPI.CredentialsSecret.Name)
+		if err != nil {
+			return nil, err
+		}
+
+		credentials := &topology.Credentials{
+			Username: string(secret.Data[tarantooliov1alpha1.AdminAPIUsernameKey]),
+			Password: string(secret.Data[tarantooliov1alpha1.AdminAPIPasswordKey]),
+			Cookie:   string(secret.Data[tarantooliov1alpha1.AdminAPICookieKey]),
+		}
+		if credentials.Username == "" && credentials.Cookie == "" {
+			return nil, fmt.Errorf("secret %s has neither %s nor %s key", secret.GetName(),
+				tarantooliov1alpha1.AdminAPIUsernameKey, tarantooliov1alpha1.AdminAPICookieKey)
+		}
+		opts = append(opts, topology.WithCredentials(credentials))
+	}
+
+	if adminAPI.Scheme == tarantooliov1alpha1.AdminAPISchemeHTTPS && adminAPI.TLS != nil {
+		tlsConfig, err := r.adminAPITLSConfig(cluster.GetNamespace(), adminAPI.TLS)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, topology.WithTLSConfig(tlsConfig))
+	}
+
+	return opts, nil
+}
+
+func (r *ClusterReconciler) adminAPITLSConfig(namespace string, spec *tarantooliov1alpha1.AdminAPITLSSpec) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		ServerName:         spec.ServerName,
+		InsecureSkipVerify: spec.InsecureSkipVerify,
+	}
+
+	var caBundle []byte
+	switch {
+	case spec.CASecret != nil:
+		secret, err := r.getSecret(namespace, spec.CASecret.Name)
+		if err != nil {
+			return nil, err
+		}
+		caBundle = secret.Data[spec.CASecret.Key]
+	case spec.CAConfigMap != nil:
+		configMap := &corev1.ConfigMap{}
+		if err := r.Get(context.TODO(), types.NamespacedName{Namespace: namespace, Name: spec.CAConfigMap.Name}, configMap); err != nil {
+			return nil, fmt.Errorf("failed to get ConfigMap %s: %w", spec.CAConfigMap.Name, err)
+		}
+		caBundle = []byte(configMap.Data[spec.CAConfigMap.Key])
+	}
+
+	if caBundle != nil {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caBundle) {
+			return nil, fmt.Errorf("no PEM certificates found in the CA bundle")
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if spec.ClientCertSecret != nil {
+		secret, err := r.getSecret(namespace, spec.ClientCertSecret.Name)
+		if err != nil {
+			return nil, err
+		}
+
+		cert, err := tls.X509KeyPair(secret.Data[corev1.TLSCertKey], secret.Data[corev1.TLSPrivateKeyKey])
+		if err != nil {
+			return nil, fmt.Errorf("invalid client certificate in secret %s: %w", secret.GetName(), err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
+
+func (r *ClusterReconciler) getSecret(namespace, name string) (*corev1.Secret, error) {
+	secret := &corev1.Secret{}
+	if err := r.Get(context.TODO(), types.NamespacedName{Namespace: namespace, Name: name}, secret); err != nil {
+		return nil, fmt.Errorf("failed to get Secret %s: %w", name, err)
+	}
+
+	return secret, nil
+}
+
+// adminAPIURL is the GraphQL endpoint of the instance serving the admin API at address
+func adminAPIURL(cluster *tarantooliov1alpha1.Cluster, address string) string {
+	scheme := tarantooliov1alpha1.AdminAPISchemeHTTP
+	if cluster.Spec.AdminAPI != nil && cluster.Spec.AdminAPI.Scheme != "" {
+		scheme = cluster.Spec.AdminAPI.Scheme
+	}
+
+	return fmt.Sprintf("%s://%s/admin/api", scheme, address)
+}
@@ -31,7 +31,6 @@ package controllers
 import (
 	"context"
 	"fmt"
-	"strconv"
 	"strings"
 	"time"
 
@@ -53,7 +52,6 @@ import (
 	tarantooliov1alpha1 "github.com/tarantool/tarantool-operator/api/v1alpha1"
 	"github.com/tarantool/tarantool-operator/controllers/tarantool"
 	"github.com/tarantool/tarantool-operator/controllers/topology"
-	"github.com/tarantool/tarantool-operator/controllers/utils"
 )
 
 var space = uuid.MustParse("73692FF6-EB42-46C2-92B6-65C45191368D")
@@ -62,21 +60,30 @@ var space = uuid.MustParse("73692FF6-EB42-46C2-92B6-65C45191368D")
 type ClusterReconciler struct {
 	client.Client
 	Scheme *runtime.Scheme
+	// TopologyFactory creates clients of the Cartridge topology, the GraphQL admin API client is used when nil
+	TopologyFactory topology.Factory
+	// RolloutStepTimeout is how long a restarted instance may take to become healthy before the rolling update
+	// of its replicaset is paused, 5 minutes when zero
+	RolloutStepTimeout time.Duration
 }
 
-// Checking for a leader in the cluster Endpoint annotation
-func IsLeaderExists(ep *corev1.Endpoints) bool {
-	leader, ok := ep.Annotations["tarantool.io/leader"]
-	if !ok || leader == "" {
-		return false
+func (r *ClusterReconciler) newTopologyService(opts ...topology.Option) topology.TopologyService {
+	if r.TopologyFactory == nil {
+		return topology.BuiltInFactory(opts...)
 	}
 
-	for _, addr := range ep.Subsets[0].Addresses {
-		if leader == fmt.Sprintf("%s:%s", addr.IP, "8081") {
-			return true
-		}
+	return r.TopologyFactory(opts...)
+}
+
+// topologyResult decides how to requeue after a failed topology operation: retryable failures are
+// repeated after the usual delay, other ones are returned so the controller backs off exponentially
+func topologyResult(ctx context.Context, err error) (ctrl.Result, error) {
+	if topology.IsRetryable(err) {
+		log.FromContext(ctx).Info("topology is temporarily unavailable, retrying", "error", err.Error())
+		return ctrl.Result{RequeueAfter: time.Duration(5 * time.Second)}, nil
 	}
-	return false
+
+	return ctrl.Result{}, err
 }
 
 // HasInstanceUUID .
@@ -89,13 +96,18 @@ func HasInstanceUUID(o *corev1.Pod) bool {
 	return false
 }
 
-// SetInstanceUUID .
-func SetInstanceUUID(o *corev1.Pod) *corev1.Pod {
+// SetInstanceUUID sets the instance uuid derived from the pod name and the incarnations of the replicaset
+// and the instance, so an instance created again after an expel never reuses the expelled uuid
+func SetInstanceUUID(o *corev1.Pod, replicasetGeneration, instanceGeneration int) *corev1.Pod {
 	labels := o.Labels
 	if len(o.GetName()) == 0 {
 		return o
 	}
-	instanceUUID := uuid.NewSHA1(space, []byte(o.GetName()))
+	seed := o.GetName()
+	if replicasetGeneration > 0 || instanceGeneration > 0 {
+		seed = fmt.Sprintf("%s/%d/%d", seed, replicasetGeneration, instanceGeneration)
+	}
+	instanceUUID := uuid.NewSHA1(space, []byte(seed))
 	labels["tarantool.io/instance-uuid"] = instanceUUID.String()
 
 	o.SetLabels(labels)
@@ -109,6 +121,8 @@ func SetInstanceUUID(o *corev1.Pod) *corev1.Pod {
 //+kubebuilder:rbac:groups="",resources=pods,verbs=get;create;update;watch;list;patch;delete
 //+kubebuilder:rbac:groups="",resources=services,verbs=get;create;update;watch;list;patch;delete
 //+kubebuilder:rbac:groups="",resources=endpoints,verbs=get;create;update;watch;list;patch;delete
+//+kubebuilder:rbac:groups="",resources=persistentvolumeclaims,verbs=get;list;watch;update;patch;delete
+//+kubebuilder:rbac:groups=snapshot.storage.k8s.io,resources=volumesnapshots,verbs=get;list;watch;create
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -119,7 +133,7 @@ func SetInstanceUUID(o *corev1.Pod) *corev1.Pod {
 //
 // For more details, check Reconcile and its Result here:
 // - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.10.0/pkg/reconcile
-func (r *ClusterReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+func (r *ClusterReconciler) Reconcile(ctx context.Context, req ctrl.Request) (result ctrl.Result, reterr error) {
 	reqLogger := log.FromContext(ctx)
 	reqLogger.Info("Reconciling Cluster")
 
@@ -127,11 +141,23 @@ func (r *ClusterReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 	cluster := &tarantooliov1alpha1.Cluster{}
 	if err := r.Get(context.TODO(), req.NamespacedName, cluster); err != nil {
 		if errors.IsNotFound(err) {
+			deleteClusterMetrics(req.Namespace, req.Name)
+			if err := r.releasePods(req.Namespace, req.Name); err != nil {
+				return ctrl.Result{RequeueAfter: time.Duration(5 * time.Second)}, err
+			}
 			return ctrl.Result{RequeueAfter: time.Duration(5 * time.Second)}, nil
 		}
 
 		return ctrl.Result{RequeueAfter: time.Duration(5 * time.Second)}, err
 	}
+	cluster.Default()
+
+	if cluster.GetDeletionTimestamp() != nil {
+		return r.tearDown(ctx, cluster)
+	}
+	if err := r.addClusterFinalizer(cluster); err != nil {
+		return ctrl.Result{RequeueAfter: time.Duration(5 * time.Second)}, err
+	}
 
 	clusterSelector, err := metav1.LabelSelectorAsSelector(cluster.Spec.Selector)
 	if err != nil {
@@ -170,6 +196,22 @@ func (r *ClusterReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 
 	reqLogger.Info("Roles reconciled, moving to pod reconcile")
 
+	if err := r.releaseDeletedPods(ctx, cluster.GetNamespace(), cluster.GetName()); err != nil {
+		return ctrl.Result{RequeueAfter: time.Duration(5 * time.Second)}, err
+	}
+
+	var (
+		topologyClient topology.TopologyService
+		leaderStatus   *tarantooliov1alpha1.LeaderStatus
+		connectErr     error
+	)
+	stsList := &appsv1.StatefulSetList{}
+	defer func() {
+		if err := r.updateClusterStatus(ctx, cluster, topologyClient, stsList, leaderStatus, connectErr, reterr); err != nil {
+			reqLogger.Error(err, "failed to update cluster status")
+		}
+	}()
+
 	// ensure cluster wide Service exists
 	svc := &corev1.Service{}
 	if err := r.Get(context.TODO(), types.NamespacedName{Namespace: cluster.GetNamespace(), Name: cluster.GetName()}, svc); err != nil {
@@ -182,7 +224,7 @@ func (r *ClusterReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 				Ports: []corev1.ServicePort{
 					{
 						Name:     "app",
-						Port:     3301,
+						Port:     cluster.Spec.BinaryPort,
 						Protocol: "TCP",
 					},
 				},
@@ -198,6 +240,11 @@ func (r *ClusterReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 		}
 	}
 
+	if err := r.reconcileStateboard(ctx, cluster); err != nil {
+		reqLogger.Error(err, "failed to reconcile the stateboard")
+		return ctrl.Result{RequeueAfter: time.Duration(5 * time.Second)}, err
+	}
+
 	// ensure Cluster leader elected
 	ep := &corev1.Endpoints{}
 	if err := r.Get(context.TODO(), types.NamespacedName{Namespace: cluster.GetNamespace(), Name: cluster.GetName()}, ep); err != nil {
@@ -208,20 +255,30 @@ func (r *ClusterReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 		return ctrl.Result{RequeueAfter: time.Duration(5 * time.Second)}, nil
 	}
 
-	if !IsLeaderExists(ep) {
-		leader := fmt.Sprintf("%s:%s", ep.Subsets[0].Addresses[0].IP, "8081")
-
-		if ep.Annotations == nil {
-			ep.Annotations = make(map[string]string)
-		}
-
-		ep.Annotations["tarantool.io/leader"] = leader
+	if _, ok := ep.Annotations[legacyLeaderAnnotation]; ok {
+		delete(ep.Annotations, legacyLeaderAnnotation)
 		if err := r.Update(context.TODO(), ep); err != nil {
 			return ctrl.Result{RequeueAfter: time.Duration(5 * time.Second)}, err
 		}
 	}
 
-	stsList := &appsv1.StatefulSetList{}
+	adminAPIOpts, err := r.adminAPIOptions(cluster)
+	if err != nil {
+		connectErr = err
+		reqLogger.Error(err, "failed to configure admin API client")
+		return ctrl.Result{RequeueAfter: time.Duration(5 * time.Second)}, nil
+	}
+
+	leaderStatus, err = r.selectLeader(ctx, cluster, ep, adminAPIOpts)
+	if err != nil {
+		connectErr = err
+		reqLogger.Info("no instance answers on the admin API, waiting", "reason", err.Error())
+		return ctrl.Result{RequeueAfter: time.Duration(5 * time.Second)}, nil
+	}
+	if cluster.Status.Leader == nil || cluster.Status.Leader.Address != leaderStatus.Address {
+		reqLogger.Info("elected cluster leader", "Pod.Name", leaderStatus.Pod, "address", leaderStatus.Address, "reason", leaderStatus.Reason)
+	}
+
 	if err := r.List(context.TODO(), stsList, &client.ListOptions{LabelSelector: clusterSelector, Namespace: req.NamespacedName.Namespace}); err != nil {
 		if errors.IsNotFound(err) {
 			return ctrl.Result{RequeueAfter: time.Duration(5 * time.Second)}, nil
@@ -230,11 +287,21 @@ func (r *ClusterReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 		return ctrl.Result{RequeueAfter: time.Duration(5 * time.Second)}, err
 	}
 
-	topologyClient := topology.NewBuiltInTopologyService(
-		topology.WithTopologyEndpoint(fmt.Sprintf("http://%s/admin/api", ep.Annotations["tarantool.io/leader"])),
+	topologyClient = topology.NewCachedService(r.newTopologyService(append([]topology.Option{
+		topology.WithTopologyEndpoint(adminAPIURL(cluster, leaderStatus.Address)),
 		topology.WithClusterID(cluster.GetName()),
-	)
+		topology.WithClusterDomainName(cluster.Spec.ClusterDomainName),
+		topology.WithBinaryPort(cluster.Spec.BinaryPort),
+		topology.WithVshardGroups(cluster.Spec.VshardGroups),
+	}, adminAPIOpts...)...))
+
+	if cluster.Spec.Import {
+		if result, err := r.importTopology(ctx, cluster, topologyClient, stsList); err != nil || !result.IsZero() {
+			return result, err
+		}
+	}
 
+	uuidsSet := false
 	for _, sts := range stsList.Items {
 		for i := 0; i < int(*sts.Spec.Replicas); i++ {
 			pod := &corev1.Pod{}
@@ -244,171 +311,139 @@ func (r *ClusterReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 			}
 			if err := r.Get(context.TODO(), name, pod); err != nil {
 				if errors.IsNotFound(err) {
-					return ctrl.Result{RequeueAfter: time.Duration(5 * time.Second)}, nil
+					continue
 				}
 
 				return ctrl.Result{RequeueAfter: time.Duration(5 * time.Second)}, err
 			}
 
+			// an imported instance keeps its uuid and a pod created from a template older than
+			// the import is moved to the replicaset the StatefulSet was matched with
+			importedUUID, imported := tarantool.GetImportedInstanceUUID(&sts, i)
+			replicasetUUID := sts.GetLabels()["tarantool.io/replicaset-uuid"]
+			relabel := (imported && instanceUUID(pod) != importedUUID) || pod.GetLabels()["tarantool.io/replicaset-uuid"] != replicasetUUID
+
 			podLogger := reqLogger.WithValues("Pod.Name", pod.GetName())
-			if HasInstanceUUID(pod) {
+			if pod.GetDeletionTimestamp() != nil || (HasInstanceUUID(pod) && controllerutil.ContainsFinalizer(pod, instanceFinalizer) && !relabel) {
 				continue
 			}
 			podLogger.Info("starting: set instance uuid")
-			pod = SetInstanceUUID(pod)
+			if imported {
+				pod.Labels["tarantool.io/instance-uuid"] = importedUUID
+			} else if !HasInstanceUUID(pod) {
+				pod = SetInstanceUUID(pod, tarantool.GetGeneration(&sts), tarantool.GetInstanceGeneration(&sts, i))
+			}
+			pod.Labels["tarantool.io/replicaset-uuid"] = replicasetUUID
+			controllerutil.AddFinalizer(pod, instanceFinalizer)
 
 			if err := r.Update(context.TODO(), pod); err != nil {
 				return ctrl.Result{RequeueAfter: time.Duration(5 * time.Second)}, err
 			}
 
 			podLogger.Info("success: set instance uuid", "UUID", pod.GetLabels()["tarantool.io/instance-uuid"])
-			return ctrl.Result{Requeue: true}, nil
+			uuidsSet = true
 		}
+	}
+	if uuidsSet {
+		return ctrl.Result{Requeue: true}, nil
+	}
 
-		for i := 0; i < int(*sts.Spec.Replicas); i++ {
-			pod := &corev1.Pod{}
-			name := types.NamespacedName{
-				Namespace: req.Namespace,
-				Name:      fmt.Sprintf("%s-%d", sts.GetName(), i),
-			}
-			if err := r.Get(context.TODO(), name, pod); err != nil {
-				if errors.IsNotFound(err) {
-					return ctrl.Result{RequeueAfter: time.Duration(5 * time.Second)}, nil
-				}
+	if result, err := r.expelRemovedInstances(ctx, cluster, topologyClient, stsList); err != nil || !result.IsZero() {
+		return result, err
+	}
 
-				return ctrl.Result{RequeueAfter: time.Duration(5 * time.Second)}, err
+	if result, err := r.reconcileTopology(ctx, cluster, topologyClient, stsList); err != nil || !result.IsZero() {
+		return result, err
+	}
+
+	for _, sts := range stsList.Items {
+		if !tarantool.IsDraining(&sts) || tarantool.IsExpelled(&sts) {
+			continue
+		}
+
+		stsLogger := reqLogger.WithValues("StatefulSet.Name", sts.GetName())
+
+		if !tarantool.IsScheduledDelete(&sts) {
+			currentWeight, err := topologyClient.GetWeight(ctx, sts.GetLabels()["tarantool.io/replicaset-uuid"])
+			if err != nil {
+				return topologyResult(ctx, err)
 			}
 
-			if tarantool.IsJoined(pod) {
+			if currentWeight > 0 {
+				stsLogger.Info("replicaset weight is not applied yet, waiting")
 				continue
 			}
 
-			if err := topologyClient.Join(pod); err != nil {
-				if topology.IsAlreadyJoined(err) {
-					tarantool.MarkJoined(pod)
-					if err := r.Update(context.TODO(), pod); err != nil {
-						return ctrl.Result{RequeueAfter: time.Duration(5 * time.Second)}, err
-					}
-					reqLogger.Info("Already joined", "Pod.Name", pod.Name)
-					continue
+			// replicasets without vshard-storage role report no weight and hold no buckets
+			if currentWeight == 0 {
+				data, err := topologyClient.GetServerStat(ctx)
+				if err != nil {
+					stsLogger.Error(err, "failed to get server stats")
+					return ctrl.Result{RequeueAfter: time.Duration(5 * time.Second)}, nil
 				}
 
-				if topology.IsTopologyDown(err) {
-					reqLogger.Info("Topology is down", "Pod.Name", pod.Name)
-					continue
+				bucketsCount := 0
+				for _, stat := range data.Stats {
+					if strings.HasPrefix(stat.URI, fmt.Sprintf("%s-", sts.GetName())) {
+						bucketsCount += stat.Statistics.BucketsCount
+					}
 				}
 
-				reqLogger.Error(err, "Join error")
-				return ctrl.Result{RequeueAfter: time.Duration(5 * time.Second)}, nil
-			} else {
-				tarantool.MarkJoined(pod)
-				if err := r.Update(context.TODO(), pod); err != nil {
-					return ctrl.Result{RequeueAfter: time.Duration(5 * time.Second)}, err
+				if bucketsCount > 0 {
+					stsLogger.Info("replicaset still has buckets, retry checking on next run", "buckets", bucketsCount)
+					continue
 				}
 			}
 
-			return ctrl.Result{RequeueAfter: time.Duration(5 * time.Second)}, nil
+			stsLogger.Info("replicaset has migrated all of its buckets away, schedule to remove")
+			tarantool.MarkScheduledDelete(&sts)
+			if err := r.Update(context.TODO(), &sts); err != nil {
+				return ctrl.Result{RequeueAfter: time.Duration(5 * time.Second)}, err
+			}
 		}
-	}
 
-	for _, sts := range stsList.Items {
-		stsAnnotations := sts.GetAnnotations()
-		weight := stsAnnotations["tarantool.io/replicaset-weight"]
-
-		current_weight, err := topologyClient.GetWeight(sts.GetLabels()["tarantool.io/replicaset-uuid"])
+		pods, err := r.getReplicasetPods(&sts)
 		if err != nil {
 			return ctrl.Result{RequeueAfter: time.Duration(5 * time.Second)}, err
 		}
 
-		if current_weight == -1 || strconv.Itoa(current_weight) == weight {
-			continue
-		}
-
-		if weight == "0" {
-			reqLogger.Info("weight is set to 0, checking replicaset buckets for scheduled deletion")
-			data, err := topologyClient.GetServerStat()
-			if err != nil {
-				reqLogger.Error(err, "failed to get server stats")
-			} else {
-				for i := 0; i < len(data.Stats); i++ {
-					if strings.HasPrefix(data.Stats[i].URI, sts.GetName()) {
-						reqLogger.Info("Found statefulset to check for buckets count", "sts.Name", sts.GetName())
-
-						bucketsCount := data.Stats[i].Statistics.BucketsCount
-						if bucketsCount == 0 {
-							reqLogger.Info("replicaset has migrated all of its buckets away, schedule to remove", "sts.Name", sts.GetName())
-
-							stsAnnotations["tarantool.io/scheduledDelete"] = "1"
-							sts.SetAnnotations(stsAnnotations)
-							if err := r.Update(context.TODO(), &sts); err != nil {
-								reqLogger.Error(err, "failed to set scheduled deletion annotation")
-							}
-						} else {
-							reqLogger.Info("replicaset still has buckets, retry checking on next run", "sts.Name", sts.GetName(), "buckets", bucketsCount)
-						}
-					}
-				}
+		var joined []*corev1.Pod
+		for _, pod := range pods {
+			if tarantool.IsJoined(pod) || tarantool.IsExpelling(pod) {
+				joined = append(joined, pod)
 			}
 		}
 
-		for i := 0; i < int(*sts.Spec.Replicas); i++ {
-			pod := &corev1.Pod{}
-			name := types.NamespacedName{
-				Namespace: req.Namespace,
-				Name:      fmt.Sprintf("%s-%d", sts.GetName(), i),
-			}
-
-			if err := r.Get(context.TODO(), name, pod); err != nil {
-				if errors.IsNotFound(err) {
-					return ctrl.Result{RequeueAfter: time.Duration(5 * time.Second)}, nil
-				}
-
+		if len(joined) > 0 {
+			stsLogger.Info("expelling instances", "count", len(joined))
+			expelErr := topologyClient.Expel(ctx, joined...)
+			if err := r.markExpelled(&sts, joined, expelErr); err != nil {
 				return ctrl.Result{RequeueAfter: time.Duration(5 * time.Second)}, err
 			}
-
-			if !tarantool.IsJoined(pod) {
-				reqLogger.Info("Not all instances joined, skip weight change", "StatefulSet.Name", sts.GetName())
-				return ctrl.Result{RequeueAfter: time.Duration(5 * time.Second)}, nil
+			if expelErr != nil {
+				stsLogger.Error(expelErr, "Expel error")
+				return topologyResult(ctx, expelErr)
 			}
 		}
 
-		if err := topologyClient.SetWeight(sts.GetLabels()["tarantool.io/replicaset-uuid"], weight); err != nil {
+		stsLogger.Info("all replicaset instances expelled, statefulset can be removed")
+		tarantool.MarkExpelled(&sts)
+		if err := r.Update(context.TODO(), &sts); err != nil {
 			return ctrl.Result{RequeueAfter: time.Duration(5 * time.Second)}, err
 		}
+
+		return ctrl.Result{Requeue: true}, nil
 	}
 
 	for _, sts := range stsList.Items {
-		replicasetUUID := sts.GetLabels()["tarantool.io/replicaset-uuid"]
-
-		actualRoles, err := topologyClient.GetReplicasetRolesFromService(replicasetUUID)
-		if err != nil {
-			reqLogger.Error(err, "Getting roles from server")
-			return ctrl.Result{RequeueAfter: time.Duration(5 * time.Second)}, err
-		}
-
-		desireRoles, err := topology.GetRoles(&sts.ObjectMeta)
-		if err != nil {
-			reqLogger.Error(err, "Getting roles from statefulset")
-			return ctrl.Result{RequeueAfter: time.Duration(5 * time.Second)}, err
-		}
-
-		if utils.IsRolesEquals(actualRoles, desireRoles) {
+		if tarantool.IsExpelled(&sts) {
 			continue
 		}
-		reqLogger.Info("Update replicaset roles", "id", replicasetUUID, "from", actualRoles, "to", desireRoles)
 
-		err = topologyClient.SetReplicasetRoles(replicasetUUID, desireRoles)
-		if err != nil {
-			reqLogger.Error(err, "Setting new replicaset roles")
-			return ctrl.Result{RequeueAfter: time.Duration(5 * time.Second)}, err
-		}
-	}
-
-	for _, sts := range stsList.Items {
 		stsAnnotations := sts.GetAnnotations()
 		if stsAnnotations["tarantool.io/isBootstrapped"] != "1" {
 			reqLogger.Info("cluster is not bootstrapped, bootstrapping", "Statefulset.Name", sts.GetName())
-			if err := topologyClient.BootstrapVshard(); err != nil {
+			if err := topologyClient.BootstrapVshard(ctx); err != nil {
 				if topology.IsAlreadyBootstrapped(err) {
 					stsAnnotations["tarantool.io/isBootstrapped"] = "1"
 					sts.SetAnnotations(stsAnnotations)
@@ -419,36 +454,32 @@ func (r *ClusterReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 
 					reqLogger.Info("Added bootstrapped annotation", "StatefulSet.Name", sts.GetName())
 
-					cluster.Status.State = "Ready"
-					err = r.Status().Update(context.TODO(), cluster)
-					if err != nil {
-						return ctrl.Result{RequeueAfter: time.Duration(5 * time.Second)}, err
-					}
 					return ctrl.Result{RequeueAfter: time.Duration(5 * time.Second)}, nil
 				}
 
 				reqLogger.Error(err, "Bootstrap vshard error")
-				return ctrl.Result{RequeueAfter: time.Duration(5 * time.Second)}, err
+				return topologyResult(ctx, err)
 			}
 		} else {
 			reqLogger.Info("cluster is already bootstrapped, not retrying", "Statefulset.Name", sts.GetName())
 		}
+	}
 
-		if stsAnnotations["tarantool.io/failoverEnabled"] == "1" {
-			reqLogger.Info("failover is enabled, not retrying")
-		} else {
-			if err := topologyClient.SetFailover(true); err != nil {
-				reqLogger.Error(err, "failed to enable cluster failover")
-			} else {
-				reqLogger.Info("enabled failover")
-
-				stsAnnotations["tarantool.io/failoverEnabled"] = "1"
-				sts.SetAnnotations(stsAnnotations)
-				if err := r.Update(context.TODO(), &sts); err != nil {
-					reqLogger.Error(err, "failed to set failover enabled annotation")
-				}
-			}
-		}
+	if err := r.reconcileFailover(ctx, cluster, topologyClient); err != nil {
+		reqLogger.Error(err, "failed to configure cluster failover")
+		return topologyResult(ctx, err)
+	}
+
+	if result, err := r.switchover(ctx, cluster, topologyClient, stsList); err != nil || !result.IsZero() {
+		return result, err
+	}
+
+	if result, err := r.rollout(ctx, cluster, topologyClient, stsList); err != nil || !result.IsZero() {
+		return result, err
+	}
+
+	if result, err := r.reconcileFailoverPriority(ctx, topologyClient, stsList); err != nil || !result.IsZero() {
+		return result, err
 	}
 
 	return ctrl.Result{RequeueAfter: time.Duration(5 * time.Second)}, nil
@@ -458,6 +489,7 @@ func (r *ClusterReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 func (r *ClusterReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&tarantooliov1alpha1.Cluster{}).
+		Owns(&appsv1.Deployment{}).
 		Watches(&source.Kind{Type: &tarantooliov1alpha1.Cluster{}}, &handler.EnqueueRequestForObject{}).
 		Watches(&source.Kind{Type: &corev1.Pod{}}, handler.EnqueueRequestsFromMapFunc(func(a client.Object) []reconcile.Request {
 			if a.GetLabels() == nil {
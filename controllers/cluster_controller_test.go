@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"math/rand"
+	"sync"
 	"time"
 
 	. "github.com/onsi/ginkgo"
@@ -11,10 +12,21 @@ import (
 
 	helpers "github.com/tarantool/tarantool-operator/test/helpers"
 
+	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
 
+	tarantooliov1alpha1 "github.com/tarantool/tarantool-operator/api/v1alpha1"
+	"github.com/tarantool/tarantool-operator/controllers/tarantool"
+	"github.com/tarantool/tarantool-operator/controllers/topology"
+
+	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 )
 
 var letterRunes = []rune("abcdefghijklmnopqrstuvwxyz")
@@ -97,28 +109,26 @@ var _ = Describe("cluster_controller unit testing", func() {
 		Context("manage cluster leader: tarantool instance accepting admin requests", func() {
 			It("change the leader if the previous one does not exist", func() {
 				By("get the chosen leader")
-				ep := corev1.Endpoints{}
+				cluster := &tarantooliov1alpha1.Cluster{}
 				Eventually(
 					func() bool {
-						err := k8sClient.Get(ctx, client.ObjectKey{Name: clusterName, Namespace: namespace}, &ep)
+						err := k8sClient.Get(ctx, client.ObjectKey{Name: clusterName, Namespace: namespace}, cluster)
 						if err != nil {
 							return false
 						}
 
-						if ep.GetAnnotations()["tarantool.io/leader"] != "" {
-							return true
-						}
-
-						return false
+						return cluster.Status.Leader != nil && cluster.Status.Leader.Reason != ""
 					},
 					2*time.Minute,
 					500*time.Millisecond,
 				).Should(BeTrue())
 
 				By("save old leader")
-				oldLeader := ep.GetAnnotations()["tarantool.io/leader"]
+				oldLeader := cluster.Status.Leader.Address
 
 				By("set all new IP addresses")
+				ep := corev1.Endpoints{}
+				Expect(k8sClient.Get(ctx, client.ObjectKey{Name: clusterName, Namespace: namespace}, &ep)).NotTo(HaveOccurred())
 				ep.Subsets = []corev1.EndpointSubset{
 					{
 						Addresses: []corev1.EndpointAddress{
@@ -133,15 +143,145 @@ var _ = Describe("cluster_controller unit testing", func() {
 				By("check that the leader has changed")
 				Eventually(
 					func() bool {
-						err := k8sClient.Get(ctx, client.ObjectKey{Name: clusterId, Namespace: namespace}, &ep)
+						err := k8sClient.Get(ctx, client.ObjectKey{Name: clusterId, Namespace: namespace}, cluster)
 						if err != nil {
 							return false
 						}
 
-						if ep.GetAnnotations()["tarantool.io/leader"] != oldLeader {
-							return true
+						return cluster.Status.Leader != nil && cluster.Status.Leader.Address != oldLeader
+					},
+					2*time.Minute,
+					500*time.Millisecond,
+				).Should(BeTrue())
+			})
+
+			It("does not keep the leader on the Endpoints object", func() {
+				ep := corev1.Endpoints{}
+				Consistently(
+					func() string {
+						if err := k8sClient.Get(ctx, client.ObjectKey{Name: clusterName, Namespace: namespace}, &ep); err != nil {
+							return ""
 						}
-						return false
+						return ep.GetAnnotations()["tarantool.io/leader"]
+					},
+					10*time.Second,
+					500*time.Millisecond,
+				).Should(BeEmpty())
+			})
+		})
+
+		Context("talk to Cartridge through the injected TopologyFactory", func() {
+			It("probe the leader and manage the topology with clients of the factory", func() {
+				By("wait for the leader to be chosen")
+				cluster := &tarantooliov1alpha1.Cluster{}
+				Eventually(
+					func() bool {
+						if err := k8sClient.Get(ctx, client.ObjectKey{Name: clusterName, Namespace: namespace}, cluster); err != nil {
+							return false
+						}
+						return cluster.Status.Leader != nil
+					},
+					2*time.Minute,
+					500*time.Millisecond,
+				).Should(BeTrue())
+				leaderURL := adminAPIURL(cluster, cluster.Status.Leader.Address)
+
+				var (
+					mu      sync.Mutex
+					created []topology.Options
+				)
+				reconciler := &ClusterReconciler{
+					Client: k8sClient,
+					Scheme: scheme.Scheme,
+					TopologyFactory: func(opts ...topology.Option) topology.TopologyService {
+						options := topology.Options{}
+						for _, opt := range opts {
+							opt(&options)
+						}
+						mu.Lock()
+						created = append(created, options)
+						mu.Unlock()
+						return fakeCartridge.Factory()(opts...)
+					},
+					RolloutStepTimeout: time.Minute,
+				}
+
+				selfCalls := fakeCartridge.Calls(helpers.FakeOpSelf)
+				// the manager reconciles the same Cluster, a status update lost to it does not matter here
+				_, _ = reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Name: clusterName, Namespace: namespace}})
+				Expect(fakeCartridge.Calls(helpers.FakeOpSelf)).To(BeNumerically(">", selfCalls))
+
+				mu.Lock()
+				defer mu.Unlock()
+				probed, managed := false, false
+				for _, options := range created {
+					if options.Endpoint != leaderURL {
+						continue
+					}
+					probed = probed || options.ClusterID == ""
+					managed = managed || options.ClusterID == clusterName
+				}
+				Expect(probed).To(BeTrue(), "the leader must be probed with a client of the factory")
+				Expect(managed).To(BeTrue(), "the topology must be managed with a client of the factory")
+			})
+		})
+
+		Context("report cluster health in status", func() {
+			It("set observed generation and topology conditions", func() {
+				cluster := &tarantooliov1alpha1.Cluster{}
+				Eventually(
+					func() bool {
+						err := k8sClient.Get(ctx, client.ObjectKey{Name: clusterName, Namespace: namespace}, cluster)
+						if err != nil {
+							return false
+						}
+
+						if cluster.Status.ObservedGeneration != cluster.GetGeneration() {
+							return false
+						}
+
+						return meta.FindStatusCondition(cluster.Status.Conditions, tarantooliov1alpha1.ClusterTopologyReachable) != nil &&
+							meta.FindStatusCondition(cluster.Status.Conditions, tarantooliov1alpha1.ClusterAllInstancesJoined) != nil
+					},
+					2*time.Minute,
+					500*time.Millisecond,
+				).Should(BeTrue())
+			})
+		})
+
+		Context("configure failover", func() {
+			It("revert failover params changed behind the operator", func() {
+				Eventually(fakeCartridge.IsFailoverEnabled, 5*time.Minute, time.Second).Should(BeTrue())
+
+				fakeCartridge.SetFailoverParams(topology.FailoverParams{Mode: topology.FailoverModeDisabled})
+				Eventually(
+					func() string {
+						return fakeCartridge.FailoverParams().Mode
+					},
+					2*time.Minute,
+					time.Second,
+				).Should(Equal(topology.FailoverModeEventual))
+			})
+		})
+
+		Context("manage instance pods", func() {
+			It("put the instance finalizer on every pod", func() {
+				Eventually(
+					func() bool {
+						pods := &corev1.PodList{}
+						if err := k8sClient.List(ctx, pods, client.InNamespace(namespace), client.MatchingLabels{"tarantool.io/cluster-id": clusterId}); err != nil {
+							return false
+						}
+						if len(pods.Items) == 0 {
+							return false
+						}
+
+						for _, pod := range pods.Items {
+							if !controllerutil.ContainsFinalizer(&pod, instanceFinalizer) {
+								return false
+							}
+						}
+						return true
 					},
 					2*time.Minute,
 					500*time.Millisecond,
@@ -150,63 +290,689 @@ var _ = Describe("cluster_controller unit testing", func() {
 		})
 	})
 
-	Describe("cluster_controller unit testing functions", func() {
-		Describe("function IsLeaderExists must check for existence of leader in annotation of cluster Endpoints", func() {
-			Context("positive cases (leader exist)", func() {
-				It("should return True if leader assigned and exist", func() {
-					leaderIP := "1.1.1.1"
-
-					ep := &corev1.Endpoints{
-						ObjectMeta: metav1.ObjectMeta{
-							Name:      "name",
-							Namespace: "namespace",
-							Annotations: map[string]string{
-								"tarantool.io/leader": fmt.Sprintf("%s:8081", leaderIP),
-							},
-						},
-						Subsets: []corev1.EndpointSubset{
-							{
-								Addresses: []corev1.EndpointAddress{
-									{IP: leaderIP},
-								},
-							},
-						},
+	Describe("cluster_controller tear down a deleted cluster", func() {
+		var (
+			namespace   = "teardown"
+			clusterName = "teardown"
+			clusterId   = "teardown"
+			cartridge   = helpers.NewCartridge(helpers.CartridgeParams{
+				Namespace:   namespace,
+				ClusterName: clusterName,
+				ClusterID:   clusterId,
+			})
+		)
+
+		BeforeEach(func() {
+			Expect(k8sClient.Create(ctx, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespace}})).
+				NotTo(HaveOccurred(), fmt.Sprintf("failed to create Namespace %s", namespace))
+
+			cartridge.Cluster.Spec.Deletion = &tarantooliov1alpha1.DeletionSpec{Policy: tarantooliov1alpha1.DeletionPolicyRetain}
+			Expect(k8sClient.Create(ctx, cartridge.Cluster)).NotTo(HaveOccurred(), "failed to create Cluster")
+			for _, role := range cartridge.Roles {
+				Expect(k8sClient.Create(ctx, role)).NotTo(HaveOccurred(), "failed to create Role")
+			}
+			for _, rs := range cartridge.ReplicasetTemplates {
+				Expect(k8sClient.Create(ctx, rs)).NotTo(HaveOccurred(), "failed to create ReplicasetTemplate")
+			}
+			for _, svc := range cartridge.Services {
+				Expect(k8sClient.Create(ctx, svc)).NotTo(HaveOccurred(), "failed to create Service")
+			}
+		})
+
+		AfterEach(func() {
+			By("remove Namespace object " + namespace)
+			ns := &corev1.Namespace{}
+			Expect(k8sClient.Get(ctx, client.ObjectKey{Name: namespace}, ns)).NotTo(HaveOccurred(), "failed to get Namespace")
+			Expect(k8sClient.Delete(ctx, ns)).NotTo(HaveOccurred(), "failed to delete Namespace")
+		})
+
+		It("remove the replicasets before the Cluster and keep labeled volumes with the Retain policy", func() {
+			By("waiting for the finalizers")
+			Eventually(
+				func() bool {
+					cluster := &tarantooliov1alpha1.Cluster{}
+					if err := k8sClient.Get(ctx, client.ObjectKey{Name: clusterName, Namespace: namespace}, cluster); err != nil {
+						return false
 					}
-					Expect(IsLeaderExists(ep)).To(BeTrue())
-				})
+					pods := &corev1.PodList{}
+					if err := k8sClient.List(ctx, pods, client.InNamespace(namespace), client.MatchingLabels{"tarantool.io/cluster-id": clusterId}); err != nil {
+						return false
+					}
+					return controllerutil.ContainsFinalizer(cluster, clusterFinalizer) && len(pods.Items) > 0 &&
+						controllerutil.ContainsFinalizer(&pods.Items[0], instanceFinalizer)
+				},
+				2*time.Minute,
+				500*time.Millisecond,
+			).Should(BeTrue())
+
+			By("deleting the Cluster")
+			Expect(k8sClient.Delete(ctx, cartridge.Cluster)).NotTo(HaveOccurred())
+			Eventually(
+				func() bool {
+					cluster := &tarantooliov1alpha1.Cluster{}
+					err := k8sClient.Get(ctx, client.ObjectKey{Name: clusterName, Namespace: namespace}, cluster)
+					return apierrors.IsNotFound(err)
+				},
+				3*time.Minute,
+				time.Second,
+			).Should(BeTrue())
+
+			stsList := &appsv1.StatefulSetList{}
+			Expect(k8sClient.List(ctx, stsList, client.InNamespace(namespace), client.MatchingLabels{"tarantool.io/cluster-id": clusterId})).NotTo(HaveOccurred())
+			Expect(stsList.Items).To(BeEmpty(), "replicasets must be removed before the Cluster")
+
+			pvcs := &corev1.PersistentVolumeClaimList{}
+			Expect(k8sClient.List(ctx, pvcs, client.InNamespace(namespace), client.MatchingLabels{"tarantool.io/cluster-id": clusterName})).NotTo(HaveOccurred())
+			Expect(pvcs.Items).NotTo(BeEmpty(), "retained volumes must be labeled with the cluster")
+			for _, pvc := range pvcs.Items {
+				Expect(pvc.GetLabels()["tarantool.io/instance-uuid"]).NotTo(BeEmpty())
+			}
+		})
+	})
+
+	Describe("cluster_controller roll out a replicaset against the fake Cartridge", func() {
+		var (
+			namespace    = "rollout"
+			clusterName  = "rollout"
+			clusterId    = "rollout"
+			roleName     = "rollout-storage"
+			templateName = "rollout-storage-template"
+			stsName      = "rollout-storage-0"
+		)
+
+		BeforeEach(func() {
+			Expect(k8sClient.Create(ctx, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespace}})).
+				NotTo(HaveOccurred(), fmt.Sprintf("failed to create Namespace %s", namespace))
+
+			cluster := helpers.NewCluster(helpers.ClusterParams{Namespace: namespace, Name: clusterName, Id: clusterId})
+			Expect(k8sClient.Create(ctx, &cluster)).NotTo(HaveOccurred(), "failed to create Cluster")
+
+			role := helpers.NewRole(helpers.RoleParams{
+				Name:           roleName,
+				Namespace:      namespace,
+				ClusterId:      clusterId,
+				RolesToAssign:  "[\"app.roles.storage\"]",
+				RsNum:          1,
+				RsTemplateName: templateName,
 			})
+			Expect(k8sClient.Create(ctx, &role)).NotTo(HaveOccurred(), "failed to create Role")
 
-			Context("negative cases (leader does not exist)", func() {
-				It("should return False if leader not assigned", func() {
-					ep := &corev1.Endpoints{
-						ObjectMeta: metav1.ObjectMeta{
-							Name:      "name",
-							Namespace: "namespace",
-						},
+			template := helpers.NewReplicasetTemplate(helpers.ReplicasetTemplateParams{
+				Name:            templateName,
+				Namespace:       namespace,
+				ClusterId:       clusterId,
+				RoleName:        roleName,
+				RolesToAssign:   "[\"app.roles.storage\"]",
+				PodTemplateName: templateName,
+				ContainerName:   "pim-storage",
+				ContainerImage:  "tarantool/tarantool-operator-examples-kv:0.0.4",
+				ServiceName:     roleName,
+			})
+			replicas := int32(2)
+			template.Spec.Replicas = &replicas
+			Expect(k8sClient.Create(ctx, &template)).NotTo(HaveOccurred(), "failed to create ReplicasetTemplate")
+
+			svc := helpers.NewService(helpers.ServiceParams{Name: roleName, Namespace: namespace, RoleName: roleName})
+			Expect(k8sClient.Create(ctx, &svc)).NotTo(HaveOccurred(), "failed to create Service")
+		})
+
+		AfterEach(func() {
+			By("remove Namespace object " + namespace)
+			ns := &corev1.Namespace{}
+			Expect(k8sClient.Get(ctx, client.ObjectKey{Name: namespace}, ns)).NotTo(HaveOccurred(), "failed to get Namespace")
+			Expect(k8sClient.Delete(ctx, ns)).NotTo(HaveOccurred(), "failed to delete Namespace")
+		})
+
+		// getPods returns the replicaset pods by ordinal once every one of them is ready and joined
+		getPods := func() []*corev1.Pod {
+			pods := []*corev1.Pod{}
+			for i := 0; i < 2; i++ {
+				pod := &corev1.Pod{}
+				if err := k8sClient.Get(ctx, client.ObjectKey{Name: fmt.Sprintf("%s-%d", stsName, i), Namespace: namespace}, pod); err != nil {
+					return nil
+				}
+				if pod.GetDeletionTimestamp() != nil || !tarantool.IsReady(pod) || !tarantool.IsJoined(pod) {
+					return nil
+				}
+				pods = append(pods, pod)
+			}
+			return pods
+		}
+
+		// allUpdated reports whether every pod runs the current revision of the StatefulSet
+		allUpdated := func() bool {
+			sts := &appsv1.StatefulSet{}
+			if err := k8sClient.Get(ctx, client.ObjectKey{Name: stsName, Namespace: namespace}, sts); err != nil {
+				return false
+			}
+			pods := getPods()
+			if pods == nil || sts.Status.UpdateRevision == "" {
+				return false
+			}
+			for _, pod := range pods {
+				if !tarantool.IsUpdated(pod, sts) {
+					return false
+				}
+			}
+			return true
+		}
+
+		// setEnv changes the pod template of the ReplicasetTemplate
+		setEnv := func(value string) {
+			Eventually(
+				func() error {
+					template := &tarantooliov1alpha1.ReplicasetTemplate{}
+					if err := k8sClient.Get(ctx, client.ObjectKey{Name: templateName, Namespace: namespace}, template); err != nil {
+						return err
 					}
-					Expect(IsLeaderExists(ep)).To(BeFalse())
-				})
-
-				It("should return False if leader assigned, but IP not exists", func() {
-					ep := &corev1.Endpoints{
-						ObjectMeta: metav1.ObjectMeta{
-							Name:      "name",
-							Namespace: "namespace",
-							Annotations: map[string]string{
-								"tarantool.io/leader": "6.6.6.6:8081",
-							},
-						},
-						Subsets: []corev1.EndpointSubset{
-							{
-								Addresses: []corev1.EndpointAddress{
-									{IP: "0.0.0.0"},
-								},
-							},
+					container := &template.Spec.Template.Spec.Containers[0]
+					container.Env = append(container.Env, corev1.EnvVar{Name: "ROLLOUT_" + value, Value: value})
+					return k8sClient.Update(ctx, template)
+				},
+				time.Minute,
+				time.Second,
+			).Should(Succeed())
+		}
+
+		It("restart replicas first, switch the master before its restart and pause on an unhealthy instance", func() {
+			By("waiting for both instances to join")
+			var pods []*corev1.Pod
+			Eventually(func() []*corev1.Pod { pods = getPods(); return pods }, 5*time.Minute, time.Second).Should(HaveLen(2))
+
+			sts := &appsv1.StatefulSet{}
+			Expect(k8sClient.Get(ctx, client.ObjectKey{Name: stsName, Namespace: namespace}, sts)).NotTo(HaveOccurred())
+			replicasetUUID := sts.GetLabels()["tarantool.io/replicaset-uuid"]
+
+			replicaset, ok := fakeCartridge.Replicaset(replicasetUUID)
+			Expect(ok).To(BeTrue(), "replicaset was not joined")
+			masterUUID := replicaset.Servers[0]
+			master, replica := pods[0], pods[1]
+			if instanceUUID(master) != masterUUID {
+				master, replica = replica, master
+			}
+
+			By("changing the pod template")
+			setEnv("1")
+
+			By("switching the master to the updated replica")
+			Eventually(
+				func() string {
+					replicaset, _ := fakeCartridge.Replicaset(replicasetUUID)
+					return replicaset.Servers[0]
+				},
+				5*time.Minute,
+				time.Second,
+			).Should(Equal(instanceUUID(replica)))
+
+			updatedReplica := &corev1.Pod{}
+			Expect(k8sClient.Get(ctx, client.ObjectKey{Name: replica.GetName(), Namespace: namespace}, updatedReplica)).NotTo(HaveOccurred())
+			Expect(updatedReplica.GetUID()).NotTo(Equal(replica.GetUID()), "the replica must be restarted before the master is switched")
+
+			By("restarting the former master")
+			Eventually(allUpdated, 5*time.Minute, time.Second).Should(BeTrue())
+			restartedMaster := &corev1.Pod{}
+			Expect(k8sClient.Get(ctx, client.ObjectKey{Name: master.GetName(), Namespace: namespace}, restartedMaster)).NotTo(HaveOccurred())
+			Expect(restartedMaster.GetUID()).NotTo(Equal(master.GetUID()))
+
+			By("pausing when the restarted instance stays unhealthy")
+			setEnv("2")
+			var restarted string
+			Eventually(
+				func() string {
+					if err := k8sClient.Get(ctx, client.ObjectKey{Name: stsName, Namespace: namespace}, sts); err != nil {
+						return ""
+					}
+					restarted, _, _ = tarantool.GetRolloutStep(sts)
+					return restarted
+				},
+				5*time.Minute,
+				500*time.Millisecond,
+			).ShouldNot(BeEmpty())
+
+			restartedPod := &corev1.Pod{}
+			Expect(k8sClient.Get(ctx, client.ObjectKey{Name: restarted, Namespace: namespace}, restartedPod)).NotTo(HaveOccurred())
+			fakeCartridge.SetServerStatus(instanceUUID(restartedPod), "unhealthy")
+
+			Eventually(
+				func() bool {
+					cluster := &tarantooliov1alpha1.Cluster{}
+					if err := k8sClient.Get(ctx, client.ObjectKey{Name: clusterName, Namespace: namespace}, cluster); err != nil {
+						return false
+					}
+					return meta.IsStatusConditionTrue(cluster.Status.Conditions, tarantooliov1alpha1.ClusterRollingUpdatePaused)
+				},
+				5*time.Minute,
+				time.Second,
+			).Should(BeTrue())
+			Consistently(allUpdated, 15*time.Second, time.Second).Should(BeFalse())
+
+			By("resuming once the instance is healthy and the pause is removed")
+			fakeCartridge.SetServerStatus(instanceUUID(restartedPod), "healthy")
+			Eventually(
+				func() error {
+					if err := k8sClient.Get(ctx, client.ObjectKey{Name: stsName, Namespace: namespace}, sts); err != nil {
+						return err
+					}
+					delete(sts.Annotations, "tarantool.io/rolloutPaused")
+					return k8sClient.Update(ctx, sts)
+				},
+				time.Minute,
+				time.Second,
+			).Should(Succeed())
+			Eventually(allUpdated, 5*time.Minute, time.Second).Should(BeTrue())
+		})
+	})
+
+	Describe("cluster_controller import an existing cluster", func() {
+		var (
+			namespace      = "import"
+			clusterName    = "import"
+			clusterId      = "import"
+			roleName       = "import-storage"
+			templateName   = "import-storage-template"
+			stsName        = "import-storage-0"
+			replicasetUUID = "4f8a5e0c-8a53-4a4e-9f5b-0d2c7d1b3e01"
+			masterUUID     = "4f8a5e0c-8a53-4a4e-9f5b-0d2c7d1b3e02"
+		)
+
+		BeforeEach(func() {
+			Expect(k8sClient.Create(ctx, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespace}})).
+				NotTo(HaveOccurred(), fmt.Sprintf("failed to create Namespace %s", namespace))
+
+			weight := 100.0
+			fakeCartridge.AddReplicaset(
+				helpers.FakeReplicaset{UUID: replicasetUUID, Alias: stsName, Roles: []string{"app.roles.storage", "vshard-storage"}, Weight: &weight},
+				helpers.FakeServer{
+					UUID:         masterUUID,
+					URI:          fmt.Sprintf("%s-0.%s.%s.svc.cluster.local:3301", stsName, clusterName, namespace),
+					BucketsCount: helpers.DefaultFakeBucketCount,
+				},
+			)
+
+			cluster := helpers.NewCluster(helpers.ClusterParams{Namespace: namespace, Name: clusterName, Id: clusterId})
+			cluster.Spec.Import = true
+			Expect(k8sClient.Create(ctx, &cluster)).NotTo(HaveOccurred(), "failed to create Cluster")
+
+			role := helpers.NewRole(helpers.RoleParams{
+				Name:           roleName,
+				Namespace:      namespace,
+				ClusterId:      clusterId,
+				RolesToAssign:  "[\"app.roles.storage\", \"vshard-storage\"]",
+				RsNum:          1,
+				RsTemplateName: templateName,
+			})
+			Expect(k8sClient.Create(ctx, &role)).NotTo(HaveOccurred(), "failed to create Role")
+
+			template := helpers.NewReplicasetTemplate(helpers.ReplicasetTemplateParams{
+				Name:            templateName,
+				Namespace:       namespace,
+				ClusterId:       clusterId,
+				RoleName:        roleName,
+				RolesToAssign:   "[\"app.roles.storage\", \"vshard-storage\"]",
+				PodTemplateName: templateName,
+				ContainerName:   "pim-storage",
+				ContainerImage:  "tarantool/tarantool-operator-examples-kv:0.0.4",
+				ServiceName:     roleName,
+			})
+			replicas := int32(2)
+			template.Spec.Replicas = &replicas
+			Expect(k8sClient.Create(ctx, &template)).NotTo(HaveOccurred(), "failed to create ReplicasetTemplate")
+
+			svc := helpers.NewService(helpers.ServiceParams{Name: roleName, Namespace: namespace, RoleName: roleName})
+			Expect(k8sClient.Create(ctx, &svc)).NotTo(HaveOccurred(), "failed to create Service")
+		})
+
+		AfterEach(func() {
+			By("remove Namespace object " + namespace)
+			ns := &corev1.Namespace{}
+			Expect(k8sClient.Get(ctx, client.ObjectKey{Name: namespace}, ns)).NotTo(HaveOccurred(), "failed to get Namespace")
+			Expect(k8sClient.Delete(ctx, ns)).NotTo(HaveOccurred(), "failed to delete Namespace")
+		})
+
+		It("keep the uuids of the topology and join only the missing instance", func() {
+			bootstrapCalls := fakeCartridge.Calls(helpers.FakeOpBootstrapVshard)
+
+			By("waiting for the replicaset to be imported")
+			sts := &appsv1.StatefulSet{}
+			Eventually(
+				func() bool {
+					if err := k8sClient.Get(ctx, client.ObjectKey{Name: stsName, Namespace: namespace}, sts); err != nil {
+						return false
+					}
+					return tarantool.IsImported(sts)
+				},
+				5*time.Minute,
+				time.Second,
+			).Should(BeTrue())
+			Expect(sts.GetLabels()["tarantool.io/replicaset-uuid"]).To(Equal(replicasetUUID))
+			Expect(sts.GetAnnotations()["tarantool.io/replicaset-weight"]).To(Equal("100"))
+			Expect(sts.GetAnnotations()["tarantool.io/isBootstrapped"]).To(Equal("1"))
+
+			By("waiting for the imported instance to keep its uuid")
+			Eventually(
+				func() string {
+					pod := &corev1.Pod{}
+					if err := k8sClient.Get(ctx, client.ObjectKey{Name: stsName + "-0", Namespace: namespace}, pod); err != nil {
+						return ""
+					}
+					return instanceUUID(pod)
+				},
+				5*time.Minute,
+				time.Second,
+			).Should(Equal(masterUUID))
+
+			By("joining the other instance to the imported replicaset")
+			Eventually(
+				func() []string {
+					replicaset, _ := fakeCartridge.Replicaset(replicasetUUID)
+					return replicaset.Servers
+				},
+				5*time.Minute,
+				time.Second,
+			).Should(HaveLen(2))
+
+			replicaset, _ := fakeCartridge.Replicaset(replicasetUUID)
+			Expect(replicaset.Servers[0]).To(Equal(masterUUID), "the imported master must stay the master")
+			Expect(fakeCartridge.Calls(helpers.FakeOpBootstrapVshard)).To(Equal(bootstrapCalls), "an imported cluster must not be bootstrapped again")
+		})
+	})
+
+	Describe("cluster_controller run a managed stateboard", func() {
+		var (
+			namespace   = "stateboard"
+			clusterName = "stateboard"
+			clusterId   = "stateboard"
+		)
+
+		BeforeEach(func() {
+			Expect(k8sClient.Create(ctx, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespace}})).
+				NotTo(HaveOccurred(), fmt.Sprintf("failed to create Namespace %s", namespace))
+
+			cluster := helpers.NewCluster(helpers.ClusterParams{Namespace: namespace, Name: clusterName, Id: clusterId})
+			cluster.Spec.Failover = &tarantooliov1alpha1.FailoverSpec{
+				Mode: tarantooliov1alpha1.FailoverModeStateful,
+				StateProvider: &tarantooliov1alpha1.FailoverStateProviderSpec{
+					Type: tarantooliov1alpha1.FailoverStateProviderStateboard,
+					Stateboard: &tarantooliov1alpha1.StateboardSpec{
+						Managed: &tarantooliov1alpha1.ManagedStateboardSpec{
+							Image: "tarantool/tarantool-operator-examples-kv:0.0.4",
 						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, &cluster)).NotTo(HaveOccurred(), "failed to create Cluster")
+		})
+
+		AfterEach(func() {
+			By("remove Namespace object " + namespace)
+			ns := &corev1.Namespace{}
+			Expect(k8sClient.Get(ctx, client.ObjectKey{Name: namespace}, ns)).NotTo(HaveOccurred(), "failed to get Namespace")
+			Expect(k8sClient.Delete(ctx, ns)).NotTo(HaveOccurred(), "failed to delete Namespace")
+		})
+
+		It("create the stateboard owned by the Cluster and report it in status", func() {
+			name := client.ObjectKey{Name: clusterName + "-stateboard", Namespace: namespace}
+
+			By("waiting for the stateboard objects")
+			deployment := &appsv1.Deployment{}
+			Eventually(func() error { return k8sClient.Get(ctx, name, deployment) }, 2*time.Minute, time.Second).Should(Succeed())
+			Expect(deployment.GetLabels()).NotTo(HaveKey("tarantool.io/cluster-id"), "the stateboard must not be taken for an instance")
+
+			Expect(k8sClient.Get(ctx, name, &corev1.Service{})).To(Succeed())
+			secret := &corev1.Secret{}
+			Expect(k8sClient.Get(ctx, name, secret)).To(Succeed())
+			Expect(secret.Data[stateboardPasswordKey]).NotTo(BeEmpty())
+
+			cluster := &tarantooliov1alpha1.Cluster{}
+			Expect(k8sClient.Get(ctx, client.ObjectKey{Name: clusterName, Namespace: namespace}, cluster)).To(Succeed())
+			Expect(metav1.IsControlledBy(deployment, cluster)).To(BeTrue())
+
+			By("reporting the stateboard in status")
+			Eventually(
+				func() string {
+					if err := k8sClient.Get(ctx, client.ObjectKey{Name: clusterName, Namespace: namespace}, cluster); err != nil {
+						return ""
+					}
+					if cluster.Status.Stateboard == nil {
+						return ""
+					}
+					return cluster.Status.Stateboard.URI
+				},
+				2*time.Minute,
+				time.Second,
+			).Should(Equal(fmt.Sprintf("%s-stateboard.%s.svc.cluster.local:4401", clusterName, namespace)))
+			Expect(meta.FindStatusCondition(cluster.Status.Conditions, tarantooliov1alpha1.ClusterStateboardReady)).NotTo(BeNil())
+		})
+	})
+
+	Describe("cluster_controller switch the master on request", func() {
+		var (
+			namespace    = "switchover"
+			clusterName  = "switchover"
+			clusterId    = "switchover"
+			roleName     = "switchover-storage"
+			templateName = "switchover-storage-template"
+			stsName      = "switchover-storage-0"
+		)
+
+		BeforeEach(func() {
+			Expect(k8sClient.Create(ctx, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespace}})).
+				NotTo(HaveOccurred(), fmt.Sprintf("failed to create Namespace %s", namespace))
+
+			cluster := helpers.NewCluster(helpers.ClusterParams{Namespace: namespace, Name: clusterName, Id: clusterId})
+			Expect(k8sClient.Create(ctx, &cluster)).NotTo(HaveOccurred(), "failed to create Cluster")
+
+			role := helpers.NewRole(helpers.RoleParams{
+				Name:           roleName,
+				Namespace:      namespace,
+				ClusterId:      clusterId,
+				RolesToAssign:  "[\"app.roles.storage\"]",
+				RsNum:          1,
+				RsTemplateName: templateName,
+			})
+			Expect(k8sClient.Create(ctx, &role)).NotTo(HaveOccurred(), "failed to create Role")
+
+			template := helpers.NewReplicasetTemplate(helpers.ReplicasetTemplateParams{
+				Name:            templateName,
+				Namespace:       namespace,
+				ClusterId:       clusterId,
+				RoleName:        roleName,
+				RolesToAssign:   "[\"app.roles.storage\"]",
+				PodTemplateName: templateName,
+				ContainerName:   "pim-storage",
+				ContainerImage:  "tarantool/tarantool-operator-examples-kv:0.0.4",
+				ServiceName:     roleName,
+			})
+			replicas := int32(2)
+			template.Spec.Replicas = &replicas
+			Expect(k8sClient.Create(ctx, &template)).NotTo(HaveOccurred(), "failed to create ReplicasetTemplate")
+
+			svc := helpers.NewService(helpers.ServiceParams{Name: roleName, Namespace: namespace, RoleName: roleName})
+			Expect(k8sClient.Create(ctx, &svc)).NotTo(HaveOccurred(), "failed to create Service")
+		})
+
+		AfterEach(func() {
+			By("remove Namespace object " + namespace)
+			ns := &corev1.Namespace{}
+			Expect(k8sClient.Get(ctx, client.ObjectKey{Name: namespace}, ns)).NotTo(HaveOccurred(), "failed to get Namespace")
+			Expect(k8sClient.Delete(ctx, ns)).NotTo(HaveOccurred(), "failed to delete Namespace")
+		})
+
+		// requestSwitchover annotates the StatefulSet with the pod to make the master
+		requestSwitchover := func(podName string) {
+			Eventually(
+				func() error {
+					sts := &appsv1.StatefulSet{}
+					if err := k8sClient.Get(ctx, client.ObjectKey{Name: stsName, Namespace: namespace}, sts); err != nil {
+						return err
+					}
+					sts.Annotations["tarantool.io/switchover"] = podName
+					return k8sClient.Update(ctx, sts)
+				},
+				time.Minute,
+				time.Second,
+			).Should(Succeed())
+		}
+
+		// lastSwitchover returns the outcome recorded on the StatefulSet for the pod
+		lastSwitchover := func(podName string) string {
+			sts := &appsv1.StatefulSet{}
+			if err := k8sClient.Get(ctx, client.ObjectKey{Name: stsName, Namespace: namespace}, sts); err != nil {
+				return ""
+			}
+			outcome, ok := tarantool.GetSwitchoverOutcome(sts)
+			if !ok || outcome.Target != podName {
+				return ""
+			}
+			return outcome.Result
+		}
+
+		It("make the requested replica the master, keep it pinned and refuse an unknown pod", func() {
+			By("waiting for both instances to join")
+			var replicasetUUID string
+			Eventually(
+				func() []string {
+					sts := &appsv1.StatefulSet{}
+					if err := k8sClient.Get(ctx, client.ObjectKey{Name: stsName, Namespace: namespace}, sts); err != nil {
+						return nil
 					}
-					Expect(IsLeaderExists(ep)).To(BeFalse())
-				})
+					replicasetUUID = sts.GetLabels()["tarantool.io/replicaset-uuid"]
+					replicaset, _ := fakeCartridge.Replicaset(replicasetUUID)
+					return replicaset.Servers
+				},
+				5*time.Minute,
+				time.Second,
+			).Should(HaveLen(2))
+
+			replicaset, _ := fakeCartridge.Replicaset(replicasetUUID)
+			var replica *corev1.Pod
+			for i := 0; i < 2; i++ {
+				pod := &corev1.Pod{}
+				Expect(k8sClient.Get(ctx, client.ObjectKey{Name: fmt.Sprintf("%s-%d", stsName, i), Namespace: namespace}, pod)).To(Succeed())
+				if instanceUUID(pod) != replicaset.Servers[0] {
+					replica = pod
+				}
+			}
+			Expect(replica).NotTo(BeNil())
+
+			By("requesting the replica to become the master")
+			requestSwitchover(replica.GetName())
+			Eventually(func() string { return lastSwitchover(replica.GetName()) }, 5*time.Minute, time.Second).
+				Should(Equal(tarantool.SwitchoverSucceeded))
+			replicaset, _ = fakeCartridge.Replicaset(replicasetUUID)
+			Expect(replicaset.Servers[0]).To(Equal(instanceUUID(replica)))
+
+			By("reporting the master in status")
+			Eventually(
+				func() string {
+					cluster := &tarantooliov1alpha1.Cluster{}
+					if err := k8sClient.Get(ctx, client.ObjectKey{Name: clusterName, Namespace: namespace}, cluster); err != nil || len(cluster.Status.Replicasets) == 0 {
+						return ""
+					}
+					return cluster.Status.Replicasets[0].Master
+				},
+				time.Minute,
+				time.Second,
+			).Should(Equal(replica.GetName()))
+
+			By("keeping the pinned master first in the failover priority")
+			Consistently(
+				func() string {
+					replicaset, _ := fakeCartridge.Replicaset(replicasetUUID)
+					return replicaset.Servers[0]
+				},
+				15*time.Second,
+				time.Second,
+			).Should(Equal(instanceUUID(replica)))
+
+			By("refusing a pod of another replicaset")
+			requestSwitchover("other-storage-0-0")
+			Eventually(func() string { return lastSwitchover("other-storage-0-0") }, 5*time.Minute, time.Second).
+				Should(Equal(tarantool.SwitchoverFailed))
+		})
+	})
+
+	Describe("cluster_controller place instances in Cartridge zones", func() {
+		var (
+			namespace    = "placement"
+			clusterName  = "placement"
+			clusterId    = "placement"
+			roleName     = "placement-storage"
+			templateName = "placement-storage-template"
+			stsName      = "placement-storage-0"
+		)
+
+		BeforeEach(func() {
+			Expect(k8sClient.Create(ctx, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespace}})).
+				NotTo(HaveOccurred(), fmt.Sprintf("failed to create Namespace %s", namespace))
+
+			cluster := helpers.NewCluster(helpers.ClusterParams{Namespace: namespace, Name: clusterName, Id: clusterId})
+			cluster.Spec.ServerLabels = []string{"tarantool.io/role"}
+			Expect(k8sClient.Create(ctx, &cluster)).NotTo(HaveOccurred(), "failed to create Cluster")
+
+			role := helpers.NewRole(helpers.RoleParams{
+				Name:           roleName,
+				Namespace:      namespace,
+				ClusterId:      clusterId,
+				RolesToAssign:  "[\"app.roles.storage\"]",
+				RsNum:          1,
+				RsTemplateName: templateName,
 			})
+			Expect(k8sClient.Create(ctx, &role)).NotTo(HaveOccurred(), "failed to create Role")
+
+			template := helpers.NewReplicasetTemplate(helpers.ReplicasetTemplateParams{
+				Name:            templateName,
+				Namespace:       namespace,
+				ClusterId:       clusterId,
+				RoleName:        roleName,
+				RolesToAssign:   "[\"app.roles.storage\"]",
+				PodTemplateName: templateName,
+				ContainerName:   "pim-storage",
+				ContainerImage:  "tarantool/tarantool-operator-examples-kv:0.0.4",
+				ServiceName:     roleName,
+			})
+			Expect(k8sClient.Create(ctx, &template)).NotTo(HaveOccurred(), "failed to create ReplicasetTemplate")
+
+			svc := helpers.NewService(helpers.ServiceParams{Name: roleName, Namespace: namespace, RoleName: roleName})
+			Expect(k8sClient.Create(ctx, &svc)).NotTo(HaveOccurred(), "failed to create Service")
+		})
+
+		AfterEach(func() {
+			By("remove Namespace object " + namespace)
+			ns := &corev1.Namespace{}
+			Expect(k8sClient.Get(ctx, client.ObjectKey{Name: namespace}, ns)).NotTo(HaveOccurred(), "failed to get Namespace")
+			Expect(k8sClient.Delete(ctx, ns)).NotTo(HaveOccurred(), "failed to delete Namespace")
+		})
+
+		It("copy the node zone and the selected pod labels to the instance", func() {
+			By("waiting for the instance to join")
+			pod := &corev1.Pod{}
+			Eventually(
+				func() bool {
+					if err := k8sClient.Get(ctx, client.ObjectKey{Name: stsName + "-0", Namespace: namespace}, pod); err != nil {
+						return false
+					}
+					return tarantool.IsJoined(pod)
+				},
+				5*time.Minute,
+				time.Second,
+			).Should(BeTrue())
+
+			node := &corev1.Node{}
+			Expect(k8sClient.Get(ctx, client.ObjectKey{Name: pod.Spec.NodeName}, node)).To(Succeed())
+
+			By("setting the labels and the zone of the instance")
+			Eventually(
+				func() map[string]string {
+					server, _ := fakeCartridge.Server(instanceUUID(pod))
+					return server.Labels
+				},
+				time.Minute,
+				time.Second,
+			).Should(HaveKeyWithValue("tarantool.io/role", roleName))
+
+			server, _ := fakeCartridge.Server(instanceUUID(pod))
+			Expect(server.Zone).To(Equal(node.GetLabels()[corev1.LabelTopologyZone]))
 		})
 	})
 })
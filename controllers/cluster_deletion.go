@@ -0,0 +1,312 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	tarantooliov1alpha1 "github.com/tarantool/tarantool-operator/api/v1alpha1"
+	"github.com/tarantool/tarantool-operator/controllers/tarantool"
+)
+
+// clusterFinalizer keeps a deleted Cluster until its teardown is over
+const clusterFinalizer = "tarantool.io/cluster"
+
+// volumeSnapshotGVK is the CSI VolumeSnapshot, used unstructured so the operator does not depend on the snapshot CRDs
+var volumeSnapshotGVK = schema.GroupVersionKind{Group: "snapshot.storage.k8s.io", Version: "v1", Kind: "VolumeSnapshot"}
+
+// addClusterFinalizer puts the finalizer on the Cluster, so its deletion waits for the teardown
+func (r *ClusterReconciler) addClusterFinalizer(cluster *tarantooliov1alpha1.Cluster) error {
+	if controllerutil.ContainsFinalizer(cluster, clusterFinalizer) {
+		return nil
+	}
+
+	patch := client.MergeFrom(cluster.DeepCopy())
+	controllerutil.AddFinalizer(cluster, clusterFinalizer)
+	return r.Patch(context.TODO(), cluster, patch)
+}
+
+// tearDown removes a deleted Cluster one phase at a time following its deletion policy.
+// The policy is recorded in the status when the teardown starts, changing the spec does not affect it afterwards.
+// Every policy labels the data volumes with the cluster, replicaset and instance they belong to and removes
+// the Roles and StatefulSets before the Cluster, so no replicaset outlives the topology it belongs to.
+// Instances are not expelled from Cartridge: the whole topology goes away with them and Cartridge refuses
+// to expel storages holding buckets, their pods are marked expelled instead unless the data is retained.
+func (r *ClusterReconciler) tearDown(ctx context.Context, cluster *tarantooliov1alpha1.Cluster) (ctrl.Result, error) {
+	reqLogger := log.FromContext(ctx)
+
+	if !controllerutil.ContainsFinalizer(cluster, clusterFinalizer) {
+		return ctrl.Result{}, nil
+	}
+
+	status := cluster.Status.Deletion.DeepCopy()
+	if status == nil {
+		status = &tarantooliov1alpha1.DeletionStatus{
+			Policy: cluster.Spec.Deletion.Policy,
+			Phase:  tarantooliov1alpha1.DeletionPhaseReleasingInstances,
+		}
+		reqLogger.Info("tearing down deleted cluster", "policy", status.Policy)
+	}
+	status.Message = ""
+
+	switch status.Phase {
+	case tarantooliov1alpha1.DeletionPhaseReleasingInstances:
+		volumes, err := r.labelClusterVolumes(cluster, status.Policy == tarantooliov1alpha1.DeletionPolicyRetain)
+		if err != nil {
+			return ctrl.Result{RequeueAfter: time.Duration(5 * time.Second)}, err
+		}
+		status.Volumes = volumes
+
+		if err := r.releaseClusterInstances(cluster, status.Policy != tarantooliov1alpha1.DeletionPolicyRetain); err != nil {
+			return ctrl.Result{RequeueAfter: time.Duration(5 * time.Second)}, err
+		}
+		status.Phase = tarantooliov1alpha1.DeletionPhaseDeletingReplicasets
+
+	case tarantooliov1alpha1.DeletionPhaseDeletingReplicasets:
+		remaining, err := r.deleteClusterReplicasets(cluster)
+		if err != nil {
+			return ctrl.Result{RequeueAfter: time.Duration(5 * time.Second)}, err
+		}
+		if remaining > 0 {
+			status.Message = fmt.Sprintf("waiting for %d pods to stop", remaining)
+			break
+		}
+
+		switch {
+		case status.Policy == tarantooliov1alpha1.DeletionPolicySnapshotThenDelete:
+			status.Phase = tarantooliov1alpha1.DeletionPhaseSnapshottingVolumes
+		case status.Policy == tarantooliov1alpha1.DeletionPolicyDelete && cluster.Spec.Deletion.DeleteVolumes:
+			status.Phase = tarantooliov1alpha1.DeletionPhaseDeletingVolumes
+		default:
+			status.Phase = tarantooliov1alpha1.DeletionPhaseCompleted
+		}
+
+	case tarantooliov1alpha1.DeletionPhaseSnapshottingVolumes:
+		snapshots, pending, err := r.snapshotClusterVolumes(cluster, status.Volumes)
+		if err != nil {
+			status.Message = err.Error()
+			break
+		}
+		status.Snapshots = snapshots
+		if len(pending) > 0 {
+			status.Message = fmt.Sprintf("waiting for snapshots to be ready: %v", pending)
+			break
+		}
+		status.Phase = tarantooliov1alpha1.DeletionPhaseDeletingVolumes
+
+	case tarantooliov1alpha1.DeletionPhaseDeletingVolumes:
+		for _, name := range status.Volumes {
+			pvc := &corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Namespace: cluster.GetNamespace(), Name: name}}
+			if err := r.Delete(context.TODO(), pvc); err != nil && !errors.IsNotFound(err) {
+				return ctrl.Result{RequeueAfter: time.Duration(5 * time.Second)}, err
+			}
+		}
+		status.Phase = tarantooliov1alpha1.DeletionPhaseCompleted
+
+	case tarantooliov1alpha1.DeletionPhaseCompleted:
+		reqLogger.Info("cluster is torn down, releasing it", "policy", status.Policy)
+		deleteClusterMetrics(cluster.GetNamespace(), cluster.GetName())
+
+		controllerutil.RemoveFinalizer(cluster, clusterFinalizer)
+		if err := r.Update(context.TODO(), cluster); err != nil && !errors.IsNotFound(err) {
+			return ctrl.Result{RequeueAfter: time.Duration(5 * time.Second)}, err
+		}
+		return ctrl.Result{}, nil
+	}
+
+	cluster.Status.State = tarantooliov1alpha1.ClusterStateDeleting
+	cluster.Status.Deletion = status
+	if err := r.Status().Update(context.TODO(), cluster); err != nil {
+		return ctrl.Result{RequeueAfter: time.Duration(5 * time.Second)}, err
+	}
+
+	if status.Message != "" {
+		reqLogger.Info("cluster teardown is waiting", "phase", status.Phase, "reason", status.Message)
+		return ctrl.Result{RequeueAfter: time.Duration(5 * time.Second)}, nil
+	}
+
+	return ctrl.Result{Requeue: true}, nil
+}
+
+// labelClusterVolumes labels the data volumes of the Cluster pods with the cluster, replicaset and instance
+// they belong to and returns their names. Retained volumes are also released from their owners.
+func (r *ClusterReconciler) labelClusterVolumes(cluster *tarantooliov1alpha1.Cluster, retain bool) ([]string, error) {
+	pods, err := r.listClusterPods(cluster.GetNamespace(), cluster.GetName())
+	if err != nil {
+		return nil, err
+	}
+
+	volumes := []string{}
+	for _, pod := range pods {
+		for _, volume := range pod.Spec.Volumes {
+			if volume.PersistentVolumeClaim == nil {
+				continue
+			}
+
+			pvc := &corev1.PersistentVolumeClaim{}
+			name := types.NamespacedName{Namespace: pod.GetNamespace(), Name: volume.PersistentVolumeClaim.ClaimName}
+			if err := r.Get(context.TODO(), name, pvc); err != nil {
+				if errors.IsNotFound(err) {
+					continue
+				}
+				return nil, err
+			}
+
+			pvcLabels := pvc.GetLabels()
+			if pvcLabels == nil {
+				pvcLabels = make(map[string]string)
+			}
+			pvcLabels["tarantool.io/cluster-id"] = cluster.GetName()
+			pvcLabels["tarantool.io/replicaset-uuid"] = pod.GetLabels()["tarantool.io/replicaset-uuid"]
+			pvcLabels["tarantool.io/instance-uuid"] = instanceUUID(pod)
+			pvc.SetLabels(pvcLabels)
+			if retain {
+				pvc.SetOwnerReferences(nil)
+			}
+
+			if err := r.Update(context.TODO(), pvc); err != nil {
+				return nil, err
+			}
+			volumes = append(volumes, pvc.GetName())
+		}
+	}
+	sort.Strings(volumes)
+
+	return volumes, nil
+}
+
+// releaseClusterInstances removes the instance finalizer from the Cluster pods, marking them expelled when
+// their instances are gone for good
+func (r *ClusterReconciler) releaseClusterInstances(cluster *tarantooliov1alpha1.Cluster, expel bool) error {
+	pods, err := r.listClusterPods(cluster.GetNamespace(), cluster.GetName())
+	if err != nil {
+		return err
+	}
+
+	for _, pod := range pods {
+		if expel && !tarantool.IsInstanceExpelled(pod) {
+			tarantool.MarkInstanceExpelled(pod)
+		} else if !controllerutil.ContainsFinalizer(pod, instanceFinalizer) {
+			continue
+		}
+
+		controllerutil.RemoveFinalizer(pod, instanceFinalizer)
+		if err := r.Update(context.TODO(), pod); err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// deleteClusterReplicasets deletes the Roles of the Cluster first, so they do not recreate the StatefulSets,
+// then the StatefulSets, and returns how many pods are still running
+func (r *ClusterReconciler) deleteClusterReplicasets(cluster *tarantooliov1alpha1.Cluster) (int, error) {
+	clusterSelector, err := metav1.LabelSelectorAsSelector(cluster.Spec.Selector)
+	if err != nil {
+		return 0, err
+	}
+	listOpts := &client.ListOptions{LabelSelector: clusterSelector, Namespace: cluster.GetNamespace()}
+
+	roleList := &tarantooliov1alpha1.RoleList{}
+	if err := r.List(context.TODO(), roleList, listOpts); err != nil {
+		return 0, err
+	}
+	for i := range roleList.Items {
+		role := &roleList.Items[i]
+		if !metav1.IsControlledBy(role, cluster) || role.GetDeletionTimestamp() != nil {
+			continue
+		}
+		if err := r.Delete(context.TODO(), role); err != nil && !errors.IsNotFound(err) {
+			return 0, err
+		}
+	}
+
+	stsList := &appsv1.StatefulSetList{}
+	if err := r.List(context.TODO(), stsList, listOpts); err != nil {
+		return 0, err
+	}
+	for i := range stsList.Items {
+		sts := &stsList.Items[i]
+		if sts.GetDeletionTimestamp() != nil {
+			continue
+		}
+		if err := r.Delete(context.TODO(), sts); err != nil && !errors.IsNotFound(err) {
+			return 0, err
+		}
+	}
+
+	// pods recreated before their StatefulSet was gone have no finalizer to hold them, they are released again
+	if err := r.releasePods(cluster.GetNamespace(), cluster.GetName()); err != nil {
+		return 0, err
+	}
+	pods, err := r.listClusterPods(cluster.GetNamespace(), cluster.GetName())
+	if err != nil {
+		return 0, err
+	}
+
+	return len(pods), nil
+}
+
+// snapshotClusterVolumes takes a VolumeSnapshot of every volume and returns the snapshots and the ones not ready yet
+func (r *ClusterReconciler) snapshotClusterVolumes(cluster *tarantooliov1alpha1.Cluster, volumes []string) ([]string, []string, error) {
+	var snapshots, pending []string
+	for _, volume := range volumes {
+		name := fmt.Sprintf("%s-%s", volume, string(cluster.GetUID())[:8])
+		snapshots = append(snapshots, name)
+
+		snapshot := &unstructured.Unstructured{}
+		snapshot.SetGroupVersionKind(volumeSnapshotGVK)
+		if err := r.Get(context.TODO(), types.NamespacedName{Namespace: cluster.GetNamespace(), Name: name}, snapshot); err != nil {
+			if !errors.IsNotFound(err) {
+				return nil, nil, err
+			}
+
+			pvc := &corev1.PersistentVolumeClaim{}
+			if err := r.Get(context.TODO(), types.NamespacedName{Namespace: cluster.GetNamespace(), Name: volume}, pvc); err != nil {
+				return nil, nil, err
+			}
+
+			snapshot.SetNamespace(cluster.GetNamespace())
+			snapshot.SetName(name)
+			snapshot.SetLabels(pvc.GetLabels())
+			spec := map[string]interface{}{
+				"source": map[string]interface{}{"persistentVolumeClaimName": volume},
+			}
+			if className := cluster.Spec.Deletion.VolumeSnapshotClassName; className != "" {
+				spec["volumeSnapshotClassName"] = className
+			}
+			if err := unstructured.SetNestedMap(snapshot.Object, spec, "spec"); err != nil {
+				return nil, nil, err
+			}
+
+			if err := r.Create(context.TODO(), snapshot); err != nil {
+				return nil, nil, fmt.Errorf("failed to snapshot volume %s: %w", volume, err)
+			}
+			pending = append(pending, name)
+			continue
+		}
+
+		if message, found, _ := unstructured.NestedString(snapshot.Object, "status", "error", "message"); found {
+			return nil, nil, fmt.Errorf("snapshot %s failed: %s", name, message)
+		}
+		if ready, _, _ := unstructured.NestedBool(snapshot.Object, "status", "readyToUse"); !ready {
+			pending = append(pending, name)
+		}
+	}
+
+	return snapshots, pending, nil
+}
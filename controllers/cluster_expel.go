@@ -0,0 +1,166 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	tarantooliov1alpha1 "github.com/tarantool/tarantool-operator/api/v1alpha1"
+	"github.com/tarantool/tarantool-operator/controllers/tarantool"
+	"github.com/tarantool/tarantool-operator/controllers/topology"
+)
+
+// expelRemovedInstances expels the instances marked as expelling, either by RoleReconciler when a replicaset
+// is scaled down or by releaseDeletedPods when a pod is deleted for good.
+// The replicaset master is never expelled: the master is switched to a remaining healthy instance first.
+func (r *ClusterReconciler) expelRemovedInstances(ctx context.Context, cluster *tarantooliov1alpha1.Cluster, topologyClient topology.TopologyService, stsList *appsv1.StatefulSetList) (ctrl.Result, error) {
+	reqLogger := log.FromContext(ctx)
+
+	clusterPods, err := r.listClusterPods(cluster.GetNamespace(), cluster.GetName())
+	if err != nil {
+		return ctrl.Result{RequeueAfter: time.Duration(5 * time.Second)}, err
+	}
+
+	var replicasetsByUUID map[string]*topology.ReplicasetData
+	for i := range stsList.Items {
+		sts := &stsList.Items[i]
+		if tarantool.IsExpelled(sts) {
+			continue
+		}
+
+		var pods, expelling []*corev1.Pod
+		for _, pod := range clusterPods {
+			if pod.GetLabels()["tarantool.io/replicaset-uuid"] != sts.GetLabels()["tarantool.io/replicaset-uuid"] {
+				continue
+			}
+
+			pods = append(pods, pod)
+			if tarantool.IsExpelling(pod) {
+				expelling = append(expelling, pod)
+			}
+		}
+		if len(expelling) == 0 {
+			continue
+		}
+
+		if replicasetsByUUID == nil {
+			replicasets, err := topologyClient.GetReplicasets(ctx)
+			if err != nil {
+				return topologyResult(ctx, err)
+			}
+
+			replicasetsByUUID = make(map[string]*topology.ReplicasetData)
+			for _, rs := range replicasets {
+				replicasetsByUUID[rs.UUID] = rs
+			}
+		}
+
+		stsLogger := reqLogger.WithValues("StatefulSet.Name", sts.GetName())
+		replicaset := replicasetsByUUID[sts.GetLabels()["tarantool.io/replicaset-uuid"]]
+
+		var members []*corev1.Pod
+		for _, pod := range expelling {
+			if replicaset == nil || !hasServer(replicaset, instanceUUID(pod)) {
+				// the instance never joined, there is nothing to expel
+				tarantool.MarkInstanceExpelled(pod)
+				if err := r.Update(context.TODO(), pod); err != nil {
+					return ctrl.Result{RequeueAfter: time.Duration(5 * time.Second)}, err
+				}
+				continue
+			}
+
+			if instanceUUID(pod) == replicasetMasterUUID(replicaset) {
+				var next *corev1.Pod
+				for _, candidate := range pods {
+					if !tarantool.IsExpelling(candidate) && isInstanceHealthy(candidate, replicaset) {
+						next = candidate
+						break
+					}
+				}
+				if next == nil {
+					stsLogger.Info("refusing to expel replicaset master, no healthy instance to take over", "Pod.Name", pod.GetName())
+					return ctrl.Result{RequeueAfter: time.Duration(5 * time.Second)}, nil
+				}
+
+				stsLogger.Info("switching master before expel", "from", pod.GetName(), "to", next.GetName())
+				if err := switchMaster(ctx, cluster, topologyClient, replicaset.UUID, instanceUUID(next)); err != nil {
+					return topologyResult(ctx, err)
+				}
+				return ctrl.Result{RequeueAfter: time.Duration(5 * time.Second)}, nil
+			}
+
+			members = append(members, pod)
+		}
+		if len(members) == 0 {
+			continue
+		}
+
+		stsLogger.Info("expelling removed instances", "count", len(members))
+		expelErr := topologyClient.Expel(ctx, members...)
+		if err := r.markExpelled(sts, members, expelErr); err != nil {
+			return ctrl.Result{RequeueAfter: time.Duration(5 * time.Second)}, err
+		}
+		if expelErr != nil {
+			stsLogger.Error(expelErr, "Expel error")
+			return topologyResult(ctx, expelErr)
+		}
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// markExpelled labels the pods whose instances were expelled, expelErr tells which of them failed.
+// The next incarnation of every expelled instance is recorded on the StatefulSet first, so a pod created
+// again with the same ordinal joins under a new uuid.
+func (r *ClusterReconciler) markExpelled(sts *appsv1.StatefulSet, pods []*corev1.Pod, expelErr error) error {
+	var partial *topology.ExpelError
+	if expelErr != nil && !errors.As(expelErr, &partial) {
+		return nil
+	}
+
+	var expelled []*corev1.Pod
+	for _, pod := range pods {
+		if partial != nil {
+			if _, failed := partial.Failed[pod.GetName()]; failed {
+				continue
+			}
+		}
+
+		expelled = append(expelled, pod)
+		if ordinal, err := podOrdinal(sts, pod); err == nil {
+			tarantool.NextInstanceGeneration(sts, ordinal)
+		}
+	}
+	if len(expelled) == 0 {
+		return nil
+	}
+
+	if err := r.Update(context.TODO(), sts); err != nil {
+		return err
+	}
+
+	for _, pod := range expelled {
+		tarantool.MarkInstanceExpelled(pod)
+		if err := r.Update(context.TODO(), pod); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// hasServer reports whether the instance is a member of the replicaset
+func hasServer(replicaset *topology.ReplicasetData, uuid string) bool {
+	for _, server := range replicaset.Servers {
+		if server.UUID == uuid {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,217 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	tarantooliov1alpha1 "github.com/tarantool/tarantool-operator/api/v1alpha1"
+	"github.com/tarantool/tarantool-operator/controllers/tarantool"
+	"github.com/tarantool/tarantool-operator/controllers/topology"
+)
+
+// reconcileFailover brings the Cartridge failover settings to the ones requested by the Cluster.
+// They are compared on every reconcile, so a change made behind the operator is reverted.
+func (r *ClusterReconciler) reconcileFailover(ctx context.Context, cluster *tarantooliov1alpha1.Cluster, topologyClient topology.TopologyService) error {
+	desired, err := r.desiredFailoverParams(cluster)
+	if err != nil {
+		return err
+	}
+
+	actual, err := topologyClient.GetFailoverParams(ctx)
+	if err != nil {
+		return err
+	}
+	if topology.FailoverParamsApplied(desired, actual) {
+		return nil
+	}
+
+	log.FromContext(ctx).Info("failover params differ from the requested ones, applying",
+		"mode", desired.Mode, "actualMode", actual.Mode, "stateProvider", desired.StateProvider)
+
+	return topologyClient.SetFailoverParams(ctx, desired)
+}
+
+// reconcileFailoverPriority derives the failover priority of every replicaset from its pods: the master pinned
+// by a switchover goes first, then the instances on nodes in the preferred zone of the Role, then the others,
+// each group by ordinal. It is compared on every reconcile, so the priority follows pods moved to other nodes.
+// A replicaset with a pending switchover, a missing pod or an unhealthy instance is left as it is.
+func (r *ClusterReconciler) reconcileFailoverPriority(ctx context.Context, topologyClient topology.TopologyService, stsList *appsv1.StatefulSetList) (ctrl.Result, error) {
+	reqLogger := log.FromContext(ctx)
+
+	replicasets, err := topologyClient.GetReplicasets(ctx)
+	if err != nil {
+		return topologyResult(ctx, err)
+	}
+
+	replicasetsByUUID := make(map[string]*topology.ReplicasetData)
+	for _, rs := range replicasets {
+		replicasetsByUUID[rs.UUID] = rs
+	}
+
+	for i := range stsList.Items {
+		sts := &stsList.Items[i]
+		if tarantool.IsDraining(sts) || tarantool.IsExpelled(sts) {
+			continue
+		}
+		if _, _, requested, _ := tarantool.GetSwitchoverRequest(sts); requested {
+			continue
+		}
+
+		replicaset, ok := replicasetsByUUID[sts.GetLabels()["tarantool.io/replicaset-uuid"]]
+		if !ok {
+			continue
+		}
+
+		stsLogger := reqLogger.WithValues("StatefulSet.Name", sts.GetName())
+
+		pods, err := r.getReplicasetPods(sts)
+		if err != nil {
+			return ctrl.Result{RequeueAfter: time.Duration(5 * time.Second)}, err
+		}
+		if len(pods) < int(*sts.Spec.Replicas) {
+			continue
+		}
+
+		var (
+			candidates []topology.PriorityCandidate
+			pinned     string
+			healthy    = true
+		)
+		for _, pod := range pods {
+			if tarantool.IsExpelling(pod) || tarantool.IsInstanceExpelled(pod) {
+				continue
+			}
+			if !isInstanceHealthy(pod, replicaset) {
+				healthy = false
+				break
+			}
+
+			ordinal, err := podOrdinal(sts, pod)
+			if err != nil {
+				return ctrl.Result{}, err
+			}
+			zone, err := r.podZone(pod)
+			if err != nil {
+				return ctrl.Result{RequeueAfter: time.Duration(5 * time.Second)}, err
+			}
+
+			candidates = append(candidates, topology.PriorityCandidate{UUID: instanceUUID(pod), Ordinal: ordinal, Zone: zone})
+			if pod.GetName() == tarantool.GetPinnedMaster(sts) {
+				pinned = instanceUUID(pod)
+			}
+		}
+		if !healthy || len(candidates) == 0 {
+			stsLogger.Info("replicaset is not healthy, postponing failover priority update")
+			continue
+		}
+
+		priority := topology.FailoverPriority(candidates, tarantool.GetPreferredZone(sts), pinned)
+		if topology.FailoverPriorityApplied(replicaset, priority) {
+			continue
+		}
+
+		stsLogger.Info("updating failover priority", "priority", priority, "preferredZone", tarantool.GetPreferredZone(sts))
+		if err := topologyClient.SetFailoverPriority(ctx, replicaset.UUID, priority); err != nil {
+			return topologyResult(ctx, err)
+		}
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// failoverConfigured reports whether the Cartridge failover settings match the requested ones
+// and explains the mismatch otherwise
+func (r *ClusterReconciler) failoverConfigured(ctx context.Context, cluster *tarantooliov1alpha1.Cluster, topologyClient topology.TopologyService) (bool, string) {
+	desired, err := r.desiredFailoverParams(cluster)
+	if err != nil {
+		return false, err.Error()
+	}
+
+	actual, err := topologyClient.GetFailoverParams(ctx)
+	if err != nil {
+		return false, err.Error()
+	}
+	if !topology.FailoverParamsApplied(desired, actual) {
+		return false, fmt.Sprintf("requested mode: %s, actual mode: %s", desired.Mode, actual.Mode)
+	}
+
+	return true, fmt.Sprintf("mode: %s", desired.Mode)
+}
+
+// desiredFailoverParams resolves the failover settings of the Cluster and the Secrets they reference
+// into Cartridge failover params
+func (r *ClusterReconciler) desiredFailoverParams(cluster *tarantooliov1alpha1.Cluster) (*topology.FailoverParams, error) {
+	spec := cluster.Spec.Failover
+	params := &topology.FailoverParams{
+		Mode:            string(spec.Mode),
+		FailoverTimeout: float64(spec.TimeoutSeconds),
+	}
+	if spec.Fencing != nil {
+		params.FencingEnabled = spec.Fencing.Enabled
+		params.FencingTimeout = float64(spec.Fencing.TimeoutSeconds)
+		params.FencingPause = float64(spec.Fencing.PauseSeconds)
+	}
+
+	if spec.Mode != tarantooliov1alpha1.FailoverModeStateful {
+		return params, nil
+	}
+	if spec.StateProvider == nil {
+		return nil, fmt.Errorf("stateful failover requires a state provider")
+	}
+
+	switch spec.StateProvider.Type {
+	case tarantooliov1alpha1.FailoverStateProviderStateboard:
+		stateboard := spec.StateProvider.Stateboard
+		if stateboard == nil {
+			return nil, fmt.Errorf("stateboard state provider is not configured")
+		}
+
+		uri, passwordSecret := stateboard.URI, stateboard.PasswordSecret
+		if stateboard.Managed != nil {
+			uri, passwordSecret = stateboardURI(cluster, stateboard.Managed), stateboardPasswordSecret(cluster)
+		}
+
+		params.StateProvider = topology.StateProviderTarantool
+		params.TarantoolParams = &topology.TarantoolProviderParams{URI: uri}
+		if passwordSecret != nil {
+			secret, err := r.getSecret(cluster.GetNamespace(), passwordSecret.Name)
+			if err != nil {
+				return nil, err
+			}
+			password, ok := secret.Data[passwordSecret.Key]
+			if !ok {
+				return nil, fmt.Errorf("secret %s has no %s key", secret.GetName(), passwordSecret.Key)
+			}
+			params.TarantoolParams.Password = string(password)
+		}
+	case tarantooliov1alpha1.FailoverStateProviderEtcd2:
+		etcd2 := spec.StateProvider.Etcd2
+		if etcd2 == nil {
+			return nil, fmt.Errorf("etcd2 state provider is not configured")
+		}
+
+		params.StateProvider = topology.StateProviderEtcd2
+		params.Etcd2Params = &topology.Etcd2ProviderParams{
+			Endpoints: etcd2.Endpoints,
+			Prefix:    etcd2.Prefix,
+			LockDelay: float64(etcd2.LockDelaySeconds),
+		}
+		if etcd2.CredentialsSecret != nil {
+			secret, err := r.getSecret(cluster.GetNamespace(), etcd2.CredentialsSecret.Name)
+			if err != nil {
+				return nil, err
+			}
+			params.Etcd2Params.Username = string(secret.Data[tarantooliov1alpha1.Etcd2UsernameKey])
+			params.Etcd2Params.Password = string(secret.Data[tarantooliov1alpha1.Etcd2PasswordKey])
+		}
+	default:
+		return nil, fmt.Errorf("unknown state provider %q", spec.StateProvider.Type)
+	}
+
+	return params, nil
+}
@@ -0,0 +1,109 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	tarantooliov1alpha1 "github.com/tarantool/tarantool-operator/api/v1alpha1"
+	"github.com/tarantool/tarantool-operator/controllers/tarantool"
+	"github.com/tarantool/tarantool-operator/controllers/topology"
+)
+
+// importTopology adopts the replicasets of a Cartridge cluster bootstrapped without the operator.
+// The instances of every StatefulSet are looked up in the topology by their advertise uri or alias, the uuids
+// found are recorded on the StatefulSet and put on its pods by Reconcile instead of generated ones.
+// The actual weight becomes the desired one and a cluster holding buckets is marked bootstrapped,
+// so the reconcile that follows has nothing to join, rebalance or bootstrap. The master is pinned,
+// the failover priority derived from the pods does not move it.
+// A StatefulSet is imported once all of its pods exist, its instances missing from the topology join as usual.
+func (r *ClusterReconciler) importTopology(ctx context.Context, cluster *tarantooliov1alpha1.Cluster, topologyClient topology.TopologyService, stsList *appsv1.StatefulSetList) (ctrl.Result, error) {
+	reqLogger := log.FromContext(ctx)
+
+	var (
+		replicasets  []*topology.ReplicasetData
+		bootstrapped bool
+	)
+	for i := range stsList.Items {
+		sts := &stsList.Items[i]
+		if tarantool.IsImported(sts) || tarantool.IsExpelled(sts) {
+			continue
+		}
+
+		stsLogger := reqLogger.WithValues("StatefulSet.Name", sts.GetName())
+
+		pods, err := r.getReplicasetPods(sts)
+		if err != nil {
+			return ctrl.Result{RequeueAfter: time.Duration(5 * time.Second)}, err
+		}
+		if len(pods) < int(*sts.Spec.Replicas) {
+			stsLogger.Info("waiting for instances to be created before import", "pending", int(*sts.Spec.Replicas)-len(pods))
+			return ctrl.Result{RequeueAfter: time.Duration(5 * time.Second)}, nil
+		}
+
+		if replicasets == nil {
+			if replicasets, err = topologyClient.GetReplicasets(ctx); err != nil {
+				return topologyResult(ctx, err)
+			}
+
+			stats, err := topologyClient.GetServerStat(ctx)
+			if err != nil {
+				return topologyResult(ctx, err)
+			}
+			for _, stat := range stats.Stats {
+				if stat.Statistics.BucketsCount > 0 {
+					bootstrapped = true
+				}
+			}
+		}
+
+		var imported *topology.ReplicasetData
+		for ordinal, pod := range pods {
+			uri := topology.AdvertiseURI(pod, cluster.GetName(), cluster.Spec.ClusterDomainName, cluster.Spec.BinaryPort)
+			rs, server := topology.FindServer(replicasets, uri, pod.GetName())
+			if rs == nil {
+				continue
+			}
+			if imported != nil && imported.UUID != rs.UUID {
+				return ctrl.Result{}, fmt.Errorf("instances of StatefulSet %s belong to replicasets %s and %s, it can not be imported", sts.GetName(), imported.UUID, rs.UUID)
+			}
+
+			imported = rs
+			tarantool.SetImportedInstanceUUID(sts, ordinal, server.UUID)
+			if server.UUID == replicasetMasterUUID(rs) {
+				tarantool.SetPinnedMaster(sts, pod.GetName())
+			}
+		}
+
+		if imported != nil {
+			labels := sts.GetLabels()
+			labels["tarantool.io/replicaset-uuid"] = imported.UUID
+			sts.SetLabels(labels)
+
+			annotations := sts.GetAnnotations()
+			if imported.Weight != nil {
+				annotations["tarantool.io/replicaset-weight"] = strconv.Itoa(*imported.Weight)
+			}
+			if bootstrapped {
+				annotations["tarantool.io/isBootstrapped"] = "1"
+			}
+			sts.SetAnnotations(annotations)
+
+			stsLogger.Info("imported replicaset", "UUID", imported.UUID, "alias", imported.Alias)
+		} else {
+			stsLogger.Info("replicaset is not in the topology, it will be joined")
+		}
+
+		tarantool.MarkImported(sts)
+		if err := r.Update(context.TODO(), sts); err != nil {
+			return ctrl.Result{RequeueAfter: time.Duration(5 * time.Second)}, err
+		}
+	}
+
+	return ctrl.Result{}, nil
+}
@@ -0,0 +1,72 @@
+package controllers
+
+import (
+	"context"
+	"net"
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	tarantooliov1alpha1 "github.com/tarantool/tarantool-operator/api/v1alpha1"
+	"github.com/tarantool/tarantool-operator/controllers/leader"
+	"github.com/tarantool/tarantool-operator/controllers/tarantool"
+	"github.com/tarantool/tarantool-operator/controllers/topology"
+)
+
+// legacyLeaderAnnotation is where previous operator versions kept the leader on the cluster Endpoints
+const legacyLeaderAnnotation = "tarantool.io/leader"
+
+// selectLeader picks the instance to send topology requests to: the current leader while it answers,
+// otherwise the ready instances of the cluster are probed all at once
+func (r *ClusterReconciler) selectLeader(ctx context.Context, cluster *tarantooliov1alpha1.Cluster, ep *corev1.Endpoints, adminAPIOpts []topology.Option) (*tarantooliov1alpha1.LeaderStatus, error) {
+	candidates := []leader.Candidate{}
+	for _, subset := range ep.Subsets {
+		for _, addr := range subset.Addresses {
+			candidate := leader.Candidate{
+				Address: net.JoinHostPort(addr.IP, strconv.Itoa(int(cluster.Spec.HTTPPort))),
+			}
+
+			if addr.TargetRef != nil && addr.TargetRef.Kind == "Pod" {
+				candidate.Pod = addr.TargetRef.Name
+
+				pod := &corev1.Pod{}
+				if err := r.Get(context.TODO(), types.NamespacedName{Namespace: ep.GetNamespace(), Name: addr.TargetRef.Name}, pod); err == nil {
+					candidate.Joined = tarantool.IsJoined(pod)
+				}
+			}
+
+			candidates = append(candidates, candidate)
+		}
+	}
+
+	current := ""
+	if cluster.Status.Leader != nil {
+		current = cluster.Status.Leader.Address
+	}
+
+	result, err := leader.Select(ctx, candidates, current, func(ctx context.Context, address string) (bool, error) {
+		opts := append([]topology.Option{topology.WithTopologyEndpoint(adminAPIURL(cluster, address))}, adminAPIOpts...)
+		self, err := r.newTopologyService(opts...).GetSelf(ctx)
+		if err != nil {
+			return false, err
+		}
+		return self.UUID != "", nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	status := &tarantooliov1alpha1.LeaderStatus{
+		Address:            result.Address,
+		Pod:                result.Pod,
+		Reason:             result.Reason,
+		LastTransitionTime: metav1.Now(),
+	}
+	if cluster.Status.Leader != nil && cluster.Status.Leader.Address == result.Address {
+		status.LastTransitionTime = cluster.Status.Leader.LastTransitionTime
+	}
+
+	return status, nil
+}
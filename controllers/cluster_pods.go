@@ -0,0 +1,158 @@
+package controllers
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/tarantool/tarantool-operator/controllers/tarantool"
+)
+
+// instanceFinalizer keeps a deleted pod until the operator decides whether its instance comes back or is expelled
+const instanceFinalizer = "tarantool.io/instance"
+
+// listClusterPods returns the pods of the Cluster ordered by name
+func (r *ClusterReconciler) listClusterPods(namespace, clusterName string) ([]*corev1.Pod, error) {
+	podList := &corev1.PodList{}
+	if err := r.List(context.TODO(), podList, client.InNamespace(namespace), client.MatchingLabels{"tarantool.io/cluster-id": clusterName}); err != nil {
+		return nil, err
+	}
+
+	pods := make([]*corev1.Pod, 0, len(podList.Items))
+	for i := range podList.Items {
+		pods = append(pods, &podList.Items[i])
+	}
+	sort.Slice(pods, func(i, j int) bool {
+		return pods[i].GetName() < pods[j].GetName()
+	})
+
+	return pods, nil
+}
+
+// releaseDeletedPods decides the fate of the instances whose pods are being deleted.
+// A pod the StatefulSet recreates with the same ordinal and volumes brings the instance back, so it is released at once.
+// When the StatefulSet shrank or the volumes of the pod are gone, the instance is marked expelling and the pod
+// is released only after expelRemovedInstances expelled it from the topology.
+func (r *ClusterReconciler) releaseDeletedPods(ctx context.Context, namespace, clusterName string) error {
+	reqLogger := log.FromContext(ctx)
+
+	pods, err := r.listClusterPods(namespace, clusterName)
+	if err != nil {
+		return err
+	}
+
+	for _, pod := range pods {
+		if pod.GetDeletionTimestamp() == nil || !controllerutil.ContainsFinalizer(pod, instanceFinalizer) {
+			continue
+		}
+
+		podLogger := reqLogger.WithValues("Pod.Name", pod.GetName())
+		if !tarantool.IsInstanceExpelled(pod) {
+			returning, err := r.isInstanceReturning(pod)
+			if err != nil {
+				return err
+			}
+
+			if !returning {
+				if !tarantool.IsExpelling(pod) {
+					podLogger.Info("pod is deleted for good, expelling its instance")
+					tarantool.MarkExpelling(pod)
+					if err := r.Update(context.TODO(), pod); err != nil {
+						return err
+					}
+				}
+				continue
+			}
+
+			podLogger.Info("pod will be recreated, keeping its instance in the topology")
+		}
+
+		controllerutil.RemoveFinalizer(pod, instanceFinalizer)
+		if err := r.Update(context.TODO(), pod); err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// releasePods removes the finalizer from all pods of a Cluster, nothing is left to expel the instances from
+func (r *ClusterReconciler) releasePods(namespace, clusterName string) error {
+	pods, err := r.listClusterPods(namespace, clusterName)
+	if err != nil {
+		return err
+	}
+
+	for _, pod := range pods {
+		if !controllerutil.ContainsFinalizer(pod, instanceFinalizer) {
+			continue
+		}
+
+		controllerutil.RemoveFinalizer(pod, instanceFinalizer)
+		if err := r.Update(context.TODO(), pod); err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// isInstanceReturning reports whether the StatefulSet recreates the pod with the same ordinal and volumes.
+// A missing StatefulSet is recreated by RoleReconciler with the same name and volume claims.
+func (r *ClusterReconciler) isInstanceReturning(pod *corev1.Pod) (bool, error) {
+	ownerRef := metav1.GetControllerOf(pod)
+	if ownerRef == nil || ownerRef.Kind != "StatefulSet" {
+		return true, nil
+	}
+
+	sts := &appsv1.StatefulSet{}
+	if err := r.Get(context.TODO(), types.NamespacedName{Namespace: pod.GetNamespace(), Name: ownerRef.Name}, sts); err != nil {
+		if errors.IsNotFound(err) {
+			return true, nil
+		}
+		return false, err
+	}
+
+	if tarantool.IsExpelled(sts) {
+		return false, nil
+	}
+
+	ordinal, err := podOrdinal(sts, pod)
+	if err != nil || ordinal >= int(*sts.Spec.Replicas) {
+		return false, nil
+	}
+
+	for _, volume := range pod.Spec.Volumes {
+		if volume.PersistentVolumeClaim == nil {
+			continue
+		}
+
+		pvc := &corev1.PersistentVolumeClaim{}
+		if err := r.Get(context.TODO(), types.NamespacedName{Namespace: pod.GetNamespace(), Name: volume.PersistentVolumeClaim.ClaimName}, pvc); err != nil {
+			if errors.IsNotFound(err) {
+				return false, nil
+			}
+			return false, err
+		}
+		if pvc.GetDeletionTimestamp() != nil {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+// podOrdinal returns the ordinal of the StatefulSet pod
+func podOrdinal(sts *appsv1.StatefulSet, pod *corev1.Pod) (int, error) {
+	return strconv.Atoi(strings.TrimPrefix(pod.GetName(), sts.GetName()+"-"))
+}
@@ -0,0 +1,85 @@
+package controllers
+
+import (
+	"context"
+	"strconv"
+
+	tarantooliov1alpha1 "github.com/tarantool/tarantool-operator/api/v1alpha1"
+	"github.com/tarantool/tarantool-operator/controllers/rebalance"
+	"github.com/tarantool/tarantool-operator/controllers/topology"
+)
+
+// newRebalancePlan compares the bucket distribution reported by storages with the one implied by their weights
+func newRebalancePlan(replicasets []*topology.ReplicasetData, stats topology.ServerStatData, total int32) rebalance.Plan {
+	bucketsByServer := make(map[string]int)
+	for _, stat := range stats.Stats {
+		bucketsByServer[stat.UUID] = stat.Statistics.BucketsCount
+	}
+
+	storages := []rebalance.Replicaset{}
+	for _, rs := range replicasets {
+		if rs.Weight == nil {
+			continue
+		}
+
+		storages = append(storages, rebalance.Replicaset{
+			UUID:    rs.UUID,
+			Weight:  float64(*rs.Weight),
+			Buckets: replicasetBucketsCount(rs, bucketsByServer),
+		})
+	}
+
+	return rebalance.NewPlan(storages, int(total))
+}
+
+// replicasetBucketsCount returns the buckets stored by the replicaset. Replicas report the buckets of their
+// master, so only the master is counted.
+func replicasetBucketsCount(rs *topology.ReplicasetData, bucketsByServer map[string]int) int {
+	if master := replicasetMasterUUID(rs); master != "" {
+		return bucketsByServer[master]
+	}
+
+	buckets := 0
+	for _, server := range rs.Servers {
+		if bucketsByServer[server.UUID] > buckets {
+			buckets = bucketsByServer[server.UUID]
+		}
+	}
+
+	return buckets
+}
+
+// nextWeight picks the weight to apply to a replicaset on the way to the desired one.
+// With a weight step configured the weight changes gradually, every step including the last one waits
+// for the buckets moved by the previous one to settle within the disbalance threshold.
+func nextWeight(ctx context.Context, cluster *tarantooliov1alpha1.Cluster, topologyClient topology.TopologyService, current int, desired string) (string, bool, error) {
+	spec := cluster.Spec.Rebalancing
+	if spec == nil || spec.WeightStep <= 0 {
+		return desired, true, nil
+	}
+
+	desiredWeight, err := strconv.Atoi(desired)
+	if err != nil {
+		return desired, true, nil
+	}
+
+	replicasets, err := topologyClient.GetReplicasets(ctx)
+	if err != nil {
+		return "", false, err
+	}
+	stats, err := topologyClient.GetServerStat(ctx)
+	if err != nil {
+		return "", false, err
+	}
+
+	if newRebalancePlan(replicasets, stats, cluster.Spec.BucketCount).InProgress(spec.DisbalanceThreshold) {
+		return "", false, nil
+	}
+
+	next := rebalance.NextWeight(current, desiredWeight, int(spec.WeightStep))
+	if next == desiredWeight {
+		return desired, true, nil
+	}
+
+	return strconv.Itoa(next), true, nil
+}
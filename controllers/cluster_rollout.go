@@ -0,0 +1,221 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	tarantooliov1alpha1 "github.com/tarantool/tarantool-operator/api/v1alpha1"
+	"github.com/tarantool/tarantool-operator/controllers/tarantool"
+	"github.com/tarantool/tarantool-operator/controllers/topology"
+)
+
+// defaultRolloutStepTimeout is how long a restarted instance may take to rejoin the cluster and become healthy
+const defaultRolloutStepTimeout = 5 * time.Minute
+
+func (r *ClusterReconciler) rolloutStepTimeout() time.Duration {
+	if r.RolloutStepTimeout == 0 {
+		return defaultRolloutStepTimeout
+	}
+
+	return r.RolloutStepTimeout
+}
+
+// rollout restarts instances running an outdated pod template one at a time.
+// StatefulSets are created with the OnDelete update strategy, so the operator deletes pods itself:
+// replicas are restarted before the master, and the master is switched to an updated healthy replica before its restart.
+// A paused replicaset is skipped until the pause is removed.
+func (r *ClusterReconciler) rollout(ctx context.Context, cluster *tarantooliov1alpha1.Cluster, topologyClient topology.TopologyService, stsList *appsv1.StatefulSetList) (ctrl.Result, error) {
+	reqLogger := log.FromContext(ctx)
+
+	replicasets, err := topologyClient.GetReplicasets(ctx)
+	if err != nil {
+		return topologyResult(ctx, err)
+	}
+
+	replicasetsByUUID := make(map[string]*topology.ReplicasetData)
+	for _, rs := range replicasets {
+		replicasetsByUUID[rs.UUID] = rs
+	}
+
+	for i := range stsList.Items {
+		sts := &stsList.Items[i]
+		if tarantool.IsDraining(sts) || tarantool.IsExpelled(sts) || sts.Spec.UpdateStrategy.Type != appsv1.OnDeleteStatefulSetStrategyType {
+			continue
+		}
+
+		stsLogger := reqLogger.WithValues("StatefulSet.Name", sts.GetName())
+		if tarantool.IsRolloutPaused(sts) {
+			// the pause is reported by the RollingUpdatePaused condition, the other replicasets go on
+			stsLogger.Info("rolling update is paused", "reason", tarantool.GetRolloutPausedReason(sts))
+			continue
+		}
+
+		replicaset, ok := replicasetsByUUID[sts.GetLabels()["tarantool.io/replicaset-uuid"]]
+		if !ok {
+			continue
+		}
+
+		pods, err := r.getReplicasetPods(sts)
+		if err != nil {
+			return ctrl.Result{RequeueAfter: time.Duration(5 * time.Second)}, err
+		}
+		if len(pods) < int(*sts.Spec.Replicas) {
+			stsLogger.Info("waiting for all replicaset pods to be created")
+			return ctrl.Result{RequeueAfter: time.Duration(5 * time.Second)}, nil
+		}
+
+		if podName, started, ok := tarantool.GetRolloutStep(sts); ok {
+			if pod := findPod(pods, podName); pod != nil && tarantool.IsUpdated(pod, sts) && isInstanceHealthy(pod, replicaset) {
+				stsLogger.Info("restarted instance is healthy", "Pod.Name", podName)
+				tarantool.ClearRolloutStep(sts)
+				if err := r.Update(context.TODO(), sts); err != nil {
+					return ctrl.Result{RequeueAfter: time.Duration(5 * time.Second)}, err
+				}
+				return ctrl.Result{Requeue: true}, nil
+			}
+
+			if time.Since(started) > r.rolloutStepTimeout() {
+				reason := fmt.Sprintf("instance %s did not become healthy in %s", podName, r.rolloutStepTimeout())
+				stsLogger.Info("pausing rolling update", "reason", reason)
+				tarantool.ClearRolloutStep(sts)
+				tarantool.MarkRolloutPaused(sts, reason)
+				if err := r.Update(context.TODO(), sts); err != nil {
+					return ctrl.Result{RequeueAfter: time.Duration(5 * time.Second)}, err
+				}
+				return ctrl.Result{RequeueAfter: time.Duration(5 * time.Second)}, nil
+			}
+
+			stsLogger.Info("waiting for restarted instance to rejoin", "Pod.Name", podName)
+			return ctrl.Result{RequeueAfter: time.Duration(5 * time.Second)}, nil
+		}
+
+		var outdated []*corev1.Pod
+		for _, pod := range pods {
+			if !tarantool.IsUpdated(pod, sts) {
+				outdated = append(outdated, pod)
+			}
+		}
+		if len(outdated) == 0 {
+			continue
+		}
+
+		for _, pod := range pods {
+			if !isInstanceHealthy(pod, replicaset) {
+				stsLogger.Info("replicaset is not healthy, postponing rolling update", "Pod.Name", pod.GetName())
+				return ctrl.Result{RequeueAfter: time.Duration(5 * time.Second)}, nil
+			}
+		}
+
+		masterUUID := replicasetMasterUUID(replicaset)
+
+		// restart replicas from the highest ordinal, the master goes last
+		sort.Slice(outdated, func(i, j int) bool {
+			iMaster := instanceUUID(outdated[i]) == masterUUID
+			jMaster := instanceUUID(outdated[j]) == masterUUID
+			if iMaster != jMaster {
+				return jMaster
+			}
+			return outdated[i].GetName() > outdated[j].GetName()
+		})
+		next := outdated[0]
+
+		if instanceUUID(next) == masterUUID && len(pods) > 1 {
+			for _, pod := range pods {
+				if pod == next || !tarantool.IsUpdated(pod, sts) || !isInstanceHealthy(pod, replicaset) {
+					continue
+				}
+
+				stsLogger.Info("switching master before restart", "from", next.GetName(), "to", pod.GetName())
+				if err := switchMaster(ctx, cluster, topologyClient, replicaset.UUID, instanceUUID(pod)); err != nil {
+					return topologyResult(ctx, err)
+				}
+				return ctrl.Result{RequeueAfter: time.Duration(5 * time.Second)}, nil
+			}
+		}
+
+		stsLogger.Info("restarting instance to apply the new pod template", "Pod.Name", next.GetName(), "revision", sts.Status.UpdateRevision)
+		tarantool.MarkRolloutStep(sts, next.GetName(), time.Now())
+		if err := r.Update(context.TODO(), sts); err != nil {
+			return ctrl.Result{RequeueAfter: time.Duration(5 * time.Second)}, err
+		}
+		if err := r.Delete(context.TODO(), next); err != nil && !errors.IsNotFound(err) {
+			return ctrl.Result{RequeueAfter: time.Duration(5 * time.Second)}, err
+		}
+
+		return ctrl.Result{RequeueAfter: time.Duration(5 * time.Second)}, nil
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// getReplicasetPods returns the existing pods of the StatefulSet ordered by ordinal
+func (r *ClusterReconciler) getReplicasetPods(sts *appsv1.StatefulSet) ([]*corev1.Pod, error) {
+	pods := []*corev1.Pod{}
+	for i := 0; i < int(*sts.Spec.Replicas); i++ {
+		pod := &corev1.Pod{}
+		name := types.NamespacedName{
+			Namespace: sts.GetNamespace(),
+			Name:      fmt.Sprintf("%s-%d", sts.GetName(), i),
+		}
+		if err := r.Get(context.TODO(), name, pod); err != nil {
+			if errors.IsNotFound(err) {
+				continue
+			}
+			return nil, err
+		}
+
+		pods = append(pods, pod)
+	}
+
+	return pods, nil
+}
+
+// isInstanceHealthy reports whether the pod is ready, joined and healthy in Cartridge
+func isInstanceHealthy(pod *corev1.Pod, replicaset *topology.ReplicasetData) bool {
+	if pod == nil || pod.GetDeletionTimestamp() != nil || !tarantool.IsReady(pod) || !tarantool.IsJoined(pod) {
+		return false
+	}
+
+	for _, server := range replicaset.Servers {
+		if server.UUID == instanceUUID(pod) {
+			return server.Status == "healthy"
+		}
+	}
+
+	return false
+}
+
+// replicasetMasterUUID returns the UUID of the instance currently acting as the replicaset master
+func replicasetMasterUUID(replicaset *topology.ReplicasetData) string {
+	if replicaset.ActiveMaster != nil {
+		return replicaset.ActiveMaster.UUID
+	}
+	if replicaset.Master != nil {
+		return replicaset.Master.UUID
+	}
+
+	return ""
+}
+
+func findPod(pods []*corev1.Pod, name string) *corev1.Pod {
+	for _, pod := range pods {
+		if pod.GetName() == name {
+			return pod
+		}
+	}
+
+	return nil
+}
+
+func instanceUUID(pod *corev1.Pod) string {
+	return pod.GetLabels()["tarantool.io/instance-uuid"]
+}
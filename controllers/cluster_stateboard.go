@@ -0,0 +1,279 @@
+package controllers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	tarantooliov1alpha1 "github.com/tarantool/tarantool-operator/api/v1alpha1"
+)
+
+const (
+	// stateboardLabel selects the pods of the managed stateboard by the Cluster name, it is not
+	// tarantool.io/cluster-id so the stateboard is never taken for an instance
+	stateboardLabel       = "tarantool.io/stateboard"
+	stateboardPasswordKey = "password"
+	stateboardWorkdir     = "/var/lib/tarantool/stateboard"
+)
+
+var defaultStateboardCommand = []string{"tarantool", "stateboard.init.lua"}
+
+//+kubebuilder:rbac:groups=apps,resources=deployments,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch;create;delete
+
+// managedStateboard returns the stateboard the operator runs for the Cluster, nil when there is none
+func managedStateboard(cluster *tarantooliov1alpha1.Cluster) *tarantooliov1alpha1.ManagedStateboardSpec {
+	provider := cluster.Spec.Failover.StateProvider
+	if provider == nil || provider.Type != tarantooliov1alpha1.FailoverStateProviderStateboard || provider.Stateboard == nil {
+		return nil
+	}
+
+	return provider.Stateboard.Managed
+}
+
+// stateboardName is the name of the Deployment, Service and password Secret of the managed stateboard
+func stateboardName(cluster *tarantooliov1alpha1.Cluster) string {
+	return fmt.Sprintf("%s-stateboard", cluster.GetName())
+}
+
+// stateboardURI is the address instances reach the managed stateboard at
+func stateboardURI(cluster *tarantooliov1alpha1.Cluster, managed *tarantooliov1alpha1.ManagedStateboardSpec) string {
+	return fmt.Sprintf("%s.%s.svc.%s:%d", stateboardName(cluster), cluster.GetNamespace(), cluster.Spec.ClusterDomainName, managed.Port)
+}
+
+// stateboardPasswordSecret selects the password of the managed stateboard, the generated one unless the Cluster names its own
+func stateboardPasswordSecret(cluster *tarantooliov1alpha1.Cluster) *corev1.SecretKeySelector {
+	if selector := cluster.Spec.Failover.StateProvider.Stateboard.PasswordSecret; selector != nil {
+		return selector
+	}
+
+	return &corev1.SecretKeySelector{
+		LocalObjectReference: corev1.LocalObjectReference{Name: stateboardName(cluster)},
+		Key:                  stateboardPasswordKey,
+	}
+}
+
+// reconcileStateboard runs the stateboard of the Cluster: a password Secret unless the Cluster names its own,
+// a single replica Deployment and a Service. They are owned by the Cluster and removed once it stops asking
+// for a managed stateboard.
+func (r *ClusterReconciler) reconcileStateboard(ctx context.Context, cluster *tarantooliov1alpha1.Cluster) error {
+	managed := managedStateboard(cluster)
+	if managed == nil {
+		return r.removeStateboard(ctx, cluster)
+	}
+
+	if cluster.Spec.Failover.StateProvider.Stateboard.PasswordSecret == nil {
+		if err := r.ensureStateboardSecret(ctx, cluster); err != nil {
+			return err
+		}
+	}
+
+	labels := map[string]string{stateboardLabel: cluster.GetName()}
+	name := types.NamespacedName{Namespace: cluster.GetNamespace(), Name: stateboardName(cluster)}
+
+	svc := &corev1.Service{}
+	if err := r.Get(context.TODO(), name, svc); err != nil {
+		if !errors.IsNotFound(err) {
+			return err
+		}
+
+		svc = &corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: name.Name, Namespace: name.Namespace, Labels: labels},
+			Spec: corev1.ServiceSpec{
+				Selector: labels,
+				Ports: []corev1.ServicePort{
+					{
+						Name:       "iproto",
+						Port:       managed.Port,
+						TargetPort: intstr.FromString("iproto"),
+						Protocol:   corev1.ProtocolTCP,
+					},
+				},
+			},
+		}
+		if err := controllerutil.SetControllerReference(cluster, svc, r.Scheme); err != nil {
+			return err
+		}
+		if err := r.Create(context.TODO(), svc); err != nil {
+			return err
+		}
+		log.FromContext(ctx).Info("created stateboard Service", "Service.Name", svc.GetName())
+	}
+
+	desired := newStateboardDeployment(cluster, managed, labels)
+	deployment := &appsv1.Deployment{}
+	if err := r.Get(context.TODO(), name, deployment); err != nil {
+		if !errors.IsNotFound(err) {
+			return err
+		}
+
+		if err := controllerutil.SetControllerReference(cluster, desired, r.Scheme); err != nil {
+			return err
+		}
+		if err := r.Create(context.TODO(), desired); err != nil {
+			return err
+		}
+		log.FromContext(ctx).Info("created stateboard Deployment", "Deployment.Name", desired.GetName())
+		return nil
+	}
+
+	// the API server fills in defaults, only the fields the operator sets are compared
+	if equality.Semantic.DeepDerivative(desired.Spec.Template, deployment.Spec.Template) {
+		return nil
+	}
+
+	deployment.Spec.Template = desired.Spec.Template
+	log.FromContext(ctx).Info("updating stateboard Deployment", "Deployment.Name", deployment.GetName())
+	return r.Update(context.TODO(), deployment)
+}
+
+func (r *ClusterReconciler) ensureStateboardSecret(ctx context.Context, cluster *tarantooliov1alpha1.Cluster) error {
+	secret := &corev1.Secret{}
+	err := r.Get(context.TODO(), types.NamespacedName{Namespace: cluster.GetNamespace(), Name: stateboardName(cluster)}, secret)
+	if err == nil || !errors.IsNotFound(err) {
+		return err
+	}
+
+	password := make([]byte, 24)
+	if _, err := rand.Read(password); err != nil {
+		return err
+	}
+
+	secret = &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      stateboardName(cluster),
+			Namespace: cluster.GetNamespace(),
+			Labels:    map[string]string{stateboardLabel: cluster.GetName()},
+		},
+		Data: map[string][]byte{stateboardPasswordKey: []byte(hex.EncodeToString(password))},
+	}
+	if err := controllerutil.SetControllerReference(cluster, secret, r.Scheme); err != nil {
+		return err
+	}
+	if err := r.Create(context.TODO(), secret); err != nil {
+		return err
+	}
+
+	log.FromContext(ctx).Info("generated stateboard password", "Secret.Name", secret.GetName())
+	return nil
+}
+
+// removeStateboard deletes the objects of a stateboard the Cluster no longer asks for
+func (r *ClusterReconciler) removeStateboard(ctx context.Context, cluster *tarantooliov1alpha1.Cluster) error {
+	objectMeta := metav1.ObjectMeta{Namespace: cluster.GetNamespace(), Name: stateboardName(cluster)}
+	for _, obj := range []client.Object{&appsv1.Deployment{ObjectMeta: objectMeta}, &corev1.Service{ObjectMeta: objectMeta}, &corev1.Secret{ObjectMeta: objectMeta}} {
+		if err := r.Get(context.TODO(), client.ObjectKeyFromObject(obj), obj); err != nil {
+			if errors.IsNotFound(err) {
+				continue
+			}
+			return err
+		}
+		if !metav1.IsControlledBy(obj, cluster) {
+			continue
+		}
+
+		if err := r.Delete(context.TODO(), obj); client.IgnoreNotFound(err) != nil {
+			return err
+		}
+		log.FromContext(ctx).Info("removed stateboard object", "name", obj.GetName())
+	}
+
+	return nil
+}
+
+// stateboardStatus observes the managed stateboard, nil when the operator runs none
+func (r *ClusterReconciler) stateboardStatus(cluster *tarantooliov1alpha1.Cluster) *tarantooliov1alpha1.StateboardStatus {
+	managed := managedStateboard(cluster)
+	if managed == nil {
+		return nil
+	}
+
+	status := &tarantooliov1alpha1.StateboardStatus{URI: stateboardURI(cluster, managed)}
+
+	deployment := &appsv1.Deployment{}
+	if err := r.Get(context.TODO(), types.NamespacedName{Namespace: cluster.GetNamespace(), Name: stateboardName(cluster)}, deployment); err != nil {
+		status.Message = err.Error()
+		return status
+	}
+
+	status.Ready = deployment.Status.AvailableReplicas > 0
+	if !status.Ready {
+		status.Message = "stateboard pod is not available"
+		for _, condition := range deployment.Status.Conditions {
+			if condition.Type == appsv1.DeploymentAvailable && condition.Message != "" {
+				status.Message = condition.Message
+			}
+		}
+	}
+
+	return status
+}
+
+// newStateboardDeployment runs a single stateboard, its state is rebuilt by the failover coordinator
+// after a restart, so it keeps no volume
+func newStateboardDeployment(cluster *tarantooliov1alpha1.Cluster, managed *tarantooliov1alpha1.ManagedStateboardSpec, labels map[string]string) *appsv1.Deployment {
+	replicas := int32(1)
+	command := managed.Command
+	if len(command) == 0 {
+		command = defaultStateboardCommand
+	}
+	password := stateboardPasswordSecret(cluster)
+
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      stateboardName(cluster),
+			Namespace: cluster.GetNamespace(),
+			Labels:    labels,
+		},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{MatchLabels: labels},
+			Strategy: appsv1.DeploymentStrategy{Type: appsv1.RecreateDeploymentStrategyType},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: labels},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{
+							Name:    "stateboard",
+							Image:   managed.Image,
+							Command: command,
+							Env: []corev1.EnvVar{
+								{Name: "TARANTOOL_LISTEN", Value: fmt.Sprintf("0.0.0.0:%d", managed.Port)},
+								{Name: "TARANTOOL_WORKDIR", Value: stateboardWorkdir},
+								{Name: "TARANTOOL_PASSWORD", ValueFrom: &corev1.EnvVarSource{SecretKeyRef: password}},
+							},
+							Ports: []corev1.ContainerPort{
+								{Name: "iproto", ContainerPort: managed.Port, Protocol: corev1.ProtocolTCP},
+							},
+							ReadinessProbe: &corev1.Probe{
+								Handler: corev1.Handler{
+									TCPSocket: &corev1.TCPSocketAction{Port: intstr.FromString("iproto")},
+								},
+								PeriodSeconds: 5,
+							},
+							Resources: managed.Resources,
+							VolumeMounts: []corev1.VolumeMount{
+								{Name: "workdir", MountPath: stateboardWorkdir},
+							},
+						},
+					},
+					Volumes: []corev1.Volume{
+						{Name: "workdir", VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}},
+					},
+				},
+			},
+		},
+	}
+}
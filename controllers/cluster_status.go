@@ -0,0 +1,272 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	tarantooliov1alpha1 "github.com/tarantool/tarantool-operator/api/v1alpha1"
+	"github.com/tarantool/tarantool-operator/controllers/tarantool"
+	"github.com/tarantool/tarantool-operator/controllers/topology"
+)
+
+// updateClusterStatus refreshes the Cluster status from the Cartridge topology and the managed StatefulSets,
+// connectErr explains why no topology client was created and reconcileErr is the error Reconcile ends with.
+// The topology client caches its queries, so the status reuses the topology the reconcile fetched last.
+// The observed generation is only bumped once a reconcile of the generation succeeds.
+func (r *ClusterReconciler) updateClusterStatus(ctx context.Context, cluster *tarantooliov1alpha1.Cluster, topologyClient topology.TopologyService, stsList *appsv1.StatefulSetList, leaderStatus *tarantooliov1alpha1.LeaderStatus, connectErr, reconcileErr error) error {
+	status := cluster.Status.DeepCopy()
+	generation := cluster.GetGeneration()
+	if connectErr == nil && reconcileErr == nil && topologyClient != nil {
+		status.ObservedGeneration = generation
+	}
+	status.Leader = leaderStatus
+
+	var (
+		replicasets []*topology.ReplicasetData
+		stats       topology.ServerStatData
+		topologyErr = connectErr
+	)
+	if topologyErr == nil {
+		topologyErr = fmt.Errorf("no instance of the Cluster answers on the admin API")
+	}
+	if topologyClient != nil {
+		replicasets, topologyErr = topologyClient.GetReplicasets(ctx)
+		if topologyErr == nil {
+			stats, topologyErr = topologyClient.GetServerStat(ctx)
+		}
+	}
+
+	reachableMessage := ""
+	if topologyErr != nil {
+		reachableMessage = topologyErr.Error()
+	}
+	setCondition(&status.Conditions, generation, tarantooliov1alpha1.ClusterTopologyReachable, topologyErr == nil,
+		"LeaderAnswered", "LeaderUnreachable", reachableMessage)
+
+	var opErr *topology.Error
+	opFailed := errors.As(reconcileErr, &opErr) && !opErr.Retryable
+	opFailedReason, opFailedMessage := "NoErrors", ""
+	if opFailed {
+		opFailedReason, opFailedMessage = "OperationRefused", opErr.Error()
+		if errors.Is(opErr, topology.ErrUnauthorized) {
+			opFailedReason = "Unauthorized"
+		}
+	}
+	setCondition(&status.Conditions, generation, tarantooliov1alpha1.ClusterTopologyError, opFailed,
+		opFailedReason, opFailedReason, opFailedMessage)
+
+	replicasetsByUUID := make(map[string]*topology.ReplicasetData)
+	for _, rs := range replicasets {
+		replicasetsByUUID[rs.UUID] = rs
+	}
+
+	bucketsByServer := make(map[string]int)
+	for _, stat := range stats.Stats {
+		bucketsByServer[stat.UUID] = stat.Statistics.BucketsCount
+	}
+
+	plan := newRebalancePlan(replicasets, stats, cluster.Spec.BucketCount)
+
+	var (
+		joined, total int32
+		updated       int32
+		paused        []string
+		imported      int
+		bootstrapped  bool
+		unhealthy     []string
+	)
+
+	status.Replicasets = []tarantooliov1alpha1.ReplicasetStatus{}
+	for _, sts := range stsList.Items {
+		if tarantool.IsExpelled(&sts) {
+			continue
+		}
+
+		rsStatus := tarantooliov1alpha1.ReplicasetStatus{
+			Name:           sts.GetName(),
+			UUID:           sts.GetLabels()["tarantool.io/replicaset-uuid"],
+			TotalInstances: *sts.Spec.Replicas,
+		}
+
+		podsByUUID := make(map[string]string)
+		for i := 0; i < int(*sts.Spec.Replicas); i++ {
+			pod := &corev1.Pod{}
+			name := types.NamespacedName{
+				Namespace: sts.GetNamespace(),
+				Name:      fmt.Sprintf("%s-%d", sts.GetName(), i),
+			}
+			if err := r.Get(context.TODO(), name, pod); err != nil {
+				continue
+			}
+
+			if tarantool.IsExpelling(pod) || tarantool.IsInstanceExpelled(pod) {
+				rsStatus.ExpellingInstances++
+				rsStatus.TotalInstances--
+				continue
+			}
+
+			if tarantool.IsJoined(pod) {
+				rsStatus.JoinedInstances++
+				podsByUUID[instanceUUID(pod)] = pod.GetName()
+			}
+			if tarantool.IsUpdated(pod, &sts) {
+				rsStatus.UpdatedInstances++
+			}
+		}
+
+		if data, ok := replicasetsByUUID[rsStatus.UUID]; ok {
+			rsStatus.Roles = data.Roles
+			if data.Weight != nil {
+				weight := int32(*data.Weight)
+				rsStatus.Weight = &weight
+			}
+
+			rsStatus.BucketsCount = int32(replicasetBucketsCount(data, bucketsByServer))
+			for _, server := range data.Servers {
+				if server.Status != "" && server.Status != "healthy" {
+					unhealthy = append(unhealthy, server.URI)
+				}
+			}
+			rsStatus.TargetBucketsCount = int32(plan.Targets[rsStatus.UUID])
+			rsStatus.Master = podsByUUID[replicasetMasterUUID(data)]
+		}
+
+		if outcome, ok := tarantool.GetSwitchoverOutcome(&sts); ok {
+			rsStatus.LastSwitchover = &tarantooliov1alpha1.SwitchoverStatus{
+				Target:   outcome.Target,
+				Result:   outcome.Result,
+				Message:  outcome.Message,
+				Finished: metav1.NewTime(outcome.Finished),
+			}
+		}
+
+		replicasetBuckets.WithLabelValues(cluster.GetNamespace(), cluster.GetName(), sts.GetName()).Set(float64(rsStatus.BucketsCount))
+		replicasetTargetBuckets.WithLabelValues(cluster.GetNamespace(), cluster.GetName(), sts.GetName()).Set(float64(rsStatus.TargetBucketsCount))
+
+		joined += rsStatus.JoinedInstances
+		updated += rsStatus.UpdatedInstances
+		if tarantool.IsRolloutPaused(&sts) {
+			paused = append(paused, fmt.Sprintf("%s: %s", sts.GetName(), tarantool.GetRolloutPausedReason(&sts)))
+		}
+		total += rsStatus.TotalInstances
+		if tarantool.IsImported(&sts) {
+			imported++
+		}
+
+		stsAnnotations := sts.GetAnnotations()
+		if stsAnnotations["tarantool.io/isBootstrapped"] == "1" {
+			bootstrapped = true
+		}
+
+		status.Replicasets = append(status.Replicasets, rsStatus)
+	}
+
+	deleteRemovedReplicasetMetrics(cluster, cluster.Status.Replicasets, status.Replicasets)
+
+	allJoined := joined == total
+	setCondition(&status.Conditions, generation, tarantooliov1alpha1.ClusterAllInstancesJoined, allJoined,
+		"AllJoined", "InstancesPending", fmt.Sprintf("%d of %d instances joined", joined, total))
+	setCondition(&status.Conditions, generation, tarantooliov1alpha1.ClusterVshardBootstrapped, bootstrapped,
+		"Bootstrapped", "NotBootstrapped", "")
+	failoverConfigured, failoverMessage := false, reachableMessage
+	if topologyErr == nil {
+		failoverConfigured, failoverMessage = r.failoverConfigured(ctx, cluster, topologyClient)
+	}
+	setCondition(&status.Conditions, generation, tarantooliov1alpha1.ClusterFailoverConfigured, failoverConfigured,
+		"Configured", "NotConfigured", failoverMessage)
+
+	status.Stateboard = r.stateboardStatus(cluster)
+	if status.Stateboard != nil {
+		setCondition(&status.Conditions, generation, tarantooliov1alpha1.ClusterStateboardReady, status.Stateboard.Ready,
+			"Available", "Unavailable", status.Stateboard.Message)
+	} else {
+		meta.RemoveStatusCondition(&status.Conditions, tarantooliov1alpha1.ClusterStateboardReady)
+	}
+
+	if cluster.Spec.Import {
+		setCondition(&status.Conditions, generation, tarantooliov1alpha1.ClusterTopologyImported, imported == len(status.Replicasets),
+			"Imported", "ImportPending", fmt.Sprintf("%d of %d replicasets imported", imported, len(status.Replicasets)))
+	} else {
+		meta.RemoveStatusCondition(&status.Conditions, tarantooliov1alpha1.ClusterTopologyImported)
+	}
+
+	setCondition(&status.Conditions, generation, tarantooliov1alpha1.ClusterRollingUpdate, updated != total,
+		"InProgress", "UpToDate", fmt.Sprintf("%d of %d instances updated", updated, total))
+	pausedMessage := ""
+	if len(paused) > 0 {
+		pausedMessage = fmt.Sprintf("remove the tarantool.io/rolloutPaused annotation to resume: %v", paused)
+	}
+	setCondition(&status.Conditions, generation, tarantooliov1alpha1.ClusterRollingUpdatePaused, len(paused) > 0,
+		"InstanceUnhealthy", "NotPaused", pausedMessage)
+
+	status.Rebalancing = nil
+	rebalancing := false
+	rebalancingMessage := ""
+	if bootstrapped && topologyErr == nil {
+		status.Rebalancing = &tarantooliov1alpha1.RebalancingStatus{
+			TotalBuckets:     int32(plan.Total),
+			MisplacedBuckets: int32(plan.Misplaced),
+			Progress:         plan.Progress(),
+		}
+		rebalancing = plan.InProgress(cluster.Spec.Rebalancing.DisbalanceThreshold)
+		rebalancingMessage = fmt.Sprintf("%d of %d buckets to be moved", plan.Misplaced, plan.Total)
+
+		rebalanceMisplacedBuckets.WithLabelValues(cluster.GetNamespace(), cluster.GetName()).Set(float64(plan.Misplaced))
+		rebalanceProgress.WithLabelValues(cluster.GetNamespace(), cluster.GetName()).Set(float64(plan.Progress()))
+	}
+	setCondition(&status.Conditions, generation, tarantooliov1alpha1.ClusterRebalancing, rebalancing,
+		"BucketsMoving", "Balanced", rebalancingMessage)
+
+	stateboardDown := status.Stateboard != nil && !status.Stateboard.Ready
+	degraded := topologyErr != nil || !allJoined || len(unhealthy) > 0 || stateboardDown
+	degradedMessage := ""
+	switch {
+	case len(unhealthy) > 0:
+		degradedMessage = fmt.Sprintf("unhealthy instances: %v", unhealthy)
+	case stateboardDown:
+		degradedMessage = fmt.Sprintf("stateboard is not ready: %s", status.Stateboard.Message)
+	}
+	setCondition(&status.Conditions, generation, tarantooliov1alpha1.ClusterDegraded, degraded,
+		"Degraded", "Healthy", degradedMessage)
+
+	switch {
+	case bootstrapped && !degraded:
+		status.State = tarantooliov1alpha1.ClusterStateReady
+	case bootstrapped && degraded:
+		status.State = tarantooliov1alpha1.ClusterStateDegraded
+	default:
+		status.State = tarantooliov1alpha1.ClusterStatePending
+	}
+
+	if equality.Semantic.DeepEqual(status, &cluster.Status) {
+		return nil
+	}
+
+	cluster.Status = *status
+	return r.Status().Update(context.TODO(), cluster)
+}
+
+// setCondition sets a condition with the reason picked by its status
+func setCondition(conditions *[]metav1.Condition, generation int64, conditionType string, ok bool, trueReason, falseReason, message string) {
+	condition := metav1.Condition{
+		Type:               conditionType,
+		Status:             metav1.ConditionTrue,
+		Reason:             trueReason,
+		Message:            message,
+		ObservedGeneration: generation,
+	}
+	if !ok {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = falseReason
+	}
+
+	meta.SetStatusCondition(conditions, condition)
+}
@@ -0,0 +1,144 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	tarantooliov1alpha1 "github.com/tarantool/tarantool-operator/api/v1alpha1"
+	"github.com/tarantool/tarantool-operator/controllers/tarantool"
+	"github.com/tarantool/tarantool-operator/controllers/topology"
+)
+
+// switchoverTimeout is how long Cartridge may take to make the requested instance the master
+const switchoverTimeout = time.Minute
+
+// switchover makes the pods named by the tarantool.io/switchover annotations the masters of their replicasets.
+// With the stateful failover the instance is promoted, otherwise it is put first in the failover priority.
+// The outcome is kept on the StatefulSet and the request is removed, a failed switchover is not retried.
+func (r *ClusterReconciler) switchover(ctx context.Context, cluster *tarantooliov1alpha1.Cluster, topologyClient topology.TopologyService, stsList *appsv1.StatefulSetList) (ctrl.Result, error) {
+	reqLogger := log.FromContext(ctx)
+
+	var replicasetsByUUID map[string]*topology.ReplicasetData
+	for i := range stsList.Items {
+		sts := &stsList.Items[i]
+		if tarantool.IsExpelled(sts) {
+			continue
+		}
+
+		target, at, ok, err := tarantool.GetSwitchoverRequest(sts)
+		if !ok {
+			continue
+		}
+
+		stsLogger := reqLogger.WithValues("StatefulSet.Name", sts.GetName(), "Pod.Name", target)
+		if err != nil {
+			return r.finishSwitchover(ctx, sts, target, false, fmt.Sprintf("invalid switchover time: %s", err))
+		}
+		if time.Now().Before(at) {
+			stsLogger.Info("switchover is scheduled", "at", at)
+			continue
+		}
+
+		if replicasetsByUUID == nil {
+			replicasets, err := topologyClient.GetReplicasets(ctx)
+			if err != nil {
+				return topologyResult(ctx, err)
+			}
+
+			replicasetsByUUID = make(map[string]*topology.ReplicasetData)
+			for _, rs := range replicasets {
+				replicasetsByUUID[rs.UUID] = rs
+			}
+		}
+
+		replicaset, ok := replicasetsByUUID[sts.GetLabels()["tarantool.io/replicaset-uuid"]]
+		if !ok {
+			stsLogger.Info("waiting for the replicaset to join before the switchover")
+			continue
+		}
+
+		if !strings.HasPrefix(target, sts.GetName()+"-") {
+			return r.finishSwitchover(ctx, sts, target, false, fmt.Sprintf("pod %s does not belong to the replicaset", target))
+		}
+
+		pods, err := r.getReplicasetPods(sts)
+		if err != nil {
+			return ctrl.Result{RequeueAfter: time.Duration(5 * time.Second)}, err
+		}
+
+		pod := findPod(pods, target)
+		if pod != nil && instanceUUID(pod) == replicasetMasterUUID(replicaset) {
+			stsLogger.Info("switchover is complete")
+			return r.finishSwitchover(ctx, sts, target, true, fmt.Sprintf("%s is the master", target))
+		}
+
+		if started, ok := tarantool.GetSwitchoverStarted(sts); ok {
+			if time.Since(started) > switchoverTimeout {
+				return r.finishSwitchover(ctx, sts, target, false, fmt.Sprintf("%s did not become the master in %s", target, switchoverTimeout))
+			}
+
+			stsLogger.Info("waiting for the master to switch")
+			return ctrl.Result{RequeueAfter: time.Duration(5 * time.Second)}, nil
+		}
+
+		if !isInstanceHealthy(pod, replicaset) {
+			return r.finishSwitchover(ctx, sts, target, false, fmt.Sprintf("instance %s is not healthy", target))
+		}
+
+		stsLogger.Info("switching master", "from", replicasetMasterUUID(replicaset), "to", instanceUUID(pod))
+		if err := switchMaster(ctx, cluster, topologyClient, replicaset.UUID, instanceUUID(pod)); err != nil {
+			if topology.IsRetryable(err) {
+				return topologyResult(ctx, err)
+			}
+			return r.finishSwitchover(ctx, sts, target, false, err.Error())
+		}
+
+		tarantool.MarkSwitchoverStarted(sts, time.Now())
+		if err := r.Update(context.TODO(), sts); err != nil {
+			return ctrl.Result{RequeueAfter: time.Duration(5 * time.Second)}, err
+		}
+
+		return ctrl.Result{RequeueAfter: time.Duration(5 * time.Second)}, nil
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// switchMaster makes the instance the master of its replicaset: the stateful failover is asked to promote it,
+// otherwise it is put first in the failover priority
+func switchMaster(ctx context.Context, cluster *tarantooliov1alpha1.Cluster, topologyClient topology.TopologyService, replicasetUUID string, instanceUUID string) error {
+	if cluster.Spec.Failover != nil && cluster.Spec.Failover.Mode == tarantooliov1alpha1.FailoverModeStateful {
+		return topologyClient.Promote(ctx, replicasetUUID, instanceUUID)
+	}
+
+	return topologyClient.SetFailoverPriority(ctx, replicasetUUID, []string{instanceUUID})
+}
+
+// finishSwitchover records the outcome of the switchover on the StatefulSet
+func (r *ClusterReconciler) finishSwitchover(ctx context.Context, sts *appsv1.StatefulSet, target string, succeeded bool, message string) (ctrl.Result, error) {
+	result := tarantool.SwitchoverSucceeded
+	if succeeded {
+		tarantool.SetPinnedMaster(sts, target)
+	} else {
+		result = tarantool.SwitchoverFailed
+		log.FromContext(ctx).Info("switchover failed", "StatefulSet.Name", sts.GetName(), "Pod.Name", target, "reason", message)
+	}
+
+	tarantool.FinishSwitchover(sts, tarantool.SwitchoverOutcome{
+		Target:   target,
+		Result:   result,
+		Message:  message,
+		Finished: time.Now(),
+	})
+	if err := r.Update(context.TODO(), sts); err != nil {
+		return ctrl.Result{RequeueAfter: time.Duration(5 * time.Second)}, err
+	}
+
+	return ctrl.Result{Requeue: true}, nil
+}
@@ -0,0 +1,338 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	tarantooliov1alpha1 "github.com/tarantool/tarantool-operator/api/v1alpha1"
+	"github.com/tarantool/tarantool-operator/controllers/tarantool"
+	"github.com/tarantool/tarantool-operator/controllers/topology"
+)
+
+// reconcileTopology brings the Cartridge topology to the state described by the StatefulSets and their pods.
+// Missing instances are joined, replicaset roles and weights and the zones and labels of instances are edited
+// by a single edit_topology request, so Cartridge validates and applies the whole change with one config reload.
+// When Cartridge refuses the request, its changes are applied one by one and the refused ones are recorded
+// on their pods and StatefulSets: the next attempts apply them apart from the others, so they block nothing.
+// Reconcile does not go further until every instance is joined.
+func (r *ClusterReconciler) reconcileTopology(ctx context.Context, cluster *tarantooliov1alpha1.Cluster, topologyClient topology.TopologyService, stsList *appsv1.StatefulSetList) (ctrl.Result, error) {
+	reqLogger := log.FromContext(ctx)
+
+	replicasets, err := topologyClient.GetReplicasets(ctx)
+	if err != nil {
+		return topologyResult(ctx, err)
+	}
+
+	actualByUUID := make(map[string]*topology.ReplicasetData)
+	servers := make(map[string]bool)
+	for _, rs := range replicasets {
+		actualByUUID[rs.UUID] = rs
+		for _, server := range rs.Servers {
+			servers[server.UUID] = true
+		}
+	}
+
+	var (
+		desired []*topology.DesiredReplicaset
+		pending int
+		targets = &topologyTargets{
+			pods:         make(map[string]*corev1.Pod),
+			statefulSets: make(map[string]*appsv1.StatefulSet),
+		}
+	)
+	for i := range stsList.Items {
+		sts := &stsList.Items[i]
+		if tarantool.IsExpelled(sts) {
+			continue
+		}
+
+		stsLogger := reqLogger.WithValues("StatefulSet.Name", sts.GetName())
+
+		roles, err := topology.GetRoles(&sts.ObjectMeta)
+		if err != nil {
+			stsLogger.Error(err, "Getting roles from statefulset")
+			return ctrl.Result{RequeueAfter: time.Duration(5 * time.Second)}, err
+		}
+
+		vshardGroup, err := topology.VshardGroup(sts.Spec.Template.GetLabels(), cluster.Spec.VshardGroups)
+		if err != nil {
+			stsLogger.Error(err, "Getting vshard group")
+			return ctrl.Result{RequeueAfter: time.Duration(5 * time.Second)}, err
+		}
+
+		rs := &topology.DesiredReplicaset{
+			UUID:        sts.GetLabels()["tarantool.io/replicaset-uuid"],
+			Alias:       sts.GetName(),
+			Roles:       roles,
+			VshardGroup: vshardGroup,
+		}
+		targets.statefulSets[rs.UUID] = sts
+
+		pods, err := r.getReplicasetPods(sts)
+		if err != nil {
+			return ctrl.Result{RequeueAfter: time.Duration(5 * time.Second)}, err
+		}
+		if !tarantool.IsDraining(sts) {
+			pending += int(*sts.Spec.Replicas) - len(pods)
+		}
+
+		for _, pod := range pods {
+			if tarantool.IsExpelling(pod) || tarantool.IsInstanceExpelled(pod) {
+				continue
+			}
+
+			if servers[instanceUUID(pod)] && !tarantool.IsJoined(pod) {
+				tarantool.MarkJoined(pod)
+				if err := r.Update(context.TODO(), pod); err != nil {
+					return ctrl.Result{RequeueAfter: time.Duration(5 * time.Second)}, err
+				}
+			}
+			if !servers[instanceUUID(pod)] && tarantool.IsDraining(sts) {
+				continue
+			}
+			// an instance that cannot answer yet would fail the join of the whole batch
+			if !servers[instanceUUID(pod)] && (!tarantool.IsReady(pod) || pod.Status.PodIP == "") {
+				pending++
+				continue
+			}
+
+			server, err := r.desiredServer(cluster, pod)
+			if err != nil {
+				return ctrl.Result{RequeueAfter: time.Duration(5 * time.Second)}, err
+			}
+			rs.Servers = append(rs.Servers, server)
+			targets.pods[instanceUUID(pod)] = pod
+		}
+
+		if actual, ok := actualByUUID[rs.UUID]; ok && actual.Weight != nil {
+			weight, err := r.desiredWeight(ctx, cluster, topologyClient, sts, *actual.Weight)
+			if err != nil {
+				return topologyResult(ctx, err)
+			}
+			rs.Weight = weight
+		}
+
+		desired = append(desired, rs)
+	}
+
+	patch := topology.PlanTopology(desired, replicasets)
+	if !patch.IsEmpty() {
+		if result, err := r.applyTopologyPatch(ctx, topologyClient, patch, targets); err != nil || !result.IsZero() {
+			return result, err
+		}
+	}
+
+	if pending > 0 {
+		reqLogger.Info("waiting for instances to be created and ready to join", "pending", pending)
+		return ctrl.Result{RequeueAfter: time.Duration(5 * time.Second)}, nil
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// desiredServer describes the instance of the pod: its advertise uri, the zone of its node
+// and the values of the labels listed in the Cluster spec
+func (r *ClusterReconciler) desiredServer(cluster *tarantooliov1alpha1.Cluster, pod *corev1.Pod) (*topology.DesiredServer, error) {
+	zone, err := r.podZone(pod)
+	if err != nil {
+		return nil, err
+	}
+
+	labels := make(map[string]string)
+	for _, name := range cluster.Spec.ServerLabels {
+		if value, ok := pod.GetLabels()[name]; ok {
+			labels[name] = value
+		}
+	}
+
+	return &topology.DesiredServer{
+		UUID:          instanceUUID(pod),
+		URI:           topology.AdvertiseURI(pod, cluster.GetName(), cluster.Spec.ClusterDomainName, cluster.Spec.BinaryPort),
+		Zone:          zone,
+		ManagedLabels: cluster.Spec.ServerLabels,
+		Labels:        labels,
+	}, nil
+}
+
+// topologyTargets are the objects the changes of a topology patch are made for
+type topologyTargets struct {
+	// pods by the uuid of the instance to join or edit
+	pods map[string]*corev1.Pod
+	// statefulSets by replicaset uuid
+	statefulSets map[string]*appsv1.StatefulSet
+}
+
+// objects returns the pods and StatefulSets changed by the patch
+func (t *topologyTargets) objects(patch *topology.TopologyPatch) []client.Object {
+	var objects []client.Object
+	for _, edit := range patch.Replicasets {
+		if sts, ok := t.statefulSets[edit.UUID]; ok && (edit.Roles != nil || edit.Weight != nil) {
+			objects = append(objects, sts)
+		}
+		for _, server := range edit.JoinServers {
+			if pod, ok := t.pods[server.UUID]; ok {
+				objects = append(objects, pod)
+			}
+		}
+	}
+	for _, edit := range patch.Servers {
+		if pod, ok := t.pods[edit.UUID]; ok {
+			objects = append(objects, pod)
+		}
+	}
+
+	return objects
+}
+
+// key identifies what a single change of the patch is made for: a replicaset, which is created by
+// its first join, or an instance
+func (t *topologyTargets) key(item *topology.TopologyPatch) string {
+	if len(item.Replicasets) > 0 {
+		return item.Replicasets[0].UUID
+	}
+
+	return item.Servers[0].UUID
+}
+
+// applyTopologyPatch applies the changes not refused before with a single edit_topology request,
+// the refused ones and all of them when the request fails are applied one by one
+func (r *ClusterReconciler) applyTopologyPatch(ctx context.Context, topologyClient topology.TopologyService, patch *topology.TopologyPatch, targets *topologyTargets) (ctrl.Result, error) {
+	reqLogger := log.FromContext(ctx)
+
+	// a replicaset is created by its first join, so all changes of a replicaset are either batched or not
+	refusedKeys := make(map[string]bool)
+	items := patch.Items()
+	for _, item := range items {
+		for _, object := range targets.objects(item) {
+			if tarantool.IsTopologyRefused(object) {
+				refusedKeys[targets.key(item)] = true
+			}
+		}
+	}
+
+	var batch, single []*topology.TopologyPatch
+	for _, item := range items {
+		if refusedKeys[targets.key(item)] {
+			single = append(single, item)
+			continue
+		}
+		batch = append(batch, item)
+	}
+
+	if len(batch) > 0 {
+		merged := topology.MergePatches(batch...)
+		reqLogger.Info("editing topology", "replicasets", len(merged.Replicasets), "changes", len(batch))
+		err := topologyClient.EditTopology(ctx, merged)
+		if result, err := r.handleTopologyEdit(ctx, merged, targets, err); err != nil || !result.IsZero() {
+			if topology.IsRetryable(err) {
+				return topologyResult(ctx, err)
+			}
+			if !result.IsZero() {
+				return result, nil
+			}
+
+			reqLogger.Info("topology edit refused, applying changes one by one", "error", err.Error())
+			single = append(batch, single...)
+		}
+	}
+
+	var (
+		refused  []string
+		firstErr error
+	)
+	for _, item := range single {
+		err := topologyClient.EditTopology(ctx, item)
+		result, err := r.handleTopologyEdit(ctx, item, targets, err)
+		if err == nil && result.IsZero() {
+			continue
+		}
+		if topology.IsRetryable(err) {
+			return topologyResult(ctx, err)
+		}
+		if !result.IsZero() {
+			return result, nil
+		}
+
+		for _, object := range targets.objects(item) {
+			refused = append(refused, object.GetName())
+			tarantool.MarkTopologyRefused(object, err.Error())
+			if err := r.Update(context.TODO(), object); err != nil {
+				return ctrl.Result{RequeueAfter: time.Duration(5 * time.Second)}, err
+			}
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	if firstErr != nil {
+		reqLogger.Error(firstErr, "Edit topology error", "refused", refused)
+		return ctrl.Result{}, fmt.Errorf("topology changes refused for %s: %w", strings.Join(refused, ", "), firstErr)
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// handleTopologyEdit marks the instances of an applied patch joined and clears the refusals of its objects.
+// A topology which is down makes Reconcile wait, other errors are returned.
+func (r *ClusterReconciler) handleTopologyEdit(ctx context.Context, patch *topology.TopologyPatch, targets *topologyTargets, err error) (ctrl.Result, error) {
+	if err != nil {
+		if topology.IsTopologyDown(err) {
+			log.FromContext(ctx).Info("Topology is down", "error", err.Error())
+			return ctrl.Result{RequeueAfter: time.Duration(5 * time.Second)}, nil
+		}
+
+		return ctrl.Result{}, err
+	}
+
+	for _, object := range targets.objects(patch) {
+		pod, isPod := object.(*corev1.Pod)
+		if !isPod && !tarantool.IsTopologyRefused(object) {
+			continue
+		}
+		if isPod {
+			tarantool.MarkJoined(pod)
+		}
+		tarantool.ClearTopologyRefused(object)
+		if err := r.Update(context.TODO(), object); err != nil {
+			return ctrl.Result{RequeueAfter: time.Duration(5 * time.Second)}, err
+		}
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// desiredWeight returns the weight the replicaset should get now, nil when it is reached or has to wait
+// for the buckets moved by the previous weight step
+func (r *ClusterReconciler) desiredWeight(ctx context.Context, cluster *tarantooliov1alpha1.Cluster, topologyClient topology.TopologyService, sts *appsv1.StatefulSet, current int) (*float64, error) {
+	weight, ok := sts.GetAnnotations()["tarantool.io/replicaset-weight"]
+	if !ok || strconv.Itoa(current) == weight {
+		return nil, nil
+	}
+
+	next, ok, err := nextWeight(ctx, cluster, topologyClient, current, weight)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		log.FromContext(ctx).Info("buckets are still moving, postpone next weight step", "StatefulSet.Name", sts.GetName())
+		return nil, nil
+	}
+
+	value, err := strconv.ParseFloat(next, 64)
+	if err != nil {
+		log.FromContext(ctx).Info("ignoring invalid replicaset weight", "StatefulSet.Name", sts.GetName(), "weight", next)
+		return nil, nil
+	}
+
+	return &value, nil
+}
@@ -0,0 +1,29 @@
+package controllers
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+//+kubebuilder:rbac:groups="",resources=nodes,verbs=get;list;watch
+
+// podZone returns the zone of the node the pod is scheduled on, empty when the pod is not scheduled yet
+// or the node has no topology.kubernetes.io/zone label
+func (r *ClusterReconciler) podZone(pod *corev1.Pod) (string, error) {
+	if pod.Spec.NodeName == "" {
+		return "", nil
+	}
+
+	node := &corev1.Node{}
+	if err := r.Get(context.TODO(), types.NamespacedName{Name: pod.Spec.NodeName}, node); err != nil {
+		if errors.IsNotFound(err) {
+			return "", nil
+		}
+		return "", err
+	}
+
+	return node.GetLabels()[corev1.LabelTopologyZone], nil
+}
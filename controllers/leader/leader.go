@@ -0,0 +1,121 @@
+package leader
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Reasons a leader was chosen
+const (
+	// ReasonCurrentLeaderHealthy means the previous leader still answers and stays in charge
+	ReasonCurrentLeaderHealthy = "CurrentLeaderHealthy"
+	// ReasonJoinedInstanceElected means an instance of the joined topology was chosen
+	ReasonJoinedInstanceElected = "JoinedInstanceElected"
+	// ReasonUnconfiguredInstanceElected means no joined instance answered and a fresh one will bootstrap the topology
+	ReasonUnconfiguredInstanceElected = "UnconfiguredInstanceElected"
+)
+
+// Candidate is an instance able to serve the Cartridge admin API
+type Candidate struct {
+	// Address is the host:port of the admin API
+	Address string
+	// Pod is the name of the pod running the instance
+	Pod string
+	// Joined reports whether the operator has joined the instance to the topology
+	Joined bool
+}
+
+// ScanTimeout bounds the probes of all candidates when the current leader does not answer
+const ScanTimeout = 5 * time.Second
+
+// Probe asks the instance at address about itself, configured reports whether it belongs to a topology
+type Probe func(ctx context.Context, address string) (configured bool, err error)
+
+// Result is the chosen leader with the reason it was chosen
+type Result struct {
+	Candidate
+	Reason string
+}
+
+// probeResult is the answer of a candidate to a probe
+type probeResult struct {
+	configured bool
+	err        error
+}
+
+// Select picks the instance to send topology requests to.
+// The current leader is probed first and kept while it answers as a configured instance. Otherwise all candidates
+// are probed concurrently within ScanTimeout and instances joined by the operator are preferred. An unconfigured
+// instance is chosen only when no configured one answers, it is the case of a cluster which is not bootstrapped yet.
+func Select(ctx context.Context, candidates []Candidate, current string, probe Probe) (*Result, error) {
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("no instances available")
+	}
+
+	ordered := make([]Candidate, len(candidates))
+	copy(ordered, candidates)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		if (ordered[i].Address == current) != (ordered[j].Address == current) {
+			return ordered[i].Address == current
+		}
+		if ordered[i].Joined != ordered[j].Joined {
+			return ordered[i].Joined
+		}
+		return ordered[i].Pod < ordered[j].Pod
+	})
+
+	results := make([]probeResult, len(ordered))
+	scanned := 0
+	if ordered[0].Address == current {
+		configured, err := probe(ctx, current)
+		if err == nil && configured {
+			return &Result{Candidate: ordered[0], Reason: ReasonCurrentLeaderHealthy}, nil
+		}
+		results[0] = probeResult{configured: configured, err: err}
+		scanned = 1
+	}
+
+	scanCtx, cancel := context.WithTimeout(ctx, ScanTimeout)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	for i := scanned; i < len(ordered); i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			configured, err := probe(scanCtx, ordered[i].Address)
+			results[i] = probeResult{configured: configured, err: err}
+		}(i)
+	}
+	wg.Wait()
+
+	var (
+		fallback *Result
+		failures []string
+	)
+	for i, candidate := range ordered {
+		if err := results[i].err; err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %s", candidate.Address, err))
+			continue
+		}
+
+		if !results[i].configured {
+			if fallback == nil {
+				fallback = &Result{Candidate: candidate, Reason: ReasonUnconfiguredInstanceElected}
+			}
+			continue
+		}
+
+		return &Result{Candidate: candidate, Reason: ReasonJoinedInstanceElected}, nil
+	}
+
+	if fallback != nil {
+		return fallback, nil
+	}
+
+	return nil, fmt.Errorf("no instance answered: %s", strings.Join(failures, "; "))
+}
@@ -0,0 +1,185 @@
+package leader
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakeInstance struct {
+	configured bool
+	down       bool
+}
+
+func probeOf(instances map[string]fakeInstance) Probe {
+	return func(_ context.Context, address string) (bool, error) {
+		instance, ok := instances[address]
+		if !ok || instance.down {
+			return false, fmt.Errorf("connection refused")
+		}
+		return instance.configured, nil
+	}
+}
+
+func TestSelect_KeepsHealthyCurrentLeader(t *testing.T) {
+	candidates := []Candidate{
+		{Address: "10.0.0.1:8081", Pod: "storage-0-0", Joined: true},
+		{Address: "10.0.0.2:8081", Pod: "storage-0-1", Joined: true},
+	}
+	probe := probeOf(map[string]fakeInstance{
+		"10.0.0.1:8081": {configured: true},
+		"10.0.0.2:8081": {configured: true},
+	})
+
+	result, err := Select(context.Background(), candidates, "10.0.0.2:8081", probe)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Pod != "storage-0-1" || result.Reason != ReasonCurrentLeaderHealthy {
+		t.Fatalf("unexpected leader: %+v", result)
+	}
+}
+
+func TestSelect_ProbesOnlyHealthyCurrentLeader(t *testing.T) {
+	candidates := []Candidate{
+		{Address: "10.0.0.1:8081", Pod: "storage-0-0", Joined: true},
+		{Address: "10.0.0.2:8081", Pod: "storage-0-1", Joined: true},
+	}
+	var probed []string
+	probe := func(_ context.Context, address string) (bool, error) {
+		probed = append(probed, address)
+		return true, nil
+	}
+
+	if _, err := Select(context.Background(), candidates, "10.0.0.2:8081", probe); err != nil {
+		t.Fatal(err)
+	}
+	if len(probed) != 1 || probed[0] != "10.0.0.2:8081" {
+		t.Fatalf("only the current leader must be probed, got %v", probed)
+	}
+}
+
+func TestSelect_ScansCandidatesConcurrently(t *testing.T) {
+	candidates := []Candidate{
+		{Address: "10.0.0.1:8081", Pod: "storage-0-0", Joined: true},
+		{Address: "10.0.0.2:8081", Pod: "storage-0-1", Joined: true},
+		{Address: "10.0.0.3:8081", Pod: "storage-0-2", Joined: true},
+	}
+
+	// every probe of the scan waits for the others to start, a sequential scan would time out
+	var (
+		started   sync.WaitGroup
+		mu        sync.Mutex
+		deadlines = make(map[time.Time]bool)
+	)
+	started.Add(2)
+	probe := func(ctx context.Context, address string) (bool, error) {
+		if address == "10.0.0.1:8081" {
+			return false, fmt.Errorf("connection refused")
+		}
+
+		deadline, ok := ctx.Deadline()
+		if !ok {
+			return false, fmt.Errorf("probe without deadline")
+		}
+		mu.Lock()
+		deadlines[deadline] = true
+		mu.Unlock()
+
+		started.Done()
+		done := make(chan struct{})
+		go func() {
+			started.Wait()
+			close(done)
+		}()
+		select {
+		case <-done:
+			return true, nil
+		case <-time.After(time.Second):
+			return false, fmt.Errorf("probes are not concurrent")
+		}
+	}
+
+	result, err := Select(context.Background(), candidates, "10.0.0.1:8081", probe)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Pod != "storage-0-1" || result.Reason != ReasonJoinedInstanceElected {
+		t.Fatalf("unexpected leader: %+v", result)
+	}
+	if len(deadlines) != 1 {
+		t.Fatalf("probes of the scan must share one deadline, got %d", len(deadlines))
+	}
+}
+
+func TestSelect_FailsOverFromDeadLeader(t *testing.T) {
+	candidates := []Candidate{
+		{Address: "10.0.0.1:8081", Pod: "storage-0-0", Joined: false},
+		{Address: "10.0.0.2:8081", Pod: "storage-0-1", Joined: true},
+		{Address: "10.0.0.3:8081", Pod: "storage-0-2", Joined: true},
+	}
+	probe := probeOf(map[string]fakeInstance{
+		"10.0.0.1:8081": {configured: false},
+		"10.0.0.2:8081": {down: true},
+		"10.0.0.3:8081": {configured: true},
+	})
+
+	result, err := Select(context.Background(), candidates, "10.0.0.2:8081", probe)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Pod != "storage-0-2" || result.Reason != ReasonJoinedInstanceElected {
+		t.Fatalf("unexpected leader: %+v", result)
+	}
+}
+
+func TestSelect_PrefersConfiguredInstances(t *testing.T) {
+	candidates := []Candidate{
+		{Address: "10.0.0.1:8081", Pod: "router-0-0", Joined: true},
+		{Address: "10.0.0.2:8081", Pod: "storage-0-0", Joined: false},
+	}
+	probe := probeOf(map[string]fakeInstance{
+		"10.0.0.1:8081": {configured: false},
+		"10.0.0.2:8081": {configured: true},
+	})
+
+	result, err := Select(context.Background(), candidates, "10.0.0.1:8081", probe)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Pod != "storage-0-0" || result.Reason != ReasonJoinedInstanceElected {
+		t.Fatalf("unexpected leader: %+v", result)
+	}
+}
+
+func TestSelect_FallsBackToUnconfiguredInstance(t *testing.T) {
+	candidates := []Candidate{
+		{Address: "10.0.0.2:8081", Pod: "storage-0-1"},
+		{Address: "10.0.0.1:8081", Pod: "storage-0-0"},
+	}
+	probe := probeOf(map[string]fakeInstance{
+		"10.0.0.1:8081": {configured: false},
+		"10.0.0.2:8081": {configured: false},
+	})
+
+	result, err := Select(context.Background(), candidates, "", probe)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Pod != "storage-0-0" || result.Reason != ReasonUnconfiguredInstanceElected {
+		t.Fatalf("unexpected leader: %+v", result)
+	}
+}
+
+func TestSelect_NoInstanceAnswers(t *testing.T) {
+	candidates := []Candidate{{Address: "10.0.0.1:8081", Pod: "storage-0-0", Joined: true}}
+
+	if _, err := Select(context.Background(), candidates, "", probeOf(nil)); err == nil {
+		t.Fatal("expected an error when no instance answers")
+	}
+	if _, err := Select(context.Background(), nil, "", probeOf(nil)); err == nil {
+		t.Fatal("expected an error without candidates")
+	}
+}
@@ -0,0 +1,57 @@
+package controllers
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	tarantooliov1alpha1 "github.com/tarantool/tarantool-operator/api/v1alpha1"
+)
+
+var (
+	replicasetBuckets = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "tarantool_operator_replicaset_buckets",
+		Help: "Number of vshard buckets stored on the replicaset",
+	}, []string{"namespace", "cluster", "replicaset"})
+
+	replicasetTargetBuckets = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "tarantool_operator_replicaset_target_buckets",
+		Help: "Number of vshard buckets the replicaset will store once rebalancing is over",
+	}, []string{"namespace", "cluster", "replicaset"})
+
+	rebalanceMisplacedBuckets = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "tarantool_operator_rebalance_misplaced_buckets",
+		Help: "Number of vshard buckets to be moved to reach the distribution implied by weights",
+	}, []string{"namespace", "cluster"})
+
+	rebalanceProgress = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "tarantool_operator_rebalance_progress",
+		Help: "Percentage of vshard buckets stored on their target replicasets",
+	}, []string{"namespace", "cluster"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(replicasetBuckets, replicasetTargetBuckets, rebalanceMisplacedBuckets, rebalanceProgress)
+}
+
+// deleteRemovedReplicasetMetrics drops the gauges of the replicasets which were reported before but are gone now
+func deleteRemovedReplicasetMetrics(cluster *tarantooliov1alpha1.Cluster, previous, current []tarantooliov1alpha1.ReplicasetStatus) {
+	kept := make(map[string]bool)
+	for _, rs := range current {
+		kept[rs.Name] = true
+	}
+
+	for _, rs := range previous {
+		if kept[rs.Name] {
+			continue
+		}
+
+		replicasetBuckets.DeleteLabelValues(cluster.GetNamespace(), cluster.GetName(), rs.Name)
+		replicasetTargetBuckets.DeleteLabelValues(cluster.GetNamespace(), cluster.GetName(), rs.Name)
+	}
+}
+
+// deleteClusterMetrics drops the cluster wide gauges of a deleted Cluster
+func deleteClusterMetrics(namespace, clusterName string) {
+	rebalanceMisplacedBuckets.DeleteLabelValues(namespace, clusterName)
+	rebalanceProgress.DeleteLabelValues(namespace, clusterName)
+}
@@ -0,0 +1,112 @@
+package rebalance
+
+import (
+	"sort"
+)
+
+// Replicaset is the bucket state of a vshard storage replicaset
+type Replicaset struct {
+	UUID    string
+	Weight  float64
+	Buckets int
+}
+
+// Plan is the distribution of buckets implied by replicaset weights compared with the observed one
+type Plan struct {
+	// Targets is the number of buckets every replicaset should store, keyed by replicaset uuid
+	Targets map[string]int
+	// Total is the number of buckets in the cluster
+	Total int
+	// Misplaced is the number of buckets to be moved to reach the target distribution
+	Misplaced int
+}
+
+// NewPlan distributes total buckets between replicasets proportionally to their weights
+// the same way vshard does, remainders go to the replicasets with the largest fractional parts
+func NewPlan(replicasets []Replicaset, total int) Plan {
+	plan := Plan{
+		Targets: make(map[string]int),
+		Total:   total,
+	}
+
+	totalWeight := 0.0
+	for _, rs := range replicasets {
+		plan.Targets[rs.UUID] = 0
+		if rs.Weight > 0 {
+			totalWeight += rs.Weight
+		}
+	}
+
+	if totalWeight > 0 {
+		type remainder struct {
+			uuid     string
+			fraction float64
+		}
+		remainders := []remainder{}
+
+		left := total
+		for _, rs := range replicasets {
+			if rs.Weight <= 0 {
+				continue
+			}
+
+			exact := float64(total) * rs.Weight / totalWeight
+			plan.Targets[rs.UUID] = int(exact)
+			left -= int(exact)
+			remainders = append(remainders, remainder{uuid: rs.UUID, fraction: exact - float64(int(exact))})
+		}
+
+		sort.SliceStable(remainders, func(i, j int) bool {
+			if remainders[i].fraction != remainders[j].fraction {
+				return remainders[i].fraction > remainders[j].fraction
+			}
+			return remainders[i].uuid < remainders[j].uuid
+		})
+		for i := 0; left > 0 && len(remainders) > 0; i, left = i+1, left-1 {
+			plan.Targets[remainders[i%len(remainders)].uuid]++
+		}
+	}
+
+	for _, rs := range replicasets {
+		if rs.Buckets > plan.Targets[rs.UUID] {
+			plan.Misplaced += rs.Buckets - plan.Targets[rs.UUID]
+		}
+	}
+
+	return plan
+}
+
+// Progress is the percentage of buckets already stored on their target replicasets
+func (p Plan) Progress() int32 {
+	if p.Total == 0 {
+		return 100
+	}
+
+	placed := p.Total - p.Misplaced
+	if placed < 0 {
+		placed = 0
+	}
+
+	return int32(placed * 100 / p.Total)
+}
+
+// InProgress reports whether the share of misplaced buckets exceeds the disbalance threshold in percent
+func (p Plan) InProgress(threshold int32) bool {
+	return p.Misplaced*100 > int(threshold)*p.Total
+}
+
+// NextWeight moves the current weight towards the desired one by at most step, a non-positive step jumps right to it
+func NextWeight(current, desired, step int) int {
+	if step <= 0 {
+		return desired
+	}
+
+	if desired > current && desired-current > step {
+		return current + step
+	}
+	if desired < current && current-desired > step {
+		return current - step
+	}
+
+	return desired
+}
@@ -0,0 +1,92 @@
+package rebalance
+
+import (
+	"testing"
+)
+
+func TestNewPlan_DistributesByWeight(t *testing.T) {
+	plan := NewPlan([]Replicaset{
+		{UUID: "a", Weight: 1, Buckets: 30000},
+		{UUID: "b", Weight: 1, Buckets: 0},
+		{UUID: "c", Weight: 1, Buckets: 0},
+	}, 30000)
+
+	for _, uuid := range []string{"a", "b", "c"} {
+		if plan.Targets[uuid] != 10000 {
+			t.Fatalf("unexpected target of %s: %d", uuid, plan.Targets[uuid])
+		}
+	}
+	if plan.Misplaced != 20000 {
+		t.Fatalf("unexpected misplaced buckets: %d", plan.Misplaced)
+	}
+	if plan.Progress() != 33 {
+		t.Fatalf("unexpected progress: %d", plan.Progress())
+	}
+	if !plan.InProgress(1) {
+		t.Fatal("plan must be in progress")
+	}
+}
+
+func TestNewPlan_AssignsRemainders(t *testing.T) {
+	plan := NewPlan([]Replicaset{
+		{UUID: "a", Weight: 1, Buckets: 34},
+		{UUID: "b", Weight: 1, Buckets: 33},
+		{UUID: "c", Weight: 1, Buckets: 33},
+	}, 100)
+
+	sum := 0
+	for _, target := range plan.Targets {
+		sum += target
+	}
+	if sum != 100 {
+		t.Fatalf("targets must sum up to total, got %d", sum)
+	}
+	if plan.Misplaced != 0 || plan.Progress() != 100 || plan.InProgress(0) {
+		t.Fatalf("balanced plan reported as in progress: %+v", plan)
+	}
+}
+
+func TestNewPlan_DrainedReplicaset(t *testing.T) {
+	plan := NewPlan([]Replicaset{
+		{UUID: "a", Weight: 100, Buckets: 500},
+		{UUID: "b", Weight: 0, Buckets: 500},
+	}, 1000)
+
+	if plan.Targets["a"] != 1000 || plan.Targets["b"] != 0 {
+		t.Fatalf("unexpected targets %v", plan.Targets)
+	}
+	if plan.Misplaced != 500 || plan.Progress() != 50 {
+		t.Fatalf("unexpected plan %+v", plan)
+	}
+	if !plan.InProgress(1) {
+		t.Fatal("plan must be in progress")
+	}
+}
+
+func TestNewPlan_NoBuckets(t *testing.T) {
+	plan := NewPlan(nil, 0)
+	if plan.Progress() != 100 || plan.InProgress(1) {
+		t.Fatalf("empty plan must be complete: %+v", plan)
+	}
+}
+
+func TestNextWeight(t *testing.T) {
+	cases := []struct {
+		current, desired, step, expected int
+	}{
+		{100, 0, 0, 0},
+		{100, 0, 50, 50},
+		{50, 0, 50, 0},
+		{100, 0, 30, 70},
+		{10, 0, 30, 0},
+		{0, 100, 40, 40},
+		{80, 100, 40, 100},
+		{100, 100, 50, 100},
+	}
+
+	for i, c := range cases {
+		if got := NextWeight(c.current, c.desired, c.step); got != c.expected {
+			t.Fatalf("%d: expected %d, got %d", i, c.expected, got)
+		}
+	}
+}
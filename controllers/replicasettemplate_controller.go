@@ -30,11 +30,20 @@ package controllers
 
 import (
 	"context"
+	"sort"
 
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	tarantooliov1alpha1 "github.com/tarantool/tarantool-operator/api/v1alpha1"
 )
@@ -49,26 +58,103 @@ type ReplicasetTemplateReconciler struct {
 //+kubebuilder:rbac:groups=tarantool.io,resources=replicasettemplates/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=tarantool.io,resources=replicasettemplates/finalizers,verbs=update
 
-// Reconcile is part of the main kubernetes reconciliation loop which aims to
-// move the current state of the cluster closer to the desired state.
-// TODO(user): Modify the Reconcile function to compare the state specified by
-// the ReplicasetTemplate object against the actual cluster state, and then
-// perform operations to make the cluster state reflect the state specified by
-// the user.
+// Reconcile validates the ReplicasetTemplate and records which Roles select it
 //
 // For more details, check Reconcile and its Result here:
 // - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.10.0/pkg/reconcile
 func (r *ReplicasetTemplateReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
-	_ = log.FromContext(ctx)
+	reqLogger := log.FromContext(ctx)
+	reqLogger.Info("Reconciling ReplicasetTemplate")
 
-	// your logic here
+	template := &tarantooliov1alpha1.ReplicasetTemplate{}
+	if err := r.Get(context.TODO(), req.NamespacedName, template); err != nil {
+		if errors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, err
+	}
+
+	status := template.Status.DeepCopy()
+	generation := template.GetGeneration()
+	status.ObservedGeneration = generation
+
+	errs := template.ValidateTemplate()
+	status.Valid = len(errs) == 0
+
+	validMessage := ""
+	if !status.Valid {
+		validMessage = errs.ToAggregate().Error()
+		reqLogger.Info("template is invalid", "errors", validMessage)
+	}
+	setCondition(&status.Conditions, generation, tarantooliov1alpha1.ReplicasetTemplateValid, status.Valid,
+		"TemplateValid", "TemplateInvalid", validMessage)
+
+	usedBy, err := r.getSelectingRoles(template)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	status.UsedBy = usedBy
+
+	if equality.Semantic.DeepEqual(status, &template.Status) {
+		return ctrl.Result{}, nil
+	}
+
+	template.Status = *status
+	if err := r.Status().Update(context.TODO(), template); err != nil {
+		return ctrl.Result{}, err
+	}
 
 	return ctrl.Result{}, nil
 }
 
+// getSelectingRoles returns sorted names of the Roles whose selector matches the template
+func (r *ReplicasetTemplateReconciler) getSelectingRoles(template *tarantooliov1alpha1.ReplicasetTemplate) ([]string, error) {
+	roleList := &tarantooliov1alpha1.RoleList{}
+	if err := r.List(context.TODO(), roleList, &client.ListOptions{Namespace: template.GetNamespace()}); err != nil {
+		return nil, err
+	}
+
+	var usedBy []string
+	for _, role := range roleList.Items {
+		if role.Spec.Selector == nil {
+			continue
+		}
+
+		selector, err := metav1.LabelSelectorAsSelector(role.Spec.Selector)
+		if err != nil {
+			continue
+		}
+
+		if selector.Matches(labels.Set(template.GetLabels())) {
+			usedBy = append(usedBy, role.GetName())
+		}
+	}
+	sort.Strings(usedBy)
+
+	return usedBy, nil
+}
+
 // SetupWithManager sets up the controller with the Manager.
 func (r *ReplicasetTemplateReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&tarantooliov1alpha1.ReplicasetTemplate{}).
+		Watches(&source.Kind{Type: &tarantooliov1alpha1.Role{}}, handler.EnqueueRequestsFromMapFunc(func(a client.Object) []reconcile.Request {
+			templateList := &tarantooliov1alpha1.ReplicasetTemplateList{}
+			if err := r.Client.List(context.TODO(), templateList, &client.ListOptions{Namespace: a.GetNamespace()}); err != nil {
+				mgr.GetLogger().Error(err, "failed to list templates for Role", "Role.Name", a.GetName())
+				return nil
+			}
+
+			res := []reconcile.Request{}
+			for _, template := range templateList.Items {
+				res = append(res, reconcile.Request{
+					NamespacedName: types.NamespacedName{
+						Name:      template.GetName(),
+						Namespace: template.GetNamespace(),
+					},
+				})
+			}
+			return res
+		})).
 		Complete(r)
 }
@@ -0,0 +1,111 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	helpers "github.com/tarantool/tarantool-operator/test/helpers"
+	"k8s.io/apimachinery/pkg/api/meta"
+
+	tarantooliov1alpha1 "github.com/tarantool/tarantool-operator/api/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ = Describe("replicasettemplate_controller unit testing", func() {
+	var (
+		namespace = "default"
+		ctx       = context.TODO()
+
+		roleName       = "" // setup for every spec in hook
+		rsTemplateName = ""
+
+		clusterId            = "t"
+		defaultRolesToAssign = "[\"A\",\"B\"]"
+	)
+
+	BeforeEach(func() {
+		roleName = fmt.Sprintf("test-role-%s", RandStringRunes(4))
+		rsTemplateName = fmt.Sprintf("test-rs-%s", RandStringRunes(4))
+
+		By("create new Role " + roleName)
+		role := helpers.NewRole(helpers.RoleParams{
+			Name:           roleName,
+			Namespace:      namespace,
+			RolesToAssign:  defaultRolesToAssign,
+			RsNum:          int32(0),
+			RsTemplateName: rsTemplateName,
+			ClusterId:      clusterId,
+		})
+		Expect(k8sClient.Create(ctx, &role)).NotTo(HaveOccurred(), "failed to create Role")
+
+		By("create new ReplicasetTemplate " + rsTemplateName)
+		rsTemplate := helpers.NewReplicasetTemplate(helpers.ReplicasetTemplateParams{
+			Name:          rsTemplateName,
+			Namespace:     namespace,
+			RoleName:      roleName,
+			RolesToAssign: defaultRolesToAssign,
+		})
+		Expect(k8sClient.Create(ctx, &rsTemplate)).NotTo(HaveOccurred(), "failed to create ReplicasetTemplate")
+	})
+
+	AfterEach(func() {
+		By("remove role object " + roleName)
+		role := &tarantooliov1alpha1.Role{}
+		Expect(
+			k8sClient.Get(ctx, client.ObjectKey{Name: roleName, Namespace: namespace}, role),
+		).NotTo(HaveOccurred(), "failed to get Role")
+		Expect(k8sClient.Delete(ctx, role)).NotTo(HaveOccurred(), "failed to delete Role")
+
+		By("remove ReplicasetTemplate object " + rsTemplateName)
+		rsTemplate := &tarantooliov1alpha1.ReplicasetTemplate{}
+		Expect(
+			k8sClient.Get(ctx, client.ObjectKey{Name: rsTemplateName, Namespace: namespace}, rsTemplate),
+		).NotTo(HaveOccurred(), "failed to get ReplicasetTemplate")
+		Expect(k8sClient.Delete(ctx, rsTemplate)).NotTo(HaveOccurred(), "failed to delete ReplicasetTemplate")
+	})
+
+	Describe("replicasettemplate_controller should validate the template", func() {
+		It("marks a valid template and lists the Roles selecting it", func() {
+			rsTemplate := &tarantooliov1alpha1.ReplicasetTemplate{}
+			Eventually(
+				func() bool {
+					if k8sClient.Get(ctx, client.ObjectKey{Name: rsTemplateName, Namespace: namespace}, rsTemplate) != nil {
+						return false
+					}
+					return meta.IsStatusConditionTrue(rsTemplate.Status.Conditions, tarantooliov1alpha1.ReplicasetTemplateValid)
+				},
+				time.Second*10, time.Millisecond*500,
+			).Should(BeTrue())
+
+			Expect(rsTemplate.Status.Valid).To(BeTrue())
+			Expect(rsTemplate.Status.UsedBy).To(ConsistOf(roleName))
+		})
+
+		It("reports an unparsable rolesToAssign annotation", func() {
+			rsTemplate := &tarantooliov1alpha1.ReplicasetTemplate{}
+			Expect(
+				k8sClient.Get(ctx, client.ObjectKey{Name: rsTemplateName, Namespace: namespace}, rsTemplate),
+			).NotTo(HaveOccurred(), "failed to get ReplicasetTemplate")
+
+			rsTemplate.ObjectMeta.Annotations["tarantool.io/rolesToAssign"] = "[\"A\","
+			Expect(
+				k8sClient.Update(ctx, rsTemplate),
+			).NotTo(HaveOccurred(), "failed to update ReplicasetTemplate")
+
+			Eventually(
+				func() bool {
+					if k8sClient.Get(ctx, client.ObjectKey{Name: rsTemplateName, Namespace: namespace}, rsTemplate) != nil {
+						return false
+					}
+					return meta.IsStatusConditionFalse(rsTemplate.Status.Conditions, tarantooliov1alpha1.ReplicasetTemplateValid)
+				},
+				time.Second*10, time.Millisecond*500,
+			).Should(BeTrue())
+
+			Expect(rsTemplate.Status.Valid).To(BeFalse())
+		})
+	})
+})
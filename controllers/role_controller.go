@@ -31,6 +31,9 @@ package controllers
 import (
 	"context"
 	"fmt"
+	"strconv"
+	"strings"
+	"time"
 
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
@@ -48,8 +51,13 @@ import (
 
 	"github.com/google/uuid"
 	tarantooliov1alpha1 "github.com/tarantool/tarantool-operator/api/v1alpha1"
+	"github.com/tarantool/tarantool-operator/controllers/tarantool"
 )
 
+// replicasetGenerationPrefix keeps on the Role the incarnation of every replicaset removed by a downscale,
+// so a replicaset created again with the same name gets a uuid Cartridge has not seen yet
+const replicasetGenerationPrefix = "tarantool.io/replicaset-generation-"
+
 // RoleReconciler reconciles a Role object
 type RoleReconciler struct {
 	client.Client
@@ -59,6 +67,8 @@ type RoleReconciler struct {
 //+kubebuilder:rbac:groups=tarantool.io,resources=roles,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=tarantool.io,resources=roles/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=tarantool.io,resources=roles/finalizers,verbs=update
+//+kubebuilder:rbac:groups="",resources=persistentvolumeclaims,verbs=get;list;watch;delete
+//+kubebuilder:rbac:groups="",resources=pods,verbs=get;list;watch;update;patch
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -81,11 +91,24 @@ func (r *RoleReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.
 		}
 		return ctrl.Result{}, err
 	}
+	role.Default()
 
 	if len(role.GetOwnerReferences()) == 0 {
 		return ctrl.Result{}, fmt.Errorf("Orphan role %s", role.GetName())
 	}
 
+	cluster, err := r.getOwnerCluster(role)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	var template *tarantooliov1alpha1.ReplicasetTemplate
+	defer func() {
+		if err := r.updateRoleStatus(role, template); err != nil {
+			reqLogger.Error(err, "failed to update role status")
+		}
+	}()
+
 	templateSelector, err := metav1.LabelSelectorAsSelector(role.Spec.Selector)
 	if err != nil {
 		return ctrl.Result{}, err
@@ -122,17 +145,59 @@ func (r *RoleReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.
 				return ctrl.Result{}, err
 			}
 
-			stsAnnotations := sts.GetAnnotations()
-			if stsAnnotations["tarantool.io/scheduledDelete"] == "1" {
-				reqLogger.Info("statefulset is ready for deletion")
+			if !tarantool.IsDraining(sts) {
+				reqLogger.Info("marking replicaset as draining before removal", "StatefulSet.Name", sts.GetName())
+				tarantool.MarkDraining(sts)
+				if err := r.Update(context.TODO(), sts); err != nil {
+					return ctrl.Result{}, err
+				}
+				return ctrl.Result{Requeue: true}, nil
+			}
+
+			if !tarantool.IsExpelled(sts) {
+				reqLogger.Info("waiting for replicaset instances to be expelled", "StatefulSet.Name", sts.GetName())
+				continue
 			}
 
-			// if err := r.client.Delete(context.TODO(), sts); err != nil {
-			// 	return reconcile.Result{}, err
-			// }
+			if err := r.removeReplicaset(ctx, role, sts, i-1); err != nil {
+				return ctrl.Result{}, err
+			}
+			return ctrl.Result{Requeue: true}, nil
 		}
 	}
 
+	// the Role may be scaled up again while a replicaset is drained: the drain is stopped unless its instances
+	// are already being expelled, then the replicaset is removed and created again under a new uuid
+	for i := range stsList.Items {
+		sts := &stsList.Items[i]
+		if !tarantool.IsDraining(sts) {
+			continue
+		}
+
+		ordinal, err := strconv.Atoi(strings.TrimPrefix(sts.GetName(), role.GetName()+"-"))
+		if err != nil || ordinal >= int(*role.Spec.NumReplicasets) {
+			continue
+		}
+
+		if tarantool.IsExpelled(sts) {
+			if err := r.removeReplicaset(ctx, role, sts, ordinal); err != nil {
+				return ctrl.Result{}, err
+			}
+			return ctrl.Result{Requeue: true}, nil
+		}
+		if tarantool.IsScheduledDelete(sts) {
+			reqLogger.Info("waiting for replicaset instances to be expelled before it is created again", "StatefulSet.Name", sts.GetName())
+			continue
+		}
+
+		reqLogger.Info("role was scaled up again, stopping the drain", "StatefulSet.Name", sts.GetName())
+		tarantool.UnmarkDraining(sts, replicasetWeight(role))
+		if err := r.Update(context.TODO(), sts); err != nil {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{Requeue: true}, nil
+	}
+
 	templateList := &tarantooliov1alpha1.ReplicasetTemplateList{}
 	if err := r.List(context.TODO(), templateList, &client.ListOptions{LabelSelector: templateSelector, Namespace: req.NamespacedName.Namespace}); err != nil {
 		return ctrl.Result{}, err
@@ -142,7 +207,13 @@ func (r *RoleReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.
 		return ctrl.Result{}, fmt.Errorf("no template")
 	}
 
-	template := templateList.Items[0]
+	if errs := templateList.Items[0].ValidateTemplate(); len(errs) > 0 {
+		reqLogger.Info("selected template is invalid, waiting for it to be fixed", "ReplicasetTemplate.Name", templateList.Items[0].GetName(), "errors", errs.ToAggregate().Error())
+		return ctrl.Result{}, nil
+	}
+
+	template = &templateList.Items[0]
+	template.Default()
 
 	if len(stsList.Items) < int(*role.Spec.NumReplicasets) {
 		for i := 0; i < int(*role.Spec.NumReplicasets); i++ {
@@ -151,7 +222,10 @@ func (r *RoleReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.
 			sts.Namespace = req.Namespace
 
 			if err := r.Get(context.TODO(), types.NamespacedName{Namespace: sts.Namespace, Name: sts.Name}, sts); err != nil {
-				sts = CreateStatefulSetFromTemplate(ctx, i, fmt.Sprintf("%s-%d", role.Name, i), role, &template)
+				sts = CreateStatefulSetFromTemplate(ctx, i, fmt.Sprintf("%s-%d", role.Name, i), role, template)
+				if cluster != nil {
+					SetBucketCount(&sts.Spec.Template.Spec, cluster.Spec.BucketCount)
+				}
 				if err := controllerutil.SetControllerReference(role, sts, r.Scheme); err != nil {
 					return ctrl.Result{}, err
 				}
@@ -162,13 +236,14 @@ func (r *RoleReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.
 		}
 	}
 
+	scaling := false
 	for _, sts := range stsList.Items {
-		if template.Spec.Replicas != sts.Spec.Replicas {
-			reqLogger.Info("Updating replicas count")
-			sts.Spec.Replicas = template.Spec.Replicas
-			if err := r.Update(context.TODO(), &sts); err != nil {
+		if !tarantool.IsDraining(&sts) {
+			done, err := r.scaleReplicas(ctx, &sts, *template.Spec.Replicas)
+			if err != nil {
 				return ctrl.Result{}, err
 			}
+			scaling = scaling || !done
 		}
 
 		if template.Spec.Template.Spec.Containers[0].Image != sts.Spec.Template.Spec.Containers[0].Image {
@@ -180,11 +255,25 @@ func (r *RoleReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.
 		}
 
 		sts.Spec.Template.Spec.Containers[0].Env = template.Spec.Template.Spec.Containers[0].Env
+		if cluster != nil {
+			SetBucketCount(&sts.Spec.Template.Spec, cluster.Spec.BucketCount)
+		}
 		reqLogger.Info("Env variables", "vars", sts.Spec.Template.Spec.Containers[0].Env)
 		if err := r.Update(context.TODO(), &sts); err != nil {
 			return ctrl.Result{}, err
 		}
 
+		if tarantool.GetPreferredZone(&sts) != role.Spec.PreferredZone {
+			reqLogger.Info("Updating replicaset preferred zone",
+				"from", tarantool.GetPreferredZone(&sts),
+				"to", role.Spec.PreferredZone)
+
+			tarantool.SetPreferredZone(&sts, role.Spec.PreferredZone)
+			if err := r.Update(context.TODO(), &sts); err != nil {
+				return ctrl.Result{}, err
+			}
+		}
+
 		if templateRolesToAssign, ok := template.ObjectMeta.Annotations["tarantool.io/rolesToAssign"]; ok {
 			// check rolesToAssign from annotations
 			if templateRolesToAssign != sts.ObjectMeta.Annotations["tarantool.io/rolesToAssign"] {
@@ -217,6 +306,10 @@ func (r *RoleReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.
 		}
 	}
 
+	if scaling {
+		return ctrl.Result{RequeueAfter: time.Duration(5 * time.Second)}, nil
+	}
+
 	return ctrl.Result{}, nil
 }
 
@@ -249,6 +342,112 @@ func (r *RoleReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		Complete(r)
 }
 
+// getOwnerCluster returns the Cluster controlling the Role or nil if the Role is owned by something else
+func (r *RoleReconciler) getOwnerCluster(role *tarantooliov1alpha1.Role) (*tarantooliov1alpha1.Cluster, error) {
+	ownerRef := metav1.GetControllerOf(role)
+	if ownerRef == nil || ownerRef.Kind != "Cluster" {
+		return nil, nil
+	}
+
+	cluster := &tarantooliov1alpha1.Cluster{}
+	if err := r.Get(context.TODO(), types.NamespacedName{Namespace: role.GetNamespace(), Name: ownerRef.Name}, cluster); err != nil {
+		if errors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	cluster.Default()
+
+	return cluster, nil
+}
+
+// SetBucketCount passes the vshard bucket count to the tarantool container as TARANTOOL_BUCKET_COUNT
+func SetBucketCount(podSpec *corev1.PodSpec, bucketCount int32) {
+	container := &podSpec.Containers[0]
+	value := strconv.Itoa(int(bucketCount))
+
+	env := make([]corev1.EnvVar, 0, len(container.Env)+1)
+	found := false
+	for _, v := range container.Env {
+		if v.Name == "TARANTOOL_BUCKET_COUNT" {
+			v.Value = value
+			v.ValueFrom = nil
+			found = true
+		}
+		env = append(env, v)
+	}
+	if !found {
+		env = append(env, corev1.EnvVar{Name: "TARANTOOL_BUCKET_COUNT", Value: value})
+	}
+
+	container.Env = env
+}
+
+// deleteVolumeClaims removes the PVCs created from the StatefulSet volume claim templates for the pods starting with the given ordinal
+func (r *RoleReconciler) deleteVolumeClaims(ctx context.Context, sts *appsv1.StatefulSet, from int) error {
+	reqLogger := log.FromContext(ctx)
+
+	for _, claimTemplate := range sts.Spec.VolumeClaimTemplates {
+		for i := from; i < int(*sts.Spec.Replicas); i++ {
+			pvc := &corev1.PersistentVolumeClaim{}
+			pvc.Name = fmt.Sprintf("%s-%s-%d", claimTemplate.GetName(), sts.GetName(), i)
+			pvc.Namespace = sts.GetNamespace()
+
+			reqLogger.Info("removing volume claim", "PersistentVolumeClaim.Name", pvc.GetName())
+			if err := r.Delete(context.TODO(), pvc); err != nil && !errors.IsNotFound(err) {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// replicasetGeneration returns the incarnation of the replicaset with the ordinal recorded on the Role
+func replicasetGeneration(role *tarantooliov1alpha1.Role, ordinal int) int {
+	generation, _ := strconv.Atoi(role.GetAnnotations()[fmt.Sprintf("%s%d", replicasetGenerationPrefix, ordinal)])
+	return generation
+}
+
+func setReplicasetGeneration(role *tarantooliov1alpha1.Role, ordinal int, generation int) {
+	annotations := role.GetAnnotations()
+	if annotations == nil {
+		annotations = make(map[string]string)
+	}
+	annotations[fmt.Sprintf("%s%d", replicasetGenerationPrefix, ordinal)] = strconv.Itoa(generation)
+	role.SetAnnotations(annotations)
+}
+
+// removeReplicaset deletes the StatefulSet of an expelled replicaset with its volume claims
+func (r *RoleReconciler) removeReplicaset(ctx context.Context, role *tarantooliov1alpha1.Role, sts *appsv1.StatefulSet, ordinal int) error {
+	if err := r.deleteVolumeClaims(ctx, sts, 0); err != nil {
+		return err
+	}
+
+	// the replicaset uuid is tombstoned by Cartridge, a replicaset created again with this name needs a new one
+	patch := client.MergeFrom(role.DeepCopy())
+	setReplicasetGeneration(role, ordinal, tarantool.GetGeneration(sts)+1)
+	if err := r.Patch(context.TODO(), role, patch); err != nil {
+		return err
+	}
+
+	log.FromContext(ctx).Info("statefulset is ready for deletion", "StatefulSet.Name", sts.GetName())
+	if err := r.Delete(context.TODO(), sts); err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+
+	return nil
+}
+
+// replicasetWeight returns the weight the replicasets of the Role are created with
+func replicasetWeight(role *tarantooliov1alpha1.Role) string {
+	if weight, ok := role.GetAnnotations()["tarantool.io/replicaset-weight"]; ok {
+		return weight
+	}
+
+	return "100"
+}
+
 // CreateStatefulSetFromTemplate .
 func CreateStatefulSetFromTemplate(ctx context.Context, replicasetNumber int, name string, role *tarantooliov1alpha1.Role, rs *tarantooliov1alpha1.ReplicasetTemplate) *appsv1.StatefulSet {
 	reqLogger := log.FromContext(ctx)
@@ -276,7 +475,12 @@ func CreateStatefulSetFromTemplate(ctx context.Context, replicasetNumber int, na
 	}
 
 	sts.Spec.ServiceName = role.GetAnnotations()["tarantool.io/cluster-id"]
-	replicasetUUID := uuid.NewSHA1(space, []byte(sts.GetName()))
+	generation := replicasetGeneration(role, replicasetNumber)
+	seed := sts.GetName()
+	if generation > 0 {
+		seed = fmt.Sprintf("%s/%d", seed, generation)
+	}
+	replicasetUUID := uuid.NewSHA1(space, []byte(seed))
 	sts.ObjectMeta.Labels["tarantool.io/replicaset-uuid"] = replicasetUUID.String()
 	sts.ObjectMeta.Labels["tarantool.io/vshardGroupName"] = role.GetLabels()["tarantool.io/role"]
 
@@ -285,7 +489,10 @@ func CreateStatefulSetFromTemplate(ctx context.Context, replicasetNumber int, na
 	}
 
 	sts.ObjectMeta.Annotations["tarantool.io/isBootstrapped"] = "0"
-	sts.ObjectMeta.Annotations["tarantool.io/replicaset-weight"] = "100"
+	tarantool.SetGeneration(sts, generation)
+	sts.ObjectMeta.Annotations["tarantool.io/replicaset-weight"] = replicasetWeight(role)
+
+	tarantool.SetPreferredZone(sts, role.Spec.PreferredZone)
 
 	sts.Spec.Template.Labels["tarantool.io/replicaset-uuid"] = replicasetUUID.String()
 	sts.Spec.Template.Labels["tarantool.io/vshardGroupName"] = role.GetLabels()["tarantool.io/role"]
@@ -12,6 +12,8 @@ import (
 
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	tarantooliov1alpha1 "github.com/tarantool/tarantool-operator/api/v1alpha1"
@@ -291,4 +293,389 @@ var _ = Describe("role_controller unit testing", func() {
 			})
 		})
 	})
+
+	Describe("role_controller should drain and remove replicasets on Role downscale", func() {
+		setNumReplicasets := func(num int32) {
+			role := &tarantooliov1alpha1.Role{}
+			Expect(
+				k8sClient.Get(ctx, client.ObjectKey{Name: roleName, Namespace: namespace}, role),
+			).NotTo(HaveOccurred(), "failed to get Role")
+
+			role.Spec.NumReplicasets = &num
+			Expect(k8sClient.Update(ctx, role)).NotTo(HaveOccurred(), "failed to update Role")
+		}
+
+		It("delete the sts only after its instances are expelled", func() {
+			removedName := fmt.Sprintf("%s-%d", roleName, 1)
+
+			By("scale the role up to 2 replicasets")
+			setNumReplicasets(2)
+			sts := &appsv1.StatefulSet{}
+			Eventually(
+				func() error {
+					return k8sClient.Get(ctx, client.ObjectKey{Name: removedName, Namespace: namespace}, sts)
+				},
+				time.Second*10, time.Millisecond*500,
+			).Should(Succeed())
+			Expect(sts.GetAnnotations()["tarantool.io/draining"]).To(BeEmpty())
+			expelledUUID := sts.GetLabels()["tarantool.io/replicaset-uuid"]
+
+			By("scale the role down to 1 replicaset")
+			setNumReplicasets(1)
+			Eventually(
+				func() map[string]string {
+					if k8sClient.Get(ctx, client.ObjectKey{Name: removedName, Namespace: namespace}, sts) != nil {
+						return nil
+					}
+					return sts.GetAnnotations()
+				},
+				time.Second*10, time.Millisecond*500,
+			).Should(And(
+				HaveKeyWithValue("tarantool.io/draining", "1"),
+				HaveKeyWithValue("tarantool.io/replicaset-weight", "0"),
+			))
+			Consistently(
+				func() error {
+					return k8sClient.Get(ctx, client.ObjectKey{Name: removedName, Namespace: namespace}, &appsv1.StatefulSet{})
+				},
+				time.Second*2, time.Millisecond*500,
+			).Should(Succeed())
+
+			By("mark the replicaset instances expelled")
+			sts.Annotations["tarantool.io/instancesExpelled"] = "1"
+			Expect(k8sClient.Update(ctx, sts)).NotTo(HaveOccurred(), "failed to update StatefulSet")
+
+			Eventually(
+				func() bool {
+					err := k8sClient.Get(ctx, client.ObjectKey{Name: removedName, Namespace: namespace}, &appsv1.StatefulSet{})
+					return errors.IsNotFound(err)
+				},
+				time.Second*10, time.Millisecond*500,
+			).Should(BeTrue())
+
+			By("scale the role up again")
+			setNumReplicasets(2)
+			Eventually(
+				func() string {
+					if k8sClient.Get(ctx, client.ObjectKey{Name: removedName, Namespace: namespace}, sts) != nil {
+						return ""
+					}
+					return sts.GetLabels()["tarantool.io/replicaset-uuid"]
+				},
+				time.Second*10, time.Millisecond*500,
+			).ShouldNot(Or(BeEmpty(), Equal(expelledUUID)))
+		})
+
+		It("stop the drain when the role is scaled up again before the instances are expelled", func() {
+			drainedName := fmt.Sprintf("%s-%d", roleName, 1)
+
+			By("scale the role up to 2 replicasets")
+			setNumReplicasets(2)
+			sts := &appsv1.StatefulSet{}
+			Eventually(
+				func() error {
+					return k8sClient.Get(ctx, client.ObjectKey{Name: drainedName, Namespace: namespace}, sts)
+				},
+				time.Second*10, time.Millisecond*500,
+			).Should(Succeed())
+			weight := sts.GetAnnotations()["tarantool.io/replicaset-weight"]
+			drainedUUID := sts.GetLabels()["tarantool.io/replicaset-uuid"]
+
+			By("scale the role down to 1 replicaset")
+			setNumReplicasets(1)
+			Eventually(
+				func() map[string]string {
+					if k8sClient.Get(ctx, client.ObjectKey{Name: drainedName, Namespace: namespace}, sts) != nil {
+						return nil
+					}
+					return sts.GetAnnotations()
+				},
+				time.Second*10, time.Millisecond*500,
+			).Should(HaveKeyWithValue("tarantool.io/draining", "1"))
+
+			By("scale the role up again")
+			setNumReplicasets(2)
+			Eventually(
+				func() map[string]string {
+					if k8sClient.Get(ctx, client.ObjectKey{Name: drainedName, Namespace: namespace}, sts) != nil {
+						return nil
+					}
+					return sts.GetAnnotations()
+				},
+				time.Second*10, time.Millisecond*500,
+			).Should(And(
+				Not(HaveKey("tarantool.io/draining")),
+				HaveKeyWithValue("tarantool.io/replicaset-weight", weight),
+			))
+			Expect(sts.GetLabels()["tarantool.io/replicaset-uuid"]).To(Equal(drainedUUID))
+		})
+
+		It("keep a replicaset created with weight 0", func() {
+			role := &tarantooliov1alpha1.Role{}
+			Expect(
+				k8sClient.Get(ctx, client.ObjectKey{Name: roleName, Namespace: namespace}, role),
+			).NotTo(HaveOccurred(), "failed to get Role")
+
+			role.Annotations["tarantool.io/replicaset-weight"] = "0"
+			Expect(k8sClient.Update(ctx, role)).NotTo(HaveOccurred(), "failed to update Role")
+
+			By("scale the role up to 2 replicasets")
+			setNumReplicasets(2)
+			addedName := fmt.Sprintf("%s-%d", roleName, 1)
+			sts := &appsv1.StatefulSet{}
+			Eventually(
+				func() error {
+					return k8sClient.Get(ctx, client.ObjectKey{Name: addedName, Namespace: namespace}, sts)
+				},
+				time.Second*10, time.Millisecond*500,
+			).Should(Succeed())
+			Expect(sts.GetAnnotations()["tarantool.io/replicaset-weight"]).To(Equal("0"))
+
+			Consistently(
+				func() string {
+					if k8sClient.Get(ctx, client.ObjectKey{Name: addedName, Namespace: namespace}, sts) != nil {
+						return "missing"
+					}
+					return sts.GetAnnotations()["tarantool.io/draining"]
+				},
+				time.Second*2, time.Millisecond*500,
+			).Should(BeEmpty())
+		})
+	})
+
+	Describe("role_controller should report rollout progress in status", func() {
+		It("set selected template and replicaset counts", func() {
+			role := &tarantooliov1alpha1.Role{}
+			Eventually(
+				func() bool {
+					if k8sClient.Get(ctx, client.ObjectKey{Name: roleName, Namespace: namespace}, role) != nil {
+						return false
+					}
+
+					return role.Status.Template == rsTemplateName &&
+						role.Status.DesiredReplicasets == 1 &&
+						role.Status.Replicasets == 1
+				},
+				time.Second*10, time.Millisecond*500,
+			).Should(BeTrue())
+
+			Expect(role.Status.StatefulSets).To(HaveLen(1))
+			Expect(role.Status.StatefulSets[0].Name).To(Equal(stsName))
+		})
+
+		// setStsStatus changes the observed state of the replicaset as the StatefulSet controller would
+		setStsStatus := func(update func(status *appsv1.StatefulSetStatus)) {
+			Eventually(
+				func() error {
+					sts := &appsv1.StatefulSet{}
+					if err := k8sClient.Get(ctx, client.ObjectKey{Name: stsName, Namespace: namespace}, sts); err != nil {
+						return err
+					}
+					update(&sts.Status)
+					return k8sClient.Status().Update(ctx, sts)
+				},
+				time.Second*10, time.Millisecond*500,
+			).Should(Succeed())
+		}
+		getRole := func() *tarantooliov1alpha1.Role {
+			role := &tarantooliov1alpha1.Role{}
+			if k8sClient.Get(ctx, client.ObjectKey{Name: roleName, Namespace: namespace}, role) != nil {
+				return nil
+			}
+			return role
+		}
+
+		It("count the replicasets with all pods ready", func() {
+			setStsStatus(func(status *appsv1.StatefulSetStatus) {
+				status.Replicas = 1
+				status.ReadyReplicas = 0
+			})
+			Eventually(
+				func() bool {
+					role := getRole()
+					return role != nil && role.Status.Replicasets == 1 && role.Status.ReadyReplicasets == 0 &&
+						meta.IsStatusConditionFalse(role.Status.Conditions, tarantooliov1alpha1.RoleReady)
+				},
+				time.Second*10, time.Millisecond*500,
+			).Should(BeTrue())
+
+			setStsStatus(func(status *appsv1.StatefulSetStatus) {
+				status.Replicas = 1
+				status.ReadyReplicas = 1
+			})
+			Eventually(
+				func() bool {
+					role := getRole()
+					return role != nil && role.Status.ReadyReplicasets == 1 &&
+						meta.IsStatusConditionTrue(role.Status.Conditions, tarantooliov1alpha1.RoleReady)
+				},
+				time.Second*10, time.Millisecond*500,
+			).Should(BeTrue())
+			Expect(getRole().Status.StatefulSets[0].ReadyReplicas).To(Equal(int32(1)))
+		})
+
+		It("count the replicasets running an outdated revision and report the image they run", func() {
+			setStsStatus(func(status *appsv1.StatefulSetStatus) {
+				status.Replicas = 1
+				status.CurrentRevision = stsName + "-a"
+				status.UpdateRevision = stsName + "-b"
+			})
+			Eventually(
+				func() string {
+					role := getRole()
+					if role == nil {
+						return ""
+					}
+					condition := meta.FindStatusCondition(role.Status.Conditions, tarantooliov1alpha1.RoleProgressing)
+					if condition == nil || condition.Status != metav1.ConditionTrue {
+						return ""
+					}
+					return condition.Message
+				},
+				time.Second*10, time.Millisecond*500,
+			).Should(ContainSubstring("1 outdated"))
+
+			setStsStatus(func(status *appsv1.StatefulSetStatus) {
+				status.CurrentRevision = status.UpdateRevision
+			})
+			Eventually(
+				func() bool {
+					role := getRole()
+					return role != nil && meta.IsStatusConditionFalse(role.Status.Conditions, tarantooliov1alpha1.RoleProgressing)
+				},
+				time.Second*10, time.Millisecond*500,
+			).Should(BeTrue())
+
+			By("change the image of the template")
+			rsTemplate := &tarantooliov1alpha1.ReplicasetTemplate{}
+			Expect(
+				k8sClient.Get(ctx, client.ObjectKey{Name: rsTemplateName, Namespace: namespace}, rsTemplate),
+			).NotTo(HaveOccurred(), "failed to get ReplicasetTemplate")
+			rsTemplate.Spec.Template.Spec.Containers[0].Image = "tarantool/cartridge:updated"
+			Expect(k8sClient.Update(ctx, rsTemplate)).NotTo(HaveOccurred(), "failed to update ReplicasetTemplate")
+
+			Eventually(
+				func() string {
+					role := getRole()
+					if role == nil || len(role.Status.StatefulSets) == 0 {
+						return ""
+					}
+					return role.Status.StatefulSets[0].Image
+				},
+				time.Second*10, time.Millisecond*500,
+			).Should(Equal("tarantool/cartridge:updated"))
+			Expect(getRole().Status.Image).To(Equal("tarantool/cartridge:updated"))
+		})
+
+		It("list the replicasets drained before removal", func() {
+			setNumReplicasets := func(num int32) {
+				role := getRole()
+				Expect(role).NotTo(BeNil(), "failed to get Role")
+				role.Spec.NumReplicasets = &num
+				Expect(k8sClient.Update(ctx, role)).NotTo(HaveOccurred(), "failed to update Role")
+			}
+			drainedName := fmt.Sprintf("%s-%d", roleName, 1)
+
+			By("scale the role up to 2 replicasets")
+			setNumReplicasets(2)
+			Eventually(
+				func() int32 {
+					role := getRole()
+					if role == nil {
+						return 0
+					}
+					return role.Status.Replicasets
+				},
+				time.Second*10, time.Millisecond*500,
+			).Should(Equal(int32(2)))
+
+			By("scale the role down to 1 replicaset")
+			setNumReplicasets(1)
+			Eventually(
+				func() []string {
+					role := getRole()
+					if role == nil {
+						return nil
+					}
+					return role.Status.PendingDeletions
+				},
+				time.Second*10, time.Millisecond*500,
+			).Should(Equal([]string{drainedName}))
+
+			role := getRole()
+			Expect(role.Status.Replicasets).To(Equal(int32(1)))
+			Expect(role.Status.StatefulSets).To(HaveLen(2))
+			Expect(role.Status.StatefulSets[1].Name).To(Equal(drainedName))
+			Expect(role.Status.StatefulSets[1].PendingDeletion).To(BeTrue())
+			Expect(meta.IsStatusConditionTrue(role.Status.Conditions, tarantooliov1alpha1.RoleProgressing)).To(BeTrue())
+		})
+	})
+
+	Describe("role_controller should expel instances before scaling a replicaset down", func() {
+		It("shrink the sts only after the removed instance is expelled", func() {
+			setReplicas := func(replicas int32) {
+				rsTemplate := &tarantooliov1alpha1.ReplicasetTemplate{}
+				Expect(
+					k8sClient.Get(ctx, client.ObjectKey{Name: rsTemplateName, Namespace: namespace}, rsTemplate),
+				).NotTo(HaveOccurred(), "failed to get ReplicasetTemplate")
+
+				rsTemplate.Spec.Replicas = &replicas
+				Expect(
+					k8sClient.Update(ctx, rsTemplate),
+				).NotTo(HaveOccurred(), "failed to update ReplicasetTemplate")
+			}
+			stsReplicas := func() int32 {
+				sts := &appsv1.StatefulSet{}
+				if k8sClient.Get(ctx, client.ObjectKey{Name: stsName, Namespace: namespace}, sts) != nil {
+					return -1
+				}
+				return *sts.Spec.Replicas
+			}
+
+			By("scale the replicaset up to 2 instances")
+			setReplicas(2)
+			Eventually(stsReplicas, time.Second*10, time.Millisecond*500).Should(Equal(int32(2)))
+
+			for i := 0; i < 2; i++ {
+				pod := &corev1.Pod{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      fmt.Sprintf("%s-%d", stsName, i),
+						Namespace: namespace,
+						Labels:    map[string]string{"tarantool.io/instance-state": "joined"},
+					},
+					Spec: corev1.PodSpec{
+						Containers: []corev1.Container{{Name: "cartridge", Image: "cartridge"}},
+					},
+				}
+				Expect(k8sClient.Create(ctx, pod)).NotTo(HaveOccurred(), "failed to create Pod")
+			}
+
+			By("scale the replicaset down to 1 instance")
+			setReplicas(1)
+
+			removed := &corev1.Pod{}
+			Eventually(
+				func() string {
+					if k8sClient.Get(ctx, client.ObjectKey{Name: stsName + "-1", Namespace: namespace}, removed) != nil {
+						return ""
+					}
+					return removed.GetLabels()["tarantool.io/instance-state"]
+				},
+				time.Second*10, time.Millisecond*500,
+			).Should(Equal("expelling"))
+			Consistently(stsReplicas, time.Second*2, time.Millisecond*500).Should(Equal(int32(2)))
+
+			kept := &corev1.Pod{}
+			Expect(
+				k8sClient.Get(ctx, client.ObjectKey{Name: stsName + "-0", Namespace: namespace}, kept),
+			).NotTo(HaveOccurred(), "failed to get Pod")
+			Expect(kept.GetLabels()["tarantool.io/instance-state"]).To(Equal("joined"))
+
+			By("mark the instance expelled")
+			removed.Labels["tarantool.io/instance-state"] = "expelled"
+			Expect(k8sClient.Update(ctx, removed)).NotTo(HaveOccurred(), "failed to update Pod")
+
+			Eventually(stsReplicas, time.Second*10, time.Millisecond*500).Should(Equal(int32(1)))
+		})
+	})
 })
@@ -0,0 +1,98 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/tarantool/tarantool-operator/controllers/tarantool"
+)
+
+// scaleReplicas brings the number of StatefulSet replicas to the desired one and reports whether it is done.
+// Scaling up is applied at once. Scaling down takes two phases: the pods with the highest ordinals are marked
+// expelling, ClusterReconciler expels their instances from the topology, and only then the StatefulSet shrinks.
+// An expelled instance cannot rejoin, so a scale down is not canceled once any of its instances is expelled.
+func (r *RoleReconciler) scaleReplicas(ctx context.Context, sts *appsv1.StatefulSet, desired int32) (bool, error) {
+	stsLogger := log.FromContext(ctx).WithValues("StatefulSet.Name", sts.GetName())
+	current := *sts.Spec.Replicas
+
+	pods := make([]*corev1.Pod, current)
+	for i := range pods {
+		pod := &corev1.Pod{}
+		name := types.NamespacedName{
+			Namespace: sts.GetNamespace(),
+			Name:      fmt.Sprintf("%s-%d", sts.GetName(), i),
+		}
+		if err := r.Get(context.TODO(), name, pod); err != nil {
+			if errors.IsNotFound(err) {
+				continue
+			}
+			return false, err
+		}
+		pods[i] = pod
+
+		if tarantool.IsInstanceExpelled(pod) && int32(i) < desired {
+			desired = int32(i)
+		}
+	}
+
+	done := true
+	for i, pod := range pods {
+		if int32(i) < desired {
+			if pod != nil && tarantool.IsExpelling(pod) {
+				stsLogger.Info("scale down canceled, instance is kept", "Pod.Name", pod.GetName())
+				tarantool.ClearExpelling(pod)
+				if err := r.Update(context.TODO(), pod); err != nil {
+					return false, err
+				}
+			}
+			continue
+		}
+
+		if pod == nil {
+			stsLogger.Info("waiting for pod to be created before expelling it", "ordinal", i)
+			done = false
+			continue
+		}
+		if tarantool.IsInstanceExpelled(pod) {
+			continue
+		}
+		done = false
+
+		if !tarantool.IsExpelling(pod) {
+			stsLogger.Info("marking instance to be expelled before scale down", "Pod.Name", pod.GetName())
+			tarantool.MarkExpelling(pod)
+			if err := r.Update(context.TODO(), pod); err != nil {
+				return false, err
+			}
+		}
+	}
+
+	if !done {
+		stsLogger.Info("waiting for instances to be expelled", "replicas", current, "desired", desired)
+		return false, nil
+	}
+
+	if desired == current {
+		return true, nil
+	}
+
+	if desired < current {
+		if err := r.deleteVolumeClaims(ctx, sts, int(desired)); err != nil {
+			return false, err
+		}
+	}
+
+	stsLogger.Info("updating replicas count", "from", current, "to", desired)
+	sts.Spec.Replicas = &desired
+	if err := r.Update(context.TODO(), sts); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
@@ -0,0 +1,108 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	tarantooliov1alpha1 "github.com/tarantool/tarantool-operator/api/v1alpha1"
+	"github.com/tarantool/tarantool-operator/controllers/tarantool"
+)
+
+// updateRoleStatus refreshes the Role status from the StatefulSets it manages
+func (r *RoleReconciler) updateRoleStatus(role *tarantooliov1alpha1.Role, template *tarantooliov1alpha1.ReplicasetTemplate) error {
+	stsSelector, err := metav1.LabelSelectorAsSelector(&metav1.LabelSelector{MatchLabels: role.GetLabels()})
+	if err != nil {
+		return err
+	}
+
+	stsList := &appsv1.StatefulSetList{}
+	if err := r.List(context.TODO(), stsList, &client.ListOptions{LabelSelector: stsSelector, Namespace: role.GetNamespace()}); err != nil {
+		return err
+	}
+	sort.Slice(stsList.Items, func(i, j int) bool {
+		return stsList.Items[i].GetName() < stsList.Items[j].GetName()
+	})
+
+	status := role.Status.DeepCopy()
+	generation := role.GetGeneration()
+	status.ObservedGeneration = generation
+
+	status.DesiredReplicasets = 0
+	if role.Spec.NumReplicasets != nil {
+		status.DesiredReplicasets = *role.Spec.NumReplicasets
+	}
+
+	status.Template = ""
+	status.Image = ""
+	if template != nil {
+		status.Template = template.GetName()
+		if template.Spec != nil && len(template.Spec.Template.Spec.Containers) > 0 {
+			status.Image = template.Spec.Template.Spec.Containers[0].Image
+		}
+	}
+
+	status.Replicasets = 0
+	status.ReadyReplicasets = 0
+	status.StatefulSets = []tarantooliov1alpha1.RoleStatefulSetStatus{}
+	status.PendingDeletions = nil
+	outdated := 0
+
+	for _, sts := range stsList.Items {
+		stsStatus := tarantooliov1alpha1.RoleStatefulSetStatus{
+			Name:            sts.GetName(),
+			ReadyReplicas:   sts.Status.ReadyReplicas,
+			PendingDeletion: tarantool.IsDraining(&sts) || tarantool.IsScheduledDelete(&sts),
+		}
+		if sts.Spec.Replicas != nil {
+			stsStatus.Replicas = *sts.Spec.Replicas
+		}
+		if len(sts.Spec.Template.Spec.Containers) > 0 {
+			stsStatus.Image = sts.Spec.Template.Spec.Containers[0].Image
+		}
+
+		status.StatefulSets = append(status.StatefulSets, stsStatus)
+
+		if stsStatus.PendingDeletion {
+			status.PendingDeletions = append(status.PendingDeletions, sts.GetName())
+			continue
+		}
+
+		status.Replicasets++
+		if stsStatus.ReadyReplicas == stsStatus.Replicas {
+			status.ReadyReplicasets++
+		}
+		if (status.Image != "" && stsStatus.Image != status.Image) || sts.Status.CurrentRevision != sts.Status.UpdateRevision {
+			outdated++
+		}
+	}
+
+	setCondition(&status.Conditions, generation, tarantooliov1alpha1.RoleTemplateSelected, template != nil,
+		"TemplateFound", "TemplateNotFound", status.Template)
+
+	ready := status.ReadyReplicasets == status.DesiredReplicasets && len(status.PendingDeletions) == 0
+	setCondition(&status.Conditions, generation, tarantooliov1alpha1.RoleReady, ready,
+		"AllReplicasetsReady", "ReplicasetsNotReady",
+		fmt.Sprintf("%d of %d replicasets ready", status.ReadyReplicasets, status.DesiredReplicasets))
+
+	progressing := status.Replicasets != status.DesiredReplicasets || len(status.PendingDeletions) > 0 || outdated > 0
+	progressingMessage := ""
+	if progressing {
+		progressingMessage = fmt.Sprintf("%d replicasets, %d pending deletion, %d outdated",
+			status.Replicasets, len(status.PendingDeletions), outdated)
+	}
+	setCondition(&status.Conditions, generation, tarantooliov1alpha1.RoleProgressing, progressing,
+		"RolloutInProgress", "RolloutComplete", progressingMessage)
+
+	if equality.Semantic.DeepEqual(status, &role.Status) {
+		return nil
+	}
+
+	role.Status = *status
+	return r.Status().Update(context.TODO(), role)
+}
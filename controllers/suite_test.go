@@ -32,6 +32,7 @@ import (
 	"context"
 	"path/filepath"
 	"testing"
+	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -44,6 +45,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 
 	tarantooliov1alpha1 "github.com/tarantool/tarantool-operator/api/v1alpha1"
+	helpers "github.com/tarantool/tarantool-operator/test/helpers"
 	//+kubebuilder:scaffold:imports
 )
 
@@ -56,6 +58,9 @@ var (
 	testEnv   *envtest.Environment
 	ctx       context.Context
 	cancel    context.CancelFunc
+
+	// fakeCartridge serves the Cartridge admin API to ClusterReconciler instead of the cluster leader
+	fakeCartridge *helpers.FakeCartridge
 )
 
 func TestAPIs(t *testing.T) {
@@ -96,9 +101,14 @@ var _ = BeforeSuite(func() {
 	mgr, err := ctrl.NewManager(cfg, ctrl.Options{})
 	Expect(err).NotTo(HaveOccurred(), "failed to create manager")
 
+	fakeCartridge = helpers.NewFakeCartridge()
+
 	clusterReconciler := &ClusterReconciler{
-		Client: mgr.GetClient(),
-		Scheme: scheme.Scheme,
+		Client:          mgr.GetClient(),
+		Scheme:          scheme.Scheme,
+		TopologyFactory: fakeCartridge.Factory(),
+		// instances of the existing cluster restart in seconds, pause rolling updates without waiting for minutes
+		RolloutStepTimeout: time.Minute,
 	}
 	err = clusterReconciler.SetupWithManager(mgr)
 	Expect(err).ToNot(HaveOccurred())
@@ -109,6 +119,12 @@ var _ = BeforeSuite(func() {
 	}).SetupWithManager(mgr)
 	Expect(err).ToNot(HaveOccurred())
 
+	err = (&ReplicasetTemplateReconciler{
+		Client: mgr.GetClient(),
+		Scheme: scheme.Scheme,
+	}).SetupWithManager(mgr)
+	Expect(err).ToNot(HaveOccurred())
+
 	go func() {
 		err = mgr.Start(ctx)
 		Expect(err).ToNot(HaveOccurred())
@@ -119,6 +135,7 @@ var _ = BeforeSuite(func() {
 var _ = AfterSuite(func() {
 	By("tearing down the test environment")
 	cancel()
+	fakeCartridge.Close()
 	err := testEnv.Stop()
 	Expect(err).NotTo(HaveOccurred())
 })
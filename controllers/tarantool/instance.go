@@ -1,6 +1,7 @@
 package tarantool
 
 import (
+	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/selection"
@@ -9,6 +10,7 @@ import (
 const (
 	instanceJoined    = "joined"
 	instanceExpelling = "expelling"
+	instanceExpelled  = "expelled"
 )
 
 // IsJoined .
@@ -88,3 +90,45 @@ func ExpellingSelector() (labels.Selector, error) {
 
 	return s, nil
 }
+
+// ClearExpelling drops the instance state so the instance is joined again instead of being expelled
+func ClearExpelling(p *corev1.Pod) {
+	if IsExpelling(p) {
+		delete(p.GetLabels(), "tarantool.io/instance-state")
+	}
+}
+
+// IsInstanceExpelled reports whether the instance was expelled from the topology and the pod may be removed
+func IsInstanceExpelled(p *corev1.Pod) bool {
+	return p.GetLabels()["tarantool.io/instance-state"] == instanceExpelled
+}
+
+// MarkInstanceExpelled .
+func MarkInstanceExpelled(p *corev1.Pod) {
+	podLabels := p.GetLabels()
+	if podLabels == nil {
+		podLabels = make(map[string]string)
+	}
+	podLabels["tarantool.io/instance-state"] = instanceExpelled
+	p.SetLabels(podLabels)
+}
+
+// IsUpdated reports whether the pod runs the latest revision of the StatefulSet pod template
+func IsUpdated(p *corev1.Pod, sts *appsv1.StatefulSet) bool {
+	if sts.Status.UpdateRevision == "" {
+		return true
+	}
+
+	return p.GetLabels()[appsv1.StatefulSetRevisionLabel] == sts.Status.UpdateRevision
+}
+
+// IsReady reports whether all containers of the pod passed their readiness probes
+func IsReady(p *corev1.Pod) bool {
+	for _, condition := range p.Status.Conditions {
+		if condition.Type == corev1.PodReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+
+	return false
+}
@@ -1 +1,199 @@
 package tarantool
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+)
+
+const (
+	replicasetWeightAnnotation  = "tarantool.io/replicaset-weight"
+	drainingAnnotation          = "tarantool.io/draining"
+	drainedWeightAnnotation     = "tarantool.io/weightBeforeDraining"
+	scheduledDeleteAnnotation   = "tarantool.io/scheduledDelete"
+	instancesExpelledAnnotation = "tarantool.io/instancesExpelled"
+	rolloutPodAnnotation        = "tarantool.io/rolloutPod"
+	rolloutStartedAnnotation    = "tarantool.io/rolloutStarted"
+	rolloutPausedAnnotation     = "tarantool.io/rolloutPaused"
+	generationAnnotation        = "tarantool.io/generation"
+	instanceGenerationPrefix    = "tarantool.io/instance-generation-"
+	importedAnnotation          = "tarantool.io/topologyImported"
+	importedInstancePrefix      = "tarantool.io/instance-uuid-"
+	preferredZoneAnnotation     = "tarantool.io/preferredZone"
+)
+
+// IsDraining reports whether the replicaset is removed by a Role downscale and gives away all of its buckets.
+// A replicaset created with weight 0 is not draining, only the downscale path marks it.
+func IsDraining(sts *appsv1.StatefulSet) bool {
+	return sts.GetAnnotations()[drainingAnnotation] == "1"
+}
+
+// MarkDraining schedules the replicaset for removal and sets its desired weight to 0,
+// the weight it had is kept for UnmarkDraining
+func MarkDraining(sts *appsv1.StatefulSet) {
+	if weight, ok := sts.GetAnnotations()[replicasetWeightAnnotation]; ok {
+		setAnnotation(sts, drainedWeightAnnotation, weight)
+	}
+	setAnnotation(sts, drainingAnnotation, "1")
+	setAnnotation(sts, replicasetWeightAnnotation, "0")
+}
+
+// UnmarkDraining keeps the replicaset and restores the weight it had before the drain,
+// defaultWeight is used if that weight is not known
+func UnmarkDraining(sts *appsv1.StatefulSet, defaultWeight string) {
+	annotations := sts.GetAnnotations()
+	weight, ok := annotations[drainedWeightAnnotation]
+	if !ok {
+		weight = defaultWeight
+	}
+
+	delete(annotations, drainingAnnotation)
+	delete(annotations, drainedWeightAnnotation)
+	sts.SetAnnotations(annotations)
+	setAnnotation(sts, replicasetWeightAnnotation, weight)
+}
+
+// IsScheduledDelete reports whether the replicaset has no buckets left and may be expelled
+func IsScheduledDelete(sts *appsv1.StatefulSet) bool {
+	return sts.GetAnnotations()[scheduledDeleteAnnotation] == "1"
+}
+
+// MarkScheduledDelete .
+func MarkScheduledDelete(sts *appsv1.StatefulSet) {
+	setAnnotation(sts, scheduledDeleteAnnotation, "1")
+}
+
+// IsExpelled reports whether all instances of the replicaset were expelled from the topology
+func IsExpelled(sts *appsv1.StatefulSet) bool {
+	return sts.GetAnnotations()[instancesExpelledAnnotation] == "1"
+}
+
+// MarkExpelled .
+func MarkExpelled(sts *appsv1.StatefulSet) {
+	setAnnotation(sts, instancesExpelledAnnotation, "1")
+}
+
+// GetGeneration returns the incarnation of the replicaset, it changes when a replicaset with the same name
+// is created again after the previous one was expelled
+func GetGeneration(sts *appsv1.StatefulSet) int {
+	generation, _ := strconv.Atoi(sts.GetAnnotations()[generationAnnotation])
+	return generation
+}
+
+// SetGeneration .
+func SetGeneration(sts *appsv1.StatefulSet, generation int) {
+	setAnnotation(sts, generationAnnotation, strconv.Itoa(generation))
+}
+
+// GetInstanceGeneration returns the incarnation of the instance with the ordinal,
+// it changes every time the instance is expelled so the next pod with the ordinal joins under a new uuid
+func GetInstanceGeneration(sts *appsv1.StatefulSet, ordinal int) int {
+	generation, _ := strconv.Atoi(sts.GetAnnotations()[fmt.Sprintf("%s%d", instanceGenerationPrefix, ordinal)])
+	return generation
+}
+
+// NextInstanceGeneration starts a new incarnation of the instance with the ordinal, an imported uuid is forgotten.
+func NextInstanceGeneration(sts *appsv1.StatefulSet, ordinal int) {
+	setAnnotation(sts, fmt.Sprintf("%s%d", instanceGenerationPrefix, ordinal), strconv.Itoa(GetInstanceGeneration(sts, ordinal)+1))
+
+	annotations := sts.GetAnnotations()
+	delete(annotations, fmt.Sprintf("%s%d", importedInstancePrefix, ordinal))
+	sts.SetAnnotations(annotations)
+}
+
+// IsImported reports whether the replicaset was matched against the topology of an adopted cluster.
+func IsImported(sts *appsv1.StatefulSet) bool {
+	return sts.GetAnnotations()[importedAnnotation] == "1"
+}
+
+// MarkImported .
+func MarkImported(sts *appsv1.StatefulSet) {
+	setAnnotation(sts, importedAnnotation, "1")
+}
+
+// GetImportedInstanceUUID returns the uuid the instance with the ordinal had in the adopted topology,
+// pods with the ordinal keep it until the instance is expelled.
+func GetImportedInstanceUUID(sts *appsv1.StatefulSet, ordinal int) (string, bool) {
+	instanceUUID, ok := sts.GetAnnotations()[fmt.Sprintf("%s%d", importedInstancePrefix, ordinal)]
+	return instanceUUID, ok
+}
+
+// SetImportedInstanceUUID .
+func SetImportedInstanceUUID(sts *appsv1.StatefulSet, ordinal int, instanceUUID string) {
+	setAnnotation(sts, fmt.Sprintf("%s%d", importedInstancePrefix, ordinal), instanceUUID)
+}
+
+// GetRolloutStep returns the pod restarted by the rolling update and the time it was restarted at
+func GetRolloutStep(sts *appsv1.StatefulSet) (string, time.Time, bool) {
+	annotations := sts.GetAnnotations()
+	podName, ok := annotations[rolloutPodAnnotation]
+	if !ok {
+		return "", time.Time{}, false
+	}
+
+	started, err := time.Parse(time.RFC3339, annotations[rolloutStartedAnnotation])
+	if err != nil {
+		return podName, time.Time{}, true
+	}
+
+	return podName, started, true
+}
+
+// MarkRolloutStep records the pod restarted by the rolling update
+func MarkRolloutStep(sts *appsv1.StatefulSet, podName string, started time.Time) {
+	setAnnotation(sts, rolloutPodAnnotation, podName)
+	setAnnotation(sts, rolloutStartedAnnotation, started.UTC().Format(time.RFC3339))
+}
+
+// ClearRolloutStep .
+func ClearRolloutStep(sts *appsv1.StatefulSet) {
+	annotations := sts.GetAnnotations()
+	delete(annotations, rolloutPodAnnotation)
+	delete(annotations, rolloutStartedAnnotation)
+	sts.SetAnnotations(annotations)
+}
+
+// IsRolloutPaused reports whether the rolling update of the replicaset was stopped after a failed step,
+// it is resumed by removing the tarantool.io/rolloutPaused annotation
+func IsRolloutPaused(sts *appsv1.StatefulSet) bool {
+	_, ok := sts.GetAnnotations()[rolloutPausedAnnotation]
+	return ok
+}
+
+// GetRolloutPausedReason .
+func GetRolloutPausedReason(sts *appsv1.StatefulSet) string {
+	return sts.GetAnnotations()[rolloutPausedAnnotation]
+}
+
+// MarkRolloutPaused .
+func MarkRolloutPaused(sts *appsv1.StatefulSet, reason string) {
+	setAnnotation(sts, rolloutPausedAnnotation, reason)
+}
+
+// GetPreferredZone returns the zone the Role keeps the masters of the replicaset in, empty when it has none
+func GetPreferredZone(sts *appsv1.StatefulSet) string {
+	return sts.GetAnnotations()[preferredZoneAnnotation]
+}
+
+// SetPreferredZone copies the preferred zone of the Role to the replicaset, an empty zone is removed
+func SetPreferredZone(sts *appsv1.StatefulSet, zone string) {
+	if zone == "" {
+		annotations := sts.GetAnnotations()
+		delete(annotations, preferredZoneAnnotation)
+		sts.SetAnnotations(annotations)
+		return
+	}
+
+	setAnnotation(sts, preferredZoneAnnotation, zone)
+}
+
+func setAnnotation(sts *appsv1.StatefulSet, key, value string) {
+	annotations := sts.GetAnnotations()
+	if annotations == nil {
+		annotations = make(map[string]string)
+	}
+	annotations[key] = value
+	sts.SetAnnotations(annotations)
+}
@@ -0,0 +1,108 @@
+package tarantool
+
+import (
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+)
+
+const (
+	switchoverAnnotation         = "tarantool.io/switchover"
+	switchoverAtAnnotation       = "tarantool.io/switchoverAt"
+	switchoverStartedAnnotation  = "tarantool.io/switchoverStarted"
+	switchoverTargetAnnotation   = "tarantool.io/switchoverTarget"
+	switchoverResultAnnotation   = "tarantool.io/switchoverResult"
+	switchoverMessageAnnotation  = "tarantool.io/switchoverMessage"
+	switchoverFinishedAnnotation = "tarantool.io/switchoverFinished"
+	pinnedMasterAnnotation       = "tarantool.io/pinnedMaster"
+)
+
+// Outcomes of a switchover
+const (
+	SwitchoverSucceeded = "Succeeded"
+	SwitchoverFailed    = "Failed"
+)
+
+// SwitchoverOutcome is the result of the last switchover of a replicaset
+type SwitchoverOutcome struct {
+	// Target is the pod which was requested to become the master
+	Target   string
+	Result   string
+	Message  string
+	Finished time.Time
+}
+
+// GetSwitchoverRequest returns the pod the tarantool.io/switchover annotation asks to make the master of the replicaset
+// and the time the tarantool.io/switchoverAt annotation schedules it at, zero when it is due right away
+func GetSwitchoverRequest(sts *appsv1.StatefulSet) (string, time.Time, bool, error) {
+	annotations := sts.GetAnnotations()
+	podName, ok := annotations[switchoverAnnotation]
+	if !ok {
+		return "", time.Time{}, false, nil
+	}
+
+	at, ok := annotations[switchoverAtAnnotation]
+	if !ok {
+		return podName, time.Time{}, true, nil
+	}
+
+	scheduled, err := time.Parse(time.RFC3339, at)
+	return podName, scheduled, true, err
+}
+
+// GetSwitchoverStarted returns when the master switch was requested from Cartridge
+func GetSwitchoverStarted(sts *appsv1.StatefulSet) (time.Time, bool) {
+	started, err := time.Parse(time.RFC3339, sts.GetAnnotations()[switchoverStartedAnnotation])
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	return started, true
+}
+
+// MarkSwitchoverStarted .
+func MarkSwitchoverStarted(sts *appsv1.StatefulSet, started time.Time) {
+	setAnnotation(sts, switchoverStartedAnnotation, started.UTC().Format(time.RFC3339))
+}
+
+// FinishSwitchover records the outcome of the switchover and removes the request
+func FinishSwitchover(sts *appsv1.StatefulSet, outcome SwitchoverOutcome) {
+	annotations := sts.GetAnnotations()
+	delete(annotations, switchoverAnnotation)
+	delete(annotations, switchoverAtAnnotation)
+	delete(annotations, switchoverStartedAnnotation)
+	sts.SetAnnotations(annotations)
+
+	setAnnotation(sts, switchoverTargetAnnotation, outcome.Target)
+	setAnnotation(sts, switchoverResultAnnotation, outcome.Result)
+	setAnnotation(sts, switchoverMessageAnnotation, outcome.Message)
+	setAnnotation(sts, switchoverFinishedAnnotation, outcome.Finished.UTC().Format(time.RFC3339))
+}
+
+// GetSwitchoverOutcome returns the outcome of the last switchover of the replicaset
+func GetSwitchoverOutcome(sts *appsv1.StatefulSet) (SwitchoverOutcome, bool) {
+	annotations := sts.GetAnnotations()
+	result, ok := annotations[switchoverResultAnnotation]
+	if !ok {
+		return SwitchoverOutcome{}, false
+	}
+
+	finished, _ := time.Parse(time.RFC3339, annotations[switchoverFinishedAnnotation])
+	return SwitchoverOutcome{
+		Target:   annotations[switchoverTargetAnnotation],
+		Result:   result,
+		Message:  annotations[switchoverMessageAnnotation],
+		Finished: finished,
+	}, true
+}
+
+// GetPinnedMaster returns the pod a successful switchover made the master, it is kept first in the failover
+// priority until the tarantool.io/pinnedMaster annotation is removed or another switchover succeeds
+func GetPinnedMaster(sts *appsv1.StatefulSet) string {
+	return sts.GetAnnotations()[pinnedMasterAnnotation]
+}
+
+// SetPinnedMaster .
+func SetPinnedMaster(sts *appsv1.StatefulSet, podName string) {
+	setAnnotation(sts, pinnedMasterAnnotation, podName)
+}
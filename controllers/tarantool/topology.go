@@ -0,0 +1,31 @@
+package tarantool
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const topologyRefusedAnnotation = "tarantool.io/topologyRefused"
+
+// IsTopologyRefused reports whether Cartridge refused the last change of the instance or the replicaset,
+// such changes are applied apart from the others so they do not block them
+func IsTopologyRefused(o metav1.Object) bool {
+	_, ok := o.GetAnnotations()[topologyRefusedAnnotation]
+	return ok
+}
+
+// MarkTopologyRefused records why Cartridge refused the change of the pod or the StatefulSet
+func MarkTopologyRefused(o metav1.Object, reason string) {
+	annotations := o.GetAnnotations()
+	if annotations == nil {
+		annotations = make(map[string]string)
+	}
+	annotations[topologyRefusedAnnotation] = reason
+	o.SetAnnotations(annotations)
+}
+
+// ClearTopologyRefused forgets the refusal once Cartridge applied the change
+func ClearTopologyRefused(o metav1.Object) {
+	annotations := o.GetAnnotations()
+	delete(annotations, topologyRefusedAnnotation)
+	o.SetAnnotations(annotations)
+}
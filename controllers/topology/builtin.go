@@ -2,22 +2,23 @@ package topology
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
-	"strings"
 	"time"
 
-	"github.com/machinebox/graphql"
 	corev1 "k8s.io/api/core/v1"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	tarantooliov1alpha1 "github.com/tarantool/tarantool-operator/api/v1alpha1"
+	"github.com/tarantool/tarantool-operator/controllers/utils"
 )
 
-// ResponseError .
+// ResponseError is an error reported by the admin API
 type ResponseError struct {
-	Message string `json:"message"`
+	Message    string                 `json:"message"`
+	Extensions map[string]interface{} `json:"extensions,omitempty"`
 }
 
 // JoinResponseData .
@@ -25,21 +26,18 @@ type JoinResponseData struct {
 	JoinInstance bool `json:"joinInstanceResponse"`
 }
 
-// JoinResponse .
-type JoinResponse struct {
-	Errors []*ResponseError  `json:"errors,omitempty"`
-	Data   *JoinResponseData `json:"data,omitempty"`
-}
-
 // ExpelResponseData .
 type ExpelResponseData struct {
-	ExpelInstance bool `json:"expel_instance"`
+	Response bool `json:"expelServerResponse"`
 }
 
-// ExpelResponse .
-type ExpelResponse struct {
-	Errors []*ResponseError   `json:"errors,omitempty"`
-	Data   *ExpelResponseData `json:"data,omitempty"`
+// EditTopologyResponse .
+type EditTopologyResponse struct {
+	Cluster struct {
+		EditTopology *struct {
+			Servers []*ServerData `json:"servers"`
+		} `json:"editTopologyResponse"`
+	} `json:"cluster"`
 }
 
 // BootstrapVshardData .
@@ -47,26 +45,18 @@ type BootstrapVshardData struct {
 	BootstrapVshard bool `json:"bootstrapVshardResponse"`
 }
 
-// BootstrapVshardResponse .
-type BootstrapVshardResponse struct {
-	Data   *BootstrapVshardData `json:"data,omitempty"`
-	Errors []*ResponseError     `json:"errors,omitempty"`
-}
-
-// FailoverData Structure of data for changing failover status
-type FailoverData struct {
-}
-
-// FailoverResponse type struct for returning on failovers
-type FailoverResponse struct {
-	Data   *FailoverData
-	Errors []*ResponseError
-}
+var _ TopologyService = &BuiltInTopologyService{}
 
 // BuiltInTopologyService .
 type BuiltInTopologyService struct {
-	serviceHost string
-	clusterID   string
+	serviceHost       string
+	clusterID         string
+	clusterDomainName string
+	binaryPort        int32
+	vshardGroups      []string
+	httpClient        *http.Client
+	maxRetries        int
+	retryBackoff      time.Duration
 }
 
 // EditReplicasetResponse .
@@ -74,12 +64,6 @@ type EditReplicasetResponse struct {
 	Response bool `json:"editReplicasetResponse"`
 }
 
-// GetServerStatResponse .
-type GetServerStatResponse struct {
-	Data   *ServerStatData  `json:"data"`
-	Errors []*ResponseError `json:"errors,omitempty"`
-}
-
 // ServerStatData .
 type ServerStatData struct {
 	Stats []*ServerStat `json:"serverStat"`
@@ -99,9 +83,48 @@ type ReplicasetsQueryResponse struct {
 
 // ReplicasetData .
 type ReplicasetData struct {
-	UUID   string   `json:"uuid"`
-	Roles  []string `json:"roles"`
-	Weight *int     `json:"weight"`
+	UUID    string        `json:"uuid"`
+	Alias   string        `json:"alias"`
+	Roles   []string      `json:"roles"`
+	Weight  *int          `json:"weight"`
+	Status  string        `json:"status"`
+	Servers []*ServerData `json:"servers"`
+	// Master is the first instance in the failover priority list
+	Master *ServerData `json:"master"`
+	// ActiveMaster is the instance currently acting as a master
+	ActiveMaster *ServerData `json:"active_master"`
+}
+
+// ServerData .
+type ServerData struct {
+	UUID   string `json:"uuid"`
+	URI    string `json:"uri"`
+	Alias  string `json:"alias"`
+	Status string `json:"status"`
+	// Zone is the zone vshard routers use to pick the nearest replica, empty when it is not set
+	Zone   string   `json:"zone"`
+	Labels []*Label `json:"labels"`
+}
+
+// Label is a label of a Cartridge instance
+type Label struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// SelfData is the instance answering the admin API
+type SelfData struct {
+	URI string `json:"uri"`
+	// UUID is empty until the instance is joined to the topology
+	UUID  string `json:"uuid"`
+	Alias string `json:"alias"`
+}
+
+// SelfQueryResponse .
+type SelfQueryResponse struct {
+	Cluster struct {
+		Self *SelfData `json:"self"`
+	} `json:"cluster"`
 }
 
 // Statistics .
@@ -116,12 +139,6 @@ type Statistics struct {
 
 var log = logf.Log.WithName("topology")
 
-var (
-	errTopologyIsDown      = errors.New("topology service is down")
-	errAlreadyJoined       = errors.New("already joined")
-	errAlreadyBootstrapped = errors.New("already bootstrapped")
-)
-
 var joinMutation = `mutation
 	do_join_server(
 		$uri: String!,
@@ -156,6 +173,37 @@ var getRsRolesQuery = `query ($uuid: String!) {
 	replicasets(uuid: $uuid) { roles }
 }`
 
+var getReplicasetsQuery = `query {
+	replicasets {
+		uuid
+		alias
+		roles
+		weight
+		status
+		servers {
+			uuid
+			uri
+			alias
+			status
+			zone
+			labels {
+				name
+				value
+			}
+		}
+		master {
+			uuid
+		}
+		active_master {
+			uuid
+		}
+	}
+}`
+
+var setRsFailoverPriorityMutation = `mutation editReplicaset($uuid: String!, $failover_priority: [String!]) {
+	editReplicasetResponse: edit_replicaset(uuid: $uuid, failover_priority: $failover_priority)
+}`
+
 var getServerStatQuery = `query serverList {
 	serverStat: servers {
 		uuid
@@ -171,62 +219,101 @@ var getServerStatQuery = `query serverList {
 	}
 }`
 
+var expelMutation = `mutation expelServer($uuid: String!) {
+	expelServerResponse: expel_server(uuid: $uuid)
+}`
+
+var editTopologyMutation = `mutation editTopology($replicasets: [EditReplicasetInput], $servers: [EditServerInput]) {
+	cluster {
+		editTopologyResponse: edit_topology(replicasets: $replicasets, servers: $servers) {
+			servers { uuid }
+		}
+	}
+}`
+
+var bootstrapVshardMutation = `mutation bootstrap {
+	bootstrapVshardResponse: bootstrap_vshard
+}`
+
+var getSelfQuery = `query self {
+	cluster {
+		self {
+			uri
+			uuid
+			alias
+		}
+	}
+}`
+
 // An interface describing an object with accessor methods for labels and annotations
 type ObjectWithMeta interface {
 	GetLabels() map[string]string
 	GetAnnotations() map[string]string
 }
 
-// GetRoles comment
+// GetRoles returns the Cartridge roles assigned to the object
 func GetRoles(obj ObjectWithMeta) ([]string, error) {
-	labels := obj.GetLabels()
-	annotations := obj.GetAnnotations()
+	roles, err := tarantooliov1alpha1.ParseRoles(obj.GetLabels(), obj.GetAnnotations())
+	if err != nil {
+		return nil, err
+	}
 
-	rolesFromAnnotations, ok := annotations["tarantool.io/rolesToAssign"]
-	if !ok {
-		rolesFromLabels, ok := labels["tarantool.io/rolesToAssign"]
+	log.Info("roles", "roles", roles)
+	return roles, nil
+}
+
+// AdvertiseURI is the iproto address the instance of the pod is reachable at
+func AdvertiseURI(pod *corev1.Pod, clusterID string, clusterDomainName string, binaryPort int32) string {
+	if clusterDomainName == "" {
+		domainFromLabels, ok := pod.GetLabels()["tarantool.io/cluster-domain-name"]
 		if !ok {
-			return nil, errors.New("role undefined")
+			domainFromLabels = "cluster.local"
 		}
+		clusterDomainName = domainFromLabels
+	}
 
-		roles := strings.Split(rolesFromLabels, ".")
-		log.Info("roles", "roles", roles)
-
-		return roles, nil
+	if binaryPort == 0 {
+		binaryPort = 3301
 	}
 
-	var singleRole string
-	var roleArray []string
+	return fmt.Sprintf("%s.%s.%s.svc.%s:%d",
+		pod.GetName(),      // Instance name
+		clusterID,          // Cartridge cluster name
+		pod.GetNamespace(), // Namespace
+		clusterDomainName,  // Cluster domain name
+		binaryPort)         // Tarantool iproto port
+}
+
+// VshardGroup returns the vshard group of the replicaset with the pod template labels,
+// empty when vshard groups are not used
+func VshardGroup(podLabels map[string]string, vshardGroups []string) (string, error) {
 
-	err := json.Unmarshal([]byte(rolesFromAnnotations), &singleRole)
-	if err == nil {
-		log.Info("roles", "roles", singleRole)
-		return []string{singleRole}, nil
+	useVshardGroups := len(vshardGroups) > 0
+	if !useVshardGroups {
+		useVshardGroupsFromLabels, ok := podLabels["tarantool.io/useVshardGroups"]
+		if !ok {
+			return "", errors.New("failed to get label tarantool.io/useVshardGroups")
+		}
+		useVshardGroups = useVshardGroupsFromLabels == "1"
 	}
 
-	err = json.Unmarshal([]byte(rolesFromAnnotations), &roleArray)
-	if err == nil {
-		log.Info("roles", "roles", roleArray)
-		return roleArray, nil
+	if !useVshardGroups {
+		return "", nil
 	}
 
-	return nil, errors.New("failed to parse roles from annotations")
+	vshardGroup, ok := podLabels["tarantool.io/vshardGroupName"]
+	if !ok {
+		return "", errors.New("vshard_group undefined")
+	}
+
+	return vshardGroup, nil
 }
 
 // Join comment
-func (s *BuiltInTopologyService) Join(pod *corev1.Pod) error {
+func (s *BuiltInTopologyService) Join(ctx context.Context, pod *corev1.Pod) error {
 
 	thisPodLabels := pod.GetLabels()
-	clusterDomainName, ok := thisPodLabels["tarantool.io/cluster-domain-name"]
-	if !ok {
-		clusterDomainName = "cluster.local"
-	}
-
-	advURI := fmt.Sprintf("%s.%s.%s.svc.%s:3301",
-		pod.GetObjectMeta().GetName(),      // Instance name
-		s.clusterID,                        // Cartridge cluster name
-		pod.GetObjectMeta().GetNamespace(), // Namespace
-		clusterDomainName)                  // Cluster domain name
+	advURI := AdvertiseURI(pod, s.clusterID, s.clusterDomainName, s.binaryPort)
 
 	replicasetUUID, ok := thisPodLabels["tarantool.io/replicaset-uuid"]
 	if !ok {
@@ -246,90 +333,131 @@ func (s *BuiltInTopologyService) Join(pod *corev1.Pod) error {
 	}
 	log.Info("roles", "roles", roles)
 
-	vshardGroup := "default"
-	useVshardGroups, ok := thisPodLabels["tarantool.io/useVshardGroups"]
-	if !ok {
-		return errors.New("failed to get label tarantool.io/useVshardGroups")
+	vshardGroup, err := VshardGroup(pod.GetLabels(), s.vshardGroups)
+	if err != nil {
+		return err
 	}
-
-	if useVshardGroups == "1" {
-		vshardGroup, ok = thisPodLabels["tarantool.io/vshardGroupName"]
-		if !ok {
-			return errors.New("vshard_group undefined")
-		}
+	if vshardGroup == "" {
+		vshardGroup = "default"
 	}
 
-	client := graphql.NewClient(s.serviceHost, graphql.WithHTTPClient(&http.Client{Timeout: time.Duration(time.Second * 5)}))
-	req := graphql.NewRequest(joinMutation)
-
-	req.Var("uri", advURI)
-	req.Var("instance_uuid", instanceUUID)
-	req.Var("replicaset_uuid", replicasetUUID)
-	req.Var("roles", roles)
-	req.Var("vshard_group", vshardGroup)
+	vars := map[string]interface{}{
+		"uri":             advURI,
+		"instance_uuid":   instanceUUID,
+		"replicaset_uuid": replicasetUUID,
+		"roles":           roles,
+		"vshard_group":    vshardGroup,
+	}
 
 	resp := &JoinResponseData{}
-	if err := client.Run(context.TODO(), req, resp); err != nil {
-		if strings.Contains(err.Error(), "already joined") {
-			return errAlreadyJoined
-		}
-		if strings.Contains(err.Error(), "This instance isn't bootstrapped yet") {
-			return errTopologyIsDown
-		}
-
-		return err
+	if err := s.run(ctx, "join_server", joinMutation, vars, resp); err != nil {
+		return s.checkJoin(ctx, err, []string{instanceUUID})
 	}
 
 	if resp.JoinInstance {
 		return nil
 	}
 
-	return errors.New("something really bad happened")
+	return newOpError("join_server", nil, "instance %s was not joined", instanceUUID)
 }
 
-// SetFailover enables cluster failover
-func (s *BuiltInTopologyService) SetFailover(enabled bool) error {
-	client := graphql.NewClient(s.serviceHost, graphql.WithHTTPClient(&http.Client{Timeout: time.Duration(time.Second * 5)}))
-	req := graphql.NewRequest(`mutation changeFailover($enabled: Boolean!) { cluster { failover(enabled: $enabled) }}`)
+// Expel removes instances from the topology with a single edit_topology request.
+// The request is applied atomically, so when it is refused or Cartridge does not support it,
+// the instances are expelled one by one with expel_server and the ones that failed are reported by ExpelError.
+func (s *BuiltInTopologyService) Expel(ctx context.Context, pods ...*corev1.Pod) error {
+	failed := make(map[string]error)
+	batch := make([]*corev1.Pod, 0, len(pods))
+	patch := &TopologyPatch{}
+	for _, pod := range pods {
+		instanceUUID, ok := pod.GetLabels()["tarantool.io/instance-uuid"]
+		if !ok || instanceUUID == "" {
+			failed[pod.GetName()] = newOpError("edit_topology", ErrNotFound, "pod %s has no instance uuid", pod.GetName())
+			continue
+		}
+
+		batch = append(batch, pod)
+		patch.Servers = append(patch.Servers, &EditServerInput{UUID: instanceUUID, Expelled: true})
+	}
 
-	req.Var("enabled", enabled)
+	if len(batch) > 0 {
+		log.Info("expelling instances", "count", len(batch))
 
-	resp := &FailoverData{}
-	if err := client.Run(context.TODO(), req, resp); err != nil {
-		log.Error(err, "failoverError")
-		return errors.New("failed to enable cluster failover")
+		err := s.EditTopology(ctx, patch)
+		if IsRetryable(err) {
+			return err
+		}
+		if err != nil {
+			log.Info("batch expel failed, expelling instances one by one", "error", err.Error())
+			for _, pod := range batch {
+				if err := s.expelServer(ctx, pod); err != nil {
+					failed[pod.GetName()] = err
+				}
+			}
+		}
+	}
+
+	if len(failed) > 0 {
+		return &ExpelError{Failed: failed}
 	}
 
 	return nil
 }
 
-// Expel removes an instance from the replicaset
-func (s *BuiltInTopologyService) Expel(pod *corev1.Pod) error {
-	req := fmt.Sprintf("mutation {expel_instance:expel_server(uuid:\\\"%s\\\")}", pod.GetAnnotations()["tarantool.io/instance_uuid"])
-	j := fmt.Sprintf("{\"query\": \"%s\"}", req)
-	rawResp, err := http.Post(s.serviceHost, "application/json", strings.NewReader(j))
-	if err != nil {
-		return err
+// EditTopology applies all changes of the patch at once: Cartridge validates the whole new configuration
+// and either applies it with a single config reload or refuses it
+func (s *BuiltInTopologyService) EditTopology(ctx context.Context, patch *TopologyPatch) error {
+	vars := map[string]interface{}{}
+	if len(patch.Replicasets) > 0 {
+		vars["replicasets"] = patch.Replicasets
+	}
+	if len(patch.Servers) > 0 {
+		vars["servers"] = patch.Servers
+	}
+
+	resp := &EditTopologyResponse{}
+	if err := s.run(ctx, "edit_topology", editTopologyMutation, vars, resp); err != nil {
+		if IsRetryable(err) && !isUndelivered(err) && s.isApplied(ctx, patch) {
+			log.Info("edit_topology request failed but the topology was changed", "error", err.Error())
+			return nil
+		}
+
+		var joining []string
+		for _, rs := range patch.Replicasets {
+			for _, server := range rs.JoinServers {
+				joining = append(joining, server.UUID)
+			}
+		}
+		return s.checkJoin(ctx, err, joining)
+	}
+
+	if resp.Cluster.EditTopology == nil {
+		return newOpError("edit_topology", nil, "topology was not changed")
 	}
-	defer rawResp.Body.Close()
 
-	resp := &ExpelResponse{Errors: []*ResponseError{}, Data: &ExpelResponseData{}}
-	if err := json.NewDecoder(rawResp.Body).Decode(resp); err != nil {
+	return nil
+}
+
+// expelServer removes a single instance with the expel_server mutation supported by all Cartridge versions
+func (s *BuiltInTopologyService) expelServer(ctx context.Context, pod *corev1.Pod) error {
+	instanceUUID := pod.GetLabels()["tarantool.io/instance-uuid"]
+
+	resp := &ExpelResponseData{}
+	if err := s.run(ctx, "expel_server", expelMutation, map[string]interface{}{"uuid": instanceUUID}, resp); err != nil {
+		if IsRetryable(err) && !isUndelivered(err) && s.isApplied(ctx, &TopologyPatch{Servers: []*EditServerInput{{UUID: instanceUUID, Expelled: true}}}) {
+			return nil
+		}
 		return err
 	}
 
-	if !resp.Data.ExpelInstance && (resp.Errors == nil || len(resp.Errors) == 0) {
-		return errors.New("something really bad happened")
+	if !resp.Response {
+		return newOpError("expel_server", nil, "instance %s was not expelled", instanceUUID)
 	}
 
 	return nil
 }
 
 // SetWeight sets weight of a replicaset
-func (s *BuiltInTopologyService) SetWeight(replicasetUUID string, replicaWeight string) error {
-	client := graphql.NewClient(s.serviceHost, graphql.WithHTTPClient(&http.Client{Timeout: time.Duration(time.Second * 5)}))
-	req := graphql.NewRequest(setRsWeightMutation)
-
+func (s *BuiltInTopologyService) SetWeight(ctx context.Context, replicasetUUID string, replicaWeight string) error {
 	reqLogger := log.WithValues("namespace", "topology.builtin")
 
 	weightParam, err := strconv.ParseUint(replicaWeight, 10, 32)
@@ -339,11 +467,8 @@ func (s *BuiltInTopologyService) SetWeight(replicasetUUID string, replicaWeight
 
 	reqLogger.Info("setting cluster weight", "uuid", replicasetUUID, "weight", replicaWeight)
 
-	req.Var("uuid", replicasetUUID)
-	req.Var("weight", weightParam)
-
 	resp := &EditReplicasetResponse{}
-	if err := client.Run(context.TODO(), req, resp); err != nil {
+	if err := s.run(ctx, "edit_replicaset", setRsWeightMutation, map[string]interface{}{"uuid": replicasetUUID, "weight": weightParam}, resp); err != nil {
 		return err
 	}
 
@@ -351,27 +476,22 @@ func (s *BuiltInTopologyService) SetWeight(replicasetUUID string, replicaWeight
 		return nil
 	}
 
-	return errors.New("something really bad happened")
+	return newOpError("edit_replicaset", nil, "weight of replicaset %s was not changed", replicasetUUID)
 }
 
 // GetWeight gets weight of a replicaset
-func (s *BuiltInTopologyService) GetWeight(replicasetUUID string) (int, error) {
-	client := graphql.NewClient(s.serviceHost, graphql.WithHTTPClient(&http.Client{Timeout: time.Duration(time.Second * 5)}))
-	req := graphql.NewRequest(getRsWeightQuery)
-
+func (s *BuiltInTopologyService) GetWeight(ctx context.Context, replicasetUUID string) (int, error) {
 	reqLogger := log.WithValues("namespace", "topology.builtin")
 
 	reqLogger.Info("getting cluster weight", "uuid", replicasetUUID)
 
-	req.Var("uuid", replicasetUUID)
-
 	resp := &ReplicasetsQueryResponse{}
-	if err := client.Run(context.TODO(), req, resp); err != nil {
+	if err := s.run(ctx, "replicasets", getRsWeightQuery, map[string]interface{}{"uuid": replicasetUUID}, resp); err != nil {
 		return -1, err
 	}
 
 	if len(resp.Replicasets) == 0 {
-		return -1, fmt.Errorf("replicaset with uuid: '%s' not found", replicasetUUID)
+		return -1, newOpError("replicasets", ErrNotFound, "replicaset with uuid: '%s' not found", replicasetUUID)
 	}
 
 	// Instance without role vshard-storage returns null as weight
@@ -383,54 +503,83 @@ func (s *BuiltInTopologyService) GetWeight(replicasetUUID string) (int, error) {
 }
 
 // SetReplicasetRoles set roles list of replicaset in the Tarantool service
-func (s *BuiltInTopologyService) SetReplicasetRoles(replicasetUUID string, roles []string) error {
+func (s *BuiltInTopologyService) SetReplicasetRoles(ctx context.Context, replicasetUUID string, roles []string) error {
 	reqLogger := log.WithValues("namespace", "topology.builtin")
 	reqLogger.Info("setting replicaset roles", "uuid", replicasetUUID, "weight", roles)
 
-	req := graphql.NewRequest(setRsRolesMutation)
-	req.Var("uuid", replicasetUUID)
-	req.Var("roles", roles)
-
 	resp := &EditReplicasetResponse{}
-	client := graphql.NewClient(s.serviceHost, graphql.WithHTTPClient(&http.Client{Timeout: time.Duration(time.Second * 5)}))
+	return s.run(ctx, "edit_replicaset", setRsRolesMutation, map[string]interface{}{"uuid": replicasetUUID, "roles": roles}, resp)
+}
+
+// SetFailoverPriority sets the order in which instances of the replicaset become a master,
+// the first instance of the list becomes the master right away
+func (s *BuiltInTopologyService) SetFailoverPriority(ctx context.Context, replicasetUUID string, priority []string) error {
+	reqLogger := log.WithValues("namespace", "topology.builtin")
+	reqLogger.Info("setting replicaset failover priority", "uuid", replicasetUUID, "priority", priority)
 
-	if err := client.Run(context.TODO(), req, resp); err != nil {
+	resp := &EditReplicasetResponse{}
+	if err := s.run(ctx, "edit_replicaset", setRsFailoverPriorityMutation, map[string]interface{}{"uuid": replicasetUUID, "failover_priority": priority}, resp); err != nil {
 		return err
 	}
-	return nil
+
+	if resp.Response {
+		return nil
+	}
+
+	return newOpError("edit_replicaset", nil, "failover priority of replicaset %s was not changed", replicasetUUID)
 }
 
 // GetReplicasetRolesFromService get roles list of replicaset from the Tarantool service
-func (s *BuiltInTopologyService) GetReplicasetRolesFromService(replicasetUUID string) ([]string, error) {
+func (s *BuiltInTopologyService) GetReplicasetRolesFromService(ctx context.Context, replicasetUUID string) ([]string, error) {
 	reqLogger := log.WithValues("namespace", "topology.builtin")
 	reqLogger.Info("getting replicaset roles", "uuid", replicasetUUID)
 
-	req := graphql.NewRequest(getRsRolesQuery)
-	req.Var("uuid", replicasetUUID)
-
 	resp := &ReplicasetsQueryResponse{}
-	client := graphql.NewClient(s.serviceHost, graphql.WithHTTPClient(&http.Client{Timeout: time.Duration(time.Second * 5)}))
-	if err := client.Run(context.TODO(), req, resp); err != nil {
+	if err := s.run(ctx, "replicasets", getRsRolesQuery, map[string]interface{}{"uuid": replicasetUUID}, resp); err != nil {
 		return nil, err
 	}
 
 	if len(resp.Replicasets) == 0 {
-		return nil, fmt.Errorf("replicaset with uuid: '%s' not found", replicasetUUID)
+		return nil, newOpError("replicasets", ErrNotFound, "replicaset with uuid: '%s' not found", replicasetUUID)
 	}
 	return resp.Replicasets[0].Roles, nil
 }
 
-// GetServerStat Fetch the replicaset as reported by cartridge
-func (s *BuiltInTopologyService) GetServerStat() (ServerStatData, error) {
-	client := graphql.NewClient(s.serviceHost, graphql.WithHTTPClient(&http.Client{Timeout: time.Duration(time.Second * 5)}))
-	req := graphql.NewRequest(getServerStatQuery)
+// GetReplicasets fetches all replicasets of the cluster with their servers
+func (s *BuiltInTopologyService) GetReplicasets(ctx context.Context) ([]*ReplicasetData, error) {
+	resp := &ReplicasetsQueryResponse{}
+	if err := s.run(ctx, "replicasets", getReplicasetsQuery, nil, resp); err != nil {
+		return nil, err
+	}
+
+	return resp.Replicasets, nil
+}
+
+// GetSelf asks the instance serving the admin API about itself.
+// It is used to probe instances, so it is not retried and gives up after probeTimeout.
+func (s *BuiltInTopologyService) GetSelf(ctx context.Context) (*SelfData, error) {
+	ctx, cancel := context.WithTimeout(ctx, probeTimeout)
+	defer cancel()
+
+	resp := &SelfQueryResponse{}
+	if err := s.do(ctx, "self", getSelfQuery, nil, resp); err != nil {
+		return nil, err
+	}
+	if resp.Cluster.Self == nil {
+		return nil, newOpError("self", nil, "instance at %s did not report itself", s.serviceHost)
+	}
+
+	return resp.Cluster.Self, nil
+}
 
+// GetServerStat Fetch the replicaset as reported by cartridge
+func (s *BuiltInTopologyService) GetServerStat(ctx context.Context) (ServerStatData, error) {
 	reqLogger := log.WithValues("function", "GetServerStat")
 
 	reqLogger.Info("fetching server stats")
 
 	resp := ServerStatData{}
-	if err := client.Run(context.TODO(), req, &resp); err != nil {
+	if err := s.run(ctx, "servers", getServerStatQuery, nil, &resp); err != nil {
 		return resp, err
 	}
 
@@ -438,77 +587,145 @@ func (s *BuiltInTopologyService) GetServerStat() (ServerStatData, error) {
 }
 
 // BootstrapVshard enable the vshard service on the cluster
-func (s *BuiltInTopologyService) BootstrapVshard() error {
+func (s *BuiltInTopologyService) BootstrapVshard(ctx context.Context) error {
 	reqLogger := log.WithValues("namespace", "topology.builtin")
 
 	reqLogger.Info("Bootstrapping vshard")
 
-	req := "mutation bootstrap {bootstrapVshardResponse: bootstrap_vshard}"
-	j := fmt.Sprintf("{\"query\": \"%s\"}", req)
-	rawResp, err := http.Post(s.serviceHost, "application/json", strings.NewReader(j))
-	if err != nil {
+	resp := &BootstrapVshardData{}
+	if err := s.run(ctx, "bootstrap_vshard", bootstrapVshardMutation, nil, resp); err != nil {
+		if isRefused(err) && s.isBootstrapped(ctx) {
+			return withKind(err, ErrAlreadyBootstrapped)
+		}
 		return err
 	}
 
-	defer rawResp.Body.Close()
+	if resp.BootstrapVshard {
+		return nil
+	}
+
+	return newOpError("bootstrap_vshard", nil, "vshard was not bootstrapped")
+}
 
-	resp := &BootstrapVshardResponse{Data: &BootstrapVshardData{}}
-	if err := json.NewDecoder(rawResp.Body).Decode(resp); err != nil {
+// checkJoin finds out whether a failed request joining instances was applied or refused because
+// one of the instances is already a member of the topology
+func (s *BuiltInTopologyService) checkJoin(ctx context.Context, err error, joining []string) error {
+	if len(joining) == 0 || !(isRefused(err) || (IsRetryable(err) && !isUndelivered(err))) {
 		return err
 	}
 
-	if resp.Data.BootstrapVshard {
-		return nil
+	replicasets, queryErr := s.GetReplicasets(ctx)
+	if queryErr != nil {
+		return err
 	}
-	if resp.Errors != nil && len(resp.Errors) > 0 {
-		if strings.Contains(resp.Errors[0].Message, "already bootstrapped") {
-			return errAlreadyBootstrapped
+
+	servers := make(map[string]bool)
+	for _, rs := range replicasets {
+		for _, server := range rs.Servers {
+			servers[server.UUID] = true
 		}
+	}
 
-		return errors.New(resp.Errors[0].Message)
+	joined := 0
+	for _, uuid := range joining {
+		if servers[uuid] {
+			joined++
+		}
 	}
 
-	return errors.New("unknown error")
-}
+	switch {
+	case isRefused(err) && joined > 0:
+		return withKind(err, ErrAlreadyJoined)
+	case IsRetryable(err) && joined == len(joining):
+		log.Info("join request failed but the instances were joined", "error", err.Error())
+		return nil
+	}
 
-// IsTopologyDown .
-func IsTopologyDown(err error) bool {
-	return err == errTopologyIsDown
+	return err
 }
 
-// IsAlreadyJoined .
-func IsAlreadyJoined(err error) bool {
-	return err == errAlreadyJoined
-}
+// isApplied reports whether the topology already has every change of the patch
+func (s *BuiltInTopologyService) isApplied(ctx context.Context, patch *TopologyPatch) bool {
+	replicasets, err := s.GetReplicasets(ctx)
+	if err != nil {
+		return false
+	}
 
-// IsAlreadyBootstrapped .
-func IsAlreadyBootstrapped(err error) bool {
-	return err == errAlreadyBootstrapped
-}
+	actualByUUID := make(map[string]*ReplicasetData)
+	servers := make(map[string]string)
+	serverData := make(map[string]*ServerData)
+	for _, rs := range replicasets {
+		actualByUUID[rs.UUID] = rs
+		for _, server := range rs.Servers {
+			servers[server.UUID] = rs.UUID
+			serverData[server.UUID] = server
+		}
+	}
 
-// Option .
-type Option func(s *BuiltInTopologyService)
+	for _, edit := range patch.Replicasets {
+		actual, ok := actualByUUID[edit.UUID]
+		if !ok {
+			return false
+		}
+		if edit.Roles != nil && !utils.IsRolesEquals(actual.Roles, *edit.Roles) {
+			return false
+		}
+		if edit.Weight != nil && (actual.Weight == nil || float64(*actual.Weight) != *edit.Weight) {
+			return false
+		}
+		for _, server := range edit.JoinServers {
+			if servers[server.UUID] != edit.UUID {
+				return false
+			}
+		}
+	}
 
-// WithTopologyEndpoint .
-func WithTopologyEndpoint(url string) Option {
-	return func(s *BuiltInTopologyService) {
-		s.serviceHost = url
+	for _, server := range patch.Servers {
+		actual, ok := serverData[server.UUID]
+		if ok && server.Expelled {
+			return false
+		}
+		if !server.Expelled && (!ok || !placementApplied(actual, server)) {
+			return false
+		}
 	}
+
+	return true
 }
 
-// WithClusterID .
-func WithClusterID(id string) Option {
-	return func(s *BuiltInTopologyService) {
-		s.clusterID = id
+// isBootstrapped reports whether vshard buckets are already distributed among the storages
+func (s *BuiltInTopologyService) isBootstrapped(ctx context.Context) bool {
+	stats, err := s.GetServerStat(ctx)
+	if err != nil {
+		return false
 	}
+
+	for _, stat := range stats.Stats {
+		if stat.Statistics.BucketsCount > 0 {
+			return true
+		}
+	}
+
+	return false
 }
 
 // NewBuiltInTopologyService .
 func NewBuiltInTopologyService(opts ...Option) *BuiltInTopologyService {
-	s := &BuiltInTopologyService{}
-	for _, opt := range opts {
-		opt(s)
+	o := NewOptions(opts...)
+
+	return &BuiltInTopologyService{
+		serviceHost:       o.Endpoint,
+		clusterID:         o.ClusterID,
+		clusterDomainName: o.ClusterDomainName,
+		binaryPort:        o.BinaryPort,
+		vshardGroups:      o.VshardGroups,
+		httpClient:        newHTTPClient(o),
+		maxRetries:        o.MaxRetries,
+		retryBackoff:      o.RetryBackoff,
 	}
+}
 
-	return s
+// BuiltInFactory is a Factory creating TopologyService talking to the Cartridge GraphQL admin API
+func BuiltInFactory(opts ...Option) TopologyService {
+	return NewBuiltInTopologyService(opts...)
 }
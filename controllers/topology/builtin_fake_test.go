@@ -0,0 +1,462 @@
+package topology_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/tarantool/tarantool-operator/controllers/topology"
+	helpers "github.com/tarantool/tarantool-operator/test/helpers"
+)
+
+func newFakePod(name, instanceUUID, replicasetUUID, roles string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "default",
+			Labels: map[string]string{
+				"tarantool.io/instance-uuid":   instanceUUID,
+				"tarantool.io/replicaset-uuid": replicasetUUID,
+				"tarantool.io/useVshardGroups": "0",
+			},
+			Annotations: map[string]string{
+				"tarantool.io/rolesToAssign": roles,
+			},
+		},
+	}
+}
+
+func newFakeClient(fake *helpers.FakeCartridge) topology.TopologyService {
+	return fake.Factory()(
+		topology.WithClusterID("test"),
+		topology.WithClusterDomainName("cluster.local"),
+		topology.WithBinaryPort(3301),
+	)
+}
+
+func TestFakeCartridge_Lifecycle(t *testing.T) {
+	ctx := context.Background()
+	fake := helpers.NewFakeCartridge()
+	defer fake.Close()
+	client := newFakeClient(fake)
+
+	router := newFakePod("router-0-0", "r0", "rs-router", "[\"router\"]")
+	storageA := newFakePod("storage-0-0", "s0", "rs-storage-0", "[\"vshard-storage\"]")
+	storageB := newFakePod("storage-1-0", "s1", "rs-storage-1", "[\"vshard-storage\"]")
+	replicaA := newFakePod("storage-0-1", "s0-replica", "rs-storage-0", "[\"vshard-storage\"]")
+
+	for _, pod := range []*corev1.Pod{router, storageA, storageB, replicaA} {
+		if err := client.Join(ctx, pod); err != nil {
+			t.Fatalf("failed to join %s: %s", pod.GetName(), err)
+		}
+	}
+
+	if err := client.Join(ctx, router); !topology.IsAlreadyJoined(err) {
+		t.Fatalf("expected already joined error, got %v", err)
+	}
+
+	server, ok := fake.Server("s0")
+	if !ok || server.URI != "storage-0-0.test.default.svc.cluster.local:3301" {
+		t.Fatalf("unexpected server %+v", server)
+	}
+
+	if err := client.BootstrapVshard(ctx); err != nil {
+		t.Fatalf("failed to bootstrap vshard: %s", err)
+	}
+	if err := client.BootstrapVshard(ctx); !topology.IsAlreadyBootstrapped(err) {
+		t.Fatalf("expected already bootstrapped error, got %v", err)
+	}
+
+	stats, err := client.GetServerStat(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buckets := map[string]int{}
+	for _, stat := range stats.Stats {
+		buckets[stat.UUID] = stat.Statistics.BucketsCount
+	}
+	if buckets["s0"] != helpers.DefaultFakeBucketCount/2 || buckets["s1"] != helpers.DefaultFakeBucketCount/2 || buckets["r0"] != 0 {
+		t.Fatalf("unexpected bucket distribution %v", buckets)
+	}
+	if buckets["s0-replica"] != buckets["s0"] {
+		t.Fatalf("replica must report the buckets of its master, got %v", buckets)
+	}
+
+	if err := client.SetWeight(ctx, "rs-storage-1", "0"); err != nil {
+		t.Fatal(err)
+	}
+	weight, err := client.GetWeight(ctx, "rs-storage-1")
+	if err != nil || weight != 0 {
+		t.Fatalf("unexpected weight %d: %v", weight, err)
+	}
+	if server, _ := fake.Server("s1"); server.BucketsCount != 0 {
+		t.Fatalf("drained replicaset still stores %d buckets", server.BucketsCount)
+	}
+
+	if weight, err := client.GetWeight(ctx, "rs-router"); err != nil || weight != -1 {
+		t.Fatalf("router must have no weight, got %d: %v", weight, err)
+	}
+
+	if err := client.SetReplicasetRoles(ctx, "rs-router", []string{"router", "api"}); err != nil {
+		t.Fatal(err)
+	}
+	roles, err := client.GetReplicasetRolesFromService(ctx, "rs-router")
+	if err != nil || len(roles) != 2 {
+		t.Fatalf("unexpected roles %v: %v", roles, err)
+	}
+
+	if err := client.SetFailoverParams(ctx, &topology.FailoverParams{Mode: topology.FailoverModeEventual}); err != nil || !fake.IsFailoverEnabled() {
+		t.Fatalf("failover was not enabled: %v", err)
+	}
+
+	replicasets, err := client.GetReplicasets(ctx)
+	if err != nil || len(replicasets) != 3 {
+		t.Fatalf("unexpected replicasets %v: %v", replicasets, err)
+	}
+}
+
+func TestFakeCartridge_FailoverPriority(t *testing.T) {
+	ctx := context.Background()
+	fake := helpers.NewFakeCartridge()
+	defer fake.Close()
+	client := newFakeClient(fake)
+
+	for _, pod := range []*corev1.Pod{
+		newFakePod("storage-0-0", "s0", "rs-storage-0", "[\"vshard-storage\"]"),
+		newFakePod("storage-0-1", "s1", "rs-storage-0", "[\"vshard-storage\"]"),
+	} {
+		if err := client.Join(ctx, pod); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := client.SetFailoverPriority(ctx, "rs-storage-0", []string{"s1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	replicasets, err := client.GetReplicasets(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replicasets[0].Master == nil || replicasets[0].Master.UUID != "s1" {
+		t.Fatalf("master was not switched: %+v", replicasets[0].Master)
+	}
+}
+
+func TestFakeCartridge_FailoverParams(t *testing.T) {
+	ctx := context.Background()
+	fake := helpers.NewFakeCartridge()
+	defer fake.Close()
+	client := newFakeClient(fake)
+
+	desired := &topology.FailoverParams{
+		Mode:           topology.FailoverModeStateful,
+		StateProvider:  topology.StateProviderTarantool,
+		FencingEnabled: true,
+		FencingTimeout: 5,
+		TarantoolParams: &topology.TarantoolProviderParams{
+			URI:      "stateboard:4401",
+			Password: "secret",
+		},
+	}
+	if err := client.SetFailoverParams(ctx, desired); err != nil {
+		t.Fatal(err)
+	}
+
+	actual, err := client.GetFailoverParams(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !topology.FailoverParamsApplied(desired, actual) || actual.FailoverTimeout != 20 {
+		t.Fatalf("unexpected failover params %+v", actual)
+	}
+
+	err = client.SetFailoverParams(ctx, &topology.FailoverParams{Mode: topology.FailoverModeStateful, StateProvider: topology.StateProviderEtcd2})
+	if err == nil || topology.IsRetryable(err) {
+		t.Fatalf("expected stateful failover without etcd2 params to be refused, got %v", err)
+	}
+	if fake.FailoverParams().StateProvider != topology.StateProviderTarantool {
+		t.Fatalf("refused failover params were applied: %+v", fake.FailoverParams())
+	}
+}
+
+func TestFakeCartridge_Promote(t *testing.T) {
+	ctx := context.Background()
+	fake := helpers.NewFakeCartridge()
+	defer fake.Close()
+	client := newFakeClient(fake)
+
+	for _, pod := range []*corev1.Pod{
+		newFakePod("storage-0-0", "s0", "rs-storage-0", "[\"vshard-storage\"]"),
+		newFakePod("storage-0-1", "s1", "rs-storage-0", "[\"vshard-storage\"]"),
+	} {
+		if err := client.Join(ctx, pod); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err := client.Promote(ctx, "rs-storage-0", "s1")
+	if err == nil || topology.IsRetryable(err) {
+		t.Fatalf("expected promote without stateful failover to be refused, got %v", err)
+	}
+
+	if err := client.SetFailoverParams(ctx, &topology.FailoverParams{
+		Mode:            topology.FailoverModeStateful,
+		StateProvider:   topology.StateProviderTarantool,
+		TarantoolParams: &topology.TarantoolProviderParams{URI: "stateboard:4401"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Promote(ctx, "rs-storage-0", "s1"); err != nil {
+		t.Fatal(err)
+	}
+
+	replicasets, err := client.GetReplicasets(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replicasets[0].ActiveMaster == nil || replicasets[0].ActiveMaster.UUID != "s1" {
+		t.Fatalf("instance was not promoted: %+v", replicasets[0].ActiveMaster)
+	}
+}
+
+func TestFakeCartridge_InjectFault(t *testing.T) {
+	ctx := context.Background()
+	fake := helpers.NewFakeCartridge()
+	defer fake.Close()
+	client := newFakeClient(fake)
+
+	pod := newFakePod("router-0-0", "r0", "rs-router", "[\"router\"]")
+
+	fake.InjectFault(helpers.FakeOpJoin, "This instance isn't bootstrapped yet", 1)
+	if err := client.Join(ctx, pod); !topology.IsTopologyDown(err) {
+		t.Fatalf("expected topology down error, got %v", err)
+	}
+
+	if err := client.Join(ctx, pod); err != nil {
+		t.Fatalf("fault must be cleared after one call: %s", err)
+	}
+
+	fake.InjectFault(helpers.FakeOpReplicasets, "timeout", 0)
+	for i := 0; i < 2; i++ {
+		if _, err := client.GetReplicasets(ctx); err == nil {
+			t.Fatal("expected injected error")
+		}
+	}
+	fake.ClearFaults()
+	if _, err := client.GetReplicasets(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if fake.Calls(helpers.FakeOpJoin) != 2 || fake.Calls(helpers.FakeOpReplicasets) != 3 {
+		t.Fatalf("unexpected call counters")
+	}
+}
+
+func TestFakeCartridge_GetSelf(t *testing.T) {
+	ctx := context.Background()
+	fake := helpers.NewFakeCartridge()
+	defer fake.Close()
+	client := newFakeClient(fake)
+
+	self, err := client.GetSelf(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if self.UUID != "" {
+		t.Fatalf("unconfigured instance reported uuid %s", self.UUID)
+	}
+
+	if err := client.Join(ctx, newFakePod("storage-0-0", "s0", "rs-storage-0", "[\"vshard-storage\"]")); err != nil {
+		t.Fatal(err)
+	}
+
+	self, err = client.GetSelf(ctx)
+	if err != nil || self.UUID != "s0" {
+		t.Fatalf("unexpected self %+v: %v", self, err)
+	}
+}
+
+func TestFakeCartridge_Expel(t *testing.T) {
+	ctx := context.Background()
+	fake := helpers.NewFakeCartridge()
+	defer fake.Close()
+	client := newFakeClient(fake)
+
+	pods := []*corev1.Pod{
+		newFakePod("storage-0-0", "s0", "rs-storage-0", "[\"vshard-storage\"]"),
+		newFakePod("storage-0-1", "s1", "rs-storage-0", "[\"vshard-storage\"]"),
+		newFakePod("storage-0-2", "s2", "rs-storage-0", "[\"vshard-storage\"]"),
+		newFakePod("storage-0-3", "s3", "rs-storage-0", "[\"vshard-storage\"]"),
+	}
+	for _, pod := range pods {
+		if err := client.Join(ctx, pod); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := client.Expel(ctx, pods[2], pods[3]); err != nil {
+		t.Fatalf("batch expel failed: %s", err)
+	}
+	if fake.Calls(helpers.FakeOpEditTopology) != 1 || fake.Calls(helpers.FakeOpExpel) != 0 {
+		t.Fatalf("instances must be expelled with a single edit_topology request")
+	}
+	if _, ok := fake.Server("s2"); ok {
+		t.Fatal("s2 was not expelled")
+	}
+	if err := client.Join(ctx, pods[2]); err == nil {
+		t.Fatal("an expelled instance must not join again")
+	}
+
+	noUUID := newFakePod("storage-0-4", "", "rs-storage-0", "[\"vshard-storage\"]")
+	delete(noUUID.Labels, "tarantool.io/instance-uuid")
+
+	err := client.Expel(ctx, pods[0], pods[1], noUUID)
+	var expelErr *topology.ExpelError
+	if !errors.As(err, &expelErr) {
+		t.Fatalf("expected *ExpelError, got %v", err)
+	}
+	if len(expelErr.Failed) != 2 || expelErr.Failed["storage-0-0"] == nil || !errors.Is(expelErr.Failed["storage-0-4"], topology.ErrNotFound) {
+		t.Fatalf("unexpected failures %v", expelErr.Failed)
+	}
+	if _, ok := fake.Server("s1"); ok {
+		t.Fatal("s1 must be expelled one by one after the batch was refused")
+	}
+	if _, ok := fake.Server("s0"); !ok {
+		t.Fatal("replicaset master must not be expelled")
+	}
+}
+
+func TestFakeCartridge_ExpelWithoutEditTopology(t *testing.T) {
+	ctx := context.Background()
+	fake := helpers.NewFakeCartridge()
+	defer fake.Close()
+	client := newFakeClient(fake)
+
+	router := newFakePod("router-0-0", "r0", "rs-router", "[\"router\"]")
+	if err := client.Join(ctx, router); err != nil {
+		t.Fatal(err)
+	}
+
+	fake.InjectFault(helpers.FakeOpEditTopology, "Cannot query field \"edit_topology\" on type \"MutationApiCluster\"", 0)
+	if err := client.Expel(ctx, router); err != nil {
+		t.Fatalf("expel must fall back to expel_server: %s", err)
+	}
+	if _, ok := fake.Server("r0"); ok || fake.Calls(helpers.FakeOpExpel) != 1 {
+		t.Fatal("r0 was not expelled")
+	}
+}
+
+func TestFakeCartridge_EditTopology(t *testing.T) {
+	ctx := context.Background()
+	fake := helpers.NewFakeCartridge()
+	defer fake.Close()
+	client := newFakeClient(fake)
+
+	if err := client.Join(ctx, newFakePod("storage-0-0", "s0", "rs-storage-0", "[\"vshard-storage\"]")); err != nil {
+		t.Fatal(err)
+	}
+
+	drained := 0.0
+	patch := &topology.TopologyPatch{
+		Replicasets: []*topology.EditReplicasetInput{
+			{
+				UUID:        "rs-storage-0",
+				Weight:      &drained,
+				JoinServers: []*topology.JoinServerInput{{UUID: "s1", URI: "storage-0-1:3301"}},
+			},
+			{
+				UUID:        "rs-router",
+				Alias:       "router-0",
+				Roles:       &[]string{"router"},
+				JoinServers: []*topology.JoinServerInput{{UUID: "r0", URI: "router-0-0:3301"}},
+			},
+		},
+	}
+	if err := client.EditTopology(ctx, patch); err != nil {
+		t.Fatal(err)
+	}
+	if fake.Calls(helpers.FakeOpEditTopology) != 1 || fake.Calls(helpers.FakeOpJoin) != 1 {
+		t.Fatal("topology must be changed with a single edit_topology request")
+	}
+
+	rs, ok := fake.Replicaset("rs-storage-0")
+	if !ok || len(rs.Servers) != 2 || rs.Weight == nil || *rs.Weight != 0 {
+		t.Fatalf("unexpected storage replicaset %+v", rs)
+	}
+	if rs, ok := fake.Replicaset("rs-router"); !ok || rs.Alias != "router-0" {
+		t.Fatalf("router replicaset was not created: %+v", rs)
+	}
+
+	err := client.EditTopology(ctx, &topology.TopologyPatch{
+		Replicasets: []*topology.EditReplicasetInput{
+			{UUID: "rs-storage-1", Roles: &[]string{"vshard-storage"}, JoinServers: []*topology.JoinServerInput{{UUID: "s2", URI: "storage-1-0:3301"}}},
+			{UUID: "rs-router", JoinServers: []*topology.JoinServerInput{{UUID: "s0", URI: "storage-0-0:3301"}}},
+		},
+	})
+	if !topology.IsAlreadyJoined(err) {
+		t.Fatalf("expected already joined error, got %v", err)
+	}
+	if _, ok := fake.Replicaset("rs-storage-1"); ok {
+		t.Fatal("refused patch must not be applied partially")
+	}
+}
+
+func TestFakeCartridge_EditServerPlacement(t *testing.T) {
+	ctx := context.Background()
+	fake := helpers.NewFakeCartridge()
+	defer fake.Close()
+	client := newFakeClient(fake)
+
+	err := client.EditTopology(ctx, &topology.TopologyPatch{
+		Replicasets: []*topology.EditReplicasetInput{{
+			UUID:  "rs-storage-0",
+			Alias: "storage-0",
+			Roles: &[]string{"vshard-storage"},
+			JoinServers: []*topology.JoinServerInput{{
+				UUID:   "s0",
+				URI:    "storage-0-0:3301",
+				Zone:   "zone-a",
+				Labels: []*topology.Label{{Name: "rack", Value: "r1"}},
+			}},
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	desired := []*topology.DesiredReplicaset{{
+		UUID:    "rs-storage-0",
+		Roles:   []string{"vshard-storage"},
+		Servers: []*topology.DesiredServer{{UUID: "s0", Zone: "zone-b", ManagedLabels: []string{"rack"}}},
+	}}
+	replicasets, err := client.GetReplicasets(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if server := replicasets[0].Servers[0]; server.Zone != "zone-a" || len(server.Labels) != 1 {
+		t.Fatalf("instance must join with its zone and labels, got %+v", server)
+	}
+
+	patch := topology.PlanTopology(desired, replicasets)
+	if len(patch.Servers) != 1 {
+		t.Fatalf("unexpected patch %+v", patch)
+	}
+	if err := client.EditTopology(ctx, patch); err != nil {
+		t.Fatal(err)
+	}
+
+	replicasets, err = client.GetReplicasets(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if server := replicasets[0].Servers[0]; server.Zone != "zone-b" || len(server.Labels) != 0 {
+		t.Fatalf("instance must be moved to zone-b without labels, got %+v", server)
+	}
+	if patch := topology.PlanTopology(desired, replicasets); !patch.IsEmpty() {
+		t.Fatalf("expected nothing to change, got %+v", patch)
+	}
+}
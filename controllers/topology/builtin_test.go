@@ -149,3 +149,28 @@ func TestGetRoles_ParseRolesFromAnnotations(t *testing.T) {
 		}
 	}
 }
+
+func TestBuiltInFactory_AppliesOptions(t *testing.T) {
+	var factory Factory = BuiltInFactory
+
+	s, ok := factory(
+		WithTopologyEndpoint("http://10.0.0.1:8081/admin/api"),
+		WithClusterID("examples-kv-cluster"),
+		WithClusterDomainName("cluster.local"),
+		WithBinaryPort(3301),
+		WithVshardGroups([]string{"hot", "cold"}),
+	).(*BuiltInTopologyService)
+	if !ok {
+		t.Fatal("factory must create BuiltInTopologyService")
+	}
+
+	if s.serviceHost != "http://10.0.0.1:8081/admin/api" {
+		t.Errorf("unexpected endpoint %s", s.serviceHost)
+	}
+	if s.clusterID != "examples-kv-cluster" || s.clusterDomainName != "cluster.local" || s.binaryPort != 3301 {
+		t.Errorf("unexpected cluster options %+v", s)
+	}
+	if !Contains(s.vshardGroups, "cold") {
+		t.Errorf("unexpected vshard groups %v", s.vshardGroups)
+	}
+}
@@ -0,0 +1,129 @@
+package topology
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// CachedService is a TopologyService answering the replicasets, server stats and failover params queries
+// from the results of their last calls until the topology is changed through it.
+// It lives for a single reconcile, so every step of the reconcile and the status update share one query.
+type CachedService struct {
+	TopologyService
+
+	replicasets    []*ReplicasetData
+	stats          *ServerStatData
+	failoverParams *FailoverParams
+}
+
+// NewCachedService wraps the service with a cache of topology queries
+func NewCachedService(service TopologyService) *CachedService {
+	return &CachedService{TopologyService: service}
+}
+
+// GetReplicasets returns the replicasets fetched since the last change of the topology
+func (s *CachedService) GetReplicasets(ctx context.Context) ([]*ReplicasetData, error) {
+	if s.replicasets != nil {
+		return s.replicasets, nil
+	}
+
+	replicasets, err := s.TopologyService.GetReplicasets(ctx)
+	if err != nil {
+		return nil, err
+	}
+	s.replicasets = replicasets
+
+	return replicasets, nil
+}
+
+// GetServerStat returns the server stats fetched since the last change of the topology
+func (s *CachedService) GetServerStat(ctx context.Context) (ServerStatData, error) {
+	if s.stats != nil {
+		return *s.stats, nil
+	}
+
+	stats, err := s.TopologyService.GetServerStat(ctx)
+	if err != nil {
+		return stats, err
+	}
+	s.stats = &stats
+
+	return stats, nil
+}
+
+// GetFailoverParams returns the failover params fetched since the last change of the topology
+func (s *CachedService) GetFailoverParams(ctx context.Context) (*FailoverParams, error) {
+	if s.failoverParams != nil {
+		return s.failoverParams, nil
+	}
+
+	params, err := s.TopologyService.GetFailoverParams(ctx)
+	if err != nil {
+		return nil, err
+	}
+	s.failoverParams = params
+
+	return params, nil
+}
+
+// Invalidate drops the cached query results
+func (s *CachedService) Invalidate() {
+	s.replicasets = nil
+	s.stats = nil
+	s.failoverParams = nil
+}
+
+// Join drops the cached queries, the topology may have changed even if the call failed
+func (s *CachedService) Join(ctx context.Context, p *corev1.Pod) error {
+	defer s.Invalidate()
+	return s.TopologyService.Join(ctx, p)
+}
+
+// Expel drops the cached queries, the topology may have changed even if the call failed
+func (s *CachedService) Expel(ctx context.Context, pods ...*corev1.Pod) error {
+	defer s.Invalidate()
+	return s.TopologyService.Expel(ctx, pods...)
+}
+
+// EditTopology drops the cached queries, the topology may have changed even if the call failed
+func (s *CachedService) EditTopology(ctx context.Context, patch *TopologyPatch) error {
+	defer s.Invalidate()
+	return s.TopologyService.EditTopology(ctx, patch)
+}
+
+// SetWeight drops the cached queries, the topology may have changed even if the call failed
+func (s *CachedService) SetWeight(ctx context.Context, replicasetUUID string, replicaWeight string) error {
+	defer s.Invalidate()
+	return s.TopologyService.SetWeight(ctx, replicasetUUID, replicaWeight)
+}
+
+// SetReplicasetRoles drops the cached queries, the topology may have changed even if the call failed
+func (s *CachedService) SetReplicasetRoles(ctx context.Context, replicasetUUID string, roles []string) error {
+	defer s.Invalidate()
+	return s.TopologyService.SetReplicasetRoles(ctx, replicasetUUID, roles)
+}
+
+// SetFailoverPriority drops the cached queries, the topology may have changed even if the call failed
+func (s *CachedService) SetFailoverPriority(ctx context.Context, replicasetUUID string, priority []string) error {
+	defer s.Invalidate()
+	return s.TopologyService.SetFailoverPriority(ctx, replicasetUUID, priority)
+}
+
+// SetFailoverParams drops the cached queries, masters may have changed even if the call failed
+func (s *CachedService) SetFailoverParams(ctx context.Context, params *FailoverParams) error {
+	defer s.Invalidate()
+	return s.TopologyService.SetFailoverParams(ctx, params)
+}
+
+// Promote drops the cached queries, the master may have changed even if the call failed
+func (s *CachedService) Promote(ctx context.Context, replicasetUUID, instanceUUID string) error {
+	defer s.Invalidate()
+	return s.TopologyService.Promote(ctx, replicasetUUID, instanceUUID)
+}
+
+// BootstrapVshard drops the cached queries, the topology may have changed even if the call failed
+func (s *CachedService) BootstrapVshard(ctx context.Context) error {
+	defer s.Invalidate()
+	return s.TopologyService.BootstrapVshard(ctx)
+}
@@ -0,0 +1,65 @@
+package topology_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/tarantool/tarantool-operator/controllers/topology"
+	helpers "github.com/tarantool/tarantool-operator/test/helpers"
+)
+
+func TestCachedService_ReusesQueriesUntilTopologyChanges(t *testing.T) {
+	ctx := context.Background()
+	fake := helpers.NewFakeCartridge()
+	defer fake.Close()
+	client := topology.NewCachedService(newFakeClient(fake))
+
+	for i := 0; i < 3; i++ {
+		if _, err := client.GetReplicasets(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := client.GetServerStat(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if fake.Calls(helpers.FakeOpReplicasets) != 1 || fake.Calls(helpers.FakeOpServers) != 1 {
+		t.Fatalf("expected a single query of each kind, got %d replicasets and %d stats queries",
+			fake.Calls(helpers.FakeOpReplicasets), fake.Calls(helpers.FakeOpServers))
+	}
+
+	if err := client.Join(ctx, newFakePod("storage-0-0", "s0", "rs-storage-0", "[\"vshard-storage\"]")); err != nil {
+		t.Fatal(err)
+	}
+	calls := fake.Calls(helpers.FakeOpReplicasets)
+
+	replicasets, err := client.GetReplicasets(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fake.Calls(helpers.FakeOpReplicasets) != calls+1 || len(replicasets) != 1 {
+		t.Fatalf("expected the replicasets to be queried again after a join, got %v", replicasets)
+	}
+}
+
+func TestCachedService_RefetchesFailoverParamsAfterChange(t *testing.T) {
+	ctx := context.Background()
+	fake := helpers.NewFakeCartridge()
+	defer fake.Close()
+	client := topology.NewCachedService(newFakeClient(fake))
+
+	for i := 0; i < 2; i++ {
+		if params, err := client.GetFailoverParams(ctx); err != nil || params.Mode != topology.FailoverModeDisabled {
+			t.Fatalf("unexpected failover params %+v: %v", params, err)
+		}
+	}
+	if fake.Calls(helpers.FakeOpFailoverParams) != 1 {
+		t.Fatalf("expected a single failover params query, got %d", fake.Calls(helpers.FakeOpFailoverParams))
+	}
+
+	if err := client.SetFailoverParams(ctx, &topology.FailoverParams{Mode: topology.FailoverModeEventual}); err != nil {
+		t.Fatal(err)
+	}
+	if params, err := client.GetFailoverParams(ctx); err != nil || params.Mode != topology.FailoverModeEventual {
+		t.Fatalf("expected the changed failover params, got %+v: %v", params, err)
+	}
+}
@@ -0,0 +1,216 @@
+package topology
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// Kinds of topology errors, match them with errors.Is
+var (
+	// ErrTopologyDown means the instance serving the admin API is not bootstrapped yet
+	ErrTopologyDown = errors.New("topology service is down")
+	// ErrAlreadyJoined means the instance is already a member of the topology
+	ErrAlreadyJoined = errors.New("already joined")
+	// ErrAlreadyBootstrapped means vshard was bootstrapped before
+	ErrAlreadyBootstrapped = errors.New("already bootstrapped")
+	// ErrUnauthorized means the admin API rejected the operator credentials
+	ErrUnauthorized = errors.New("unauthorized")
+	// ErrNotFound means the replicaset or the instance the operation refers to does not exist
+	ErrNotFound = errors.New("not found")
+	// ErrUnavailable means the admin API could not be reached or is overloaded
+	ErrUnavailable = errors.New("admin API unavailable")
+)
+
+// classNameExtension is the GraphQL error extension Cartridge reports the error class in
+const classNameExtension = "io.tarantool.errors.class_name"
+
+// Error is a failed topology operation
+type Error struct {
+	// Op is the topology operation, e.g. join or bootstrap_vshard
+	Op string
+	// Class is the Cartridge error class, empty when Cartridge did not answer
+	Class string
+	// Message describes the failure
+	Message string
+	// StatusCode is the HTTP status of the admin API response, 0 when no response was received
+	StatusCode int
+	// Retryable reports whether repeating the operation soon may succeed
+	Retryable bool
+
+	kind error
+	err  error
+	// refused is set when Cartridge answered the request with an error
+	refused bool
+}
+
+// Error implements error
+func (e *Error) Error() string {
+	if e.Class != "" {
+		return fmt.Sprintf("%s: %s: %s", e.Op, e.Class, e.Message)
+	}
+
+	return fmt.Sprintf("%s: %s", e.Op, e.Message)
+}
+
+// Unwrap returns the transport error the operation failed with
+func (e *Error) Unwrap() error {
+	return e.err
+}
+
+// Is reports whether the error is of the target kind
+func (e *Error) Is(target error) bool {
+	return e.kind != nil && e.kind == target
+}
+
+// ExpelError reports the instances an expel failed for, the other instances were expelled
+type ExpelError struct {
+	// Failed maps the name of a pod to the reason its instance was not expelled
+	Failed map[string]error
+}
+
+// Error implements error
+func (e *ExpelError) Error() string {
+	failures := make([]string, 0, len(e.Failed))
+	for _, name := range e.pods() {
+		failures = append(failures, fmt.Sprintf("%s: %s", name, e.Failed[name]))
+	}
+
+	return fmt.Sprintf("failed to expel %d instances: %s", len(e.Failed), strings.Join(failures, "; "))
+}
+
+// Unwrap returns the failure of the first pod by name, so the error can be matched as a single one
+func (e *ExpelError) Unwrap() error {
+	if pods := e.pods(); len(pods) > 0 {
+		return e.Failed[pods[0]]
+	}
+
+	return nil
+}
+
+func (e *ExpelError) pods() []string {
+	pods := make([]string, 0, len(e.Failed))
+	for name := range e.Failed {
+		pods = append(pods, name)
+	}
+	sort.Strings(pods)
+
+	return pods
+}
+
+// newResponseError builds the error of an operation Cartridge refused.
+// Cartridge reports most topology failures under a few broad error classes and its messages are not stable,
+// so the kind of a refusal is not guessed from the response: the operation checks the topology instead,
+// see BuiltInTopologyService.classifyRefusal.
+func newResponseError(op string, statusCode int, respErr *ResponseError) *Error {
+	e := &Error{
+		Op:         op,
+		Message:    respErr.Message,
+		StatusCode: statusCode,
+		refused:    true,
+	}
+	if class, ok := respErr.Extensions[classNameExtension].(string); ok {
+		e.Class = class
+	}
+
+	return e
+}
+
+// newStatusError builds the error of an operation the admin API answered with a failed HTTP status
+func newStatusError(op string, statusCode int, status string) *Error {
+	e := &Error{
+		Op:         op,
+		Message:    fmt.Sprintf("admin API responded with %s", status),
+		StatusCode: statusCode,
+	}
+
+	switch statusCode {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		e.kind = ErrUnauthorized
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		e.kind = ErrUnavailable
+		e.Retryable = true
+	}
+
+	return e
+}
+
+// newTransportError builds the error of an operation which got no answer
+func newTransportError(op string, err error, retryable bool) *Error {
+	return &Error{
+		Op:        op,
+		Message:   err.Error(),
+		Retryable: retryable,
+		kind:      ErrUnavailable,
+		err:       err,
+	}
+}
+
+// newOpError builds the error of an operation Cartridge did not confirm or which refers to missing objects
+func newOpError(op string, kind error, format string, args ...interface{}) *Error {
+	return &Error{
+		Op:      op,
+		Message: fmt.Sprintf(format, args...),
+		kind:    kind,
+	}
+}
+
+// isRefused reports whether Cartridge answered the operation with an error the kind of which is not known yet
+func isRefused(err error) bool {
+	var topologyErr *Error
+	return errors.As(err, &topologyErr) && topologyErr.refused && topologyErr.kind == nil
+}
+
+// withKind returns a copy of the topology error classified as kind
+func withKind(err error, kind error) error {
+	var topologyErr *Error
+	if !errors.As(err, &topologyErr) {
+		return err
+	}
+
+	classified := *topologyErr
+	classified.kind = kind
+	return &classified
+}
+
+// isUndelivered reports whether the request failed before it reached the admin API,
+// so even an operation which is not idempotent may be sent again
+func isUndelivered(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// IsRetryable reports whether the operation failed for a reason that may go away by itself,
+// an expel is retryable only if all of its instances failed for such a reason
+func IsRetryable(err error) bool {
+	var expelErr *ExpelError
+	if errors.As(err, &expelErr) {
+		for _, instanceErr := range expelErr.Failed {
+			if !IsRetryable(instanceErr) {
+				return false
+			}
+		}
+		return len(expelErr.Failed) > 0
+	}
+
+	var topologyErr *Error
+	return errors.As(err, &topologyErr) && topologyErr.Retryable
+}
+
+// IsTopologyDown .
+func IsTopologyDown(err error) bool {
+	return errors.Is(err, ErrTopologyDown)
+}
+
+// IsAlreadyJoined .
+func IsAlreadyJoined(err error) bool {
+	return errors.Is(err, ErrAlreadyJoined)
+}
+
+// IsAlreadyBootstrapped .
+func IsAlreadyBootstrapped(err error) bool {
+	return errors.Is(err, ErrAlreadyBootstrapped)
+}
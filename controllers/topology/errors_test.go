@@ -0,0 +1,76 @@
+package topology
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestError_CarriesCartridgeDetails(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"errors":[{"message":"Sharding config is already bootstrapped","extensions":{"io.tarantool.errors.class_name":"BootstrapError"}}]}`)
+	}))
+	defer server.Close()
+
+	err := NewBuiltInTopologyService(WithTopologyEndpoint(server.URL)).BootstrapVshard(context.Background())
+
+	var topologyErr *Error
+	if !errors.As(err, &topologyErr) {
+		t.Fatalf("expected *Error, got %T", err)
+	}
+	if topologyErr.Op != "bootstrap_vshard" || topologyErr.Class != "BootstrapError" || topologyErr.StatusCode != http.StatusOK || topologyErr.Retryable {
+		t.Errorf("unexpected error details %+v", topologyErr)
+	}
+	if errors.Is(err, ErrAlreadyBootstrapped) || errors.Is(err, ErrAlreadyJoined) {
+		t.Errorf("the kind of %v must not be guessed from the message", err)
+	}
+}
+
+func TestError_ClassifiesHTTPFailures(t *testing.T) {
+	status := http.StatusUnauthorized
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+
+	s := NewBuiltInTopologyService(WithTopologyEndpoint(server.URL), WithRetries(0, time.Millisecond))
+
+	_, err := s.GetReplicasets(context.Background())
+	if !errors.Is(err, ErrUnauthorized) || IsRetryable(err) {
+		t.Errorf("expected non retryable unauthorized error, got %v", err)
+	}
+
+	status = http.StatusServiceUnavailable
+	_, err = s.GetReplicasets(context.Background())
+	if !errors.Is(err, ErrUnavailable) || !IsRetryable(err) {
+		t.Errorf("expected retryable unavailable error, got %v", err)
+	}
+
+	server.Close()
+	_, err = s.GetReplicasets(context.Background())
+	var topologyErr *Error
+	if !errors.As(err, &topologyErr) || topologyErr.StatusCode != 0 || !topologyErr.Retryable || errors.Unwrap(err) == nil {
+		t.Errorf("expected retryable transport error, got %#v", err)
+	}
+}
+
+func TestExpelError_RetryableOnlyIfAllFailuresAre(t *testing.T) {
+	unavailable := newStatusError("expel_server", http.StatusServiceUnavailable, "503 Service Unavailable")
+	refused := newOpError("expel_server", nil, "instance was not expelled")
+
+	err := fmt.Errorf("scale down: %w", &ExpelError{Failed: map[string]error{"storage-0-1": unavailable}})
+	if !IsRetryable(err) || !errors.Is(err, ErrUnavailable) {
+		t.Errorf("expected retryable unavailable error, got %v", err)
+	}
+
+	err = &ExpelError{Failed: map[string]error{"storage-0-1": unavailable, "storage-0-2": refused}}
+	if IsRetryable(err) {
+		t.Errorf("expel with a refused instance must not be retryable: %v", err)
+	}
+	if err.Error() != "failed to expel 2 instances: storage-0-1: expel_server: admin API responded with 503 Service Unavailable; storage-0-2: expel_server: instance was not expelled" {
+		t.Errorf("unexpected message %q", err.Error())
+	}
+}
@@ -0,0 +1,196 @@
+package topology
+
+import (
+	"context"
+	"reflect"
+)
+
+// Cartridge failover modes
+const (
+	FailoverModeDisabled = "disabled"
+	FailoverModeEventual = "eventual"
+	FailoverModeStateful = "stateful"
+)
+
+// Cartridge state providers of the stateful failover
+const (
+	StateProviderTarantool = "tarantool"
+	StateProviderEtcd2     = "etcd2"
+)
+
+// FailoverParams are the Cartridge failover settings, zero timeouts are left as Cartridge has them
+type FailoverParams struct {
+	Mode            string                   `json:"mode"`
+	StateProvider   string                   `json:"state_provider,omitempty"`
+	FailoverTimeout float64                  `json:"failover_timeout,omitempty"`
+	FencingEnabled  bool                     `json:"fencing_enabled"`
+	FencingTimeout  float64                  `json:"fencing_timeout,omitempty"`
+	FencingPause    float64                  `json:"fencing_pause,omitempty"`
+	TarantoolParams *TarantoolProviderParams `json:"tarantool_params,omitempty"`
+	Etcd2Params     *Etcd2ProviderParams     `json:"etcd2_params,omitempty"`
+}
+
+// TarantoolProviderParams point the stateful failover to a stateboard
+type TarantoolProviderParams struct {
+	URI      string `json:"uri"`
+	Password string `json:"password"`
+}
+
+// Etcd2ProviderParams point the stateful failover to an etcd cluster
+type Etcd2ProviderParams struct {
+	Endpoints []string `json:"endpoints,omitempty"`
+	Prefix    string   `json:"prefix,omitempty"`
+	LockDelay float64  `json:"lock_delay,omitempty"`
+	Username  string   `json:"username,omitempty"`
+	Password  string   `json:"password,omitempty"`
+}
+
+// FailoverParamsQueryResponse .
+type FailoverParamsQueryResponse struct {
+	Cluster struct {
+		FailoverParams *FailoverParams `json:"failover_params"`
+	} `json:"cluster"`
+}
+
+var getFailoverParamsQuery = `query getFailoverParams {
+	cluster {
+		failover_params {
+			mode
+			state_provider
+			failover_timeout
+			fencing_enabled
+			fencing_timeout
+			fencing_pause
+			tarantool_params { uri password }
+			etcd2_params { endpoints prefix lock_delay username password }
+		}
+	}
+}`
+
+var setFailoverParamsMutation = `mutation setFailoverParams(
+		$mode: String,
+		$state_provider: String,
+		$failover_timeout: Float,
+		$fencing_enabled: Boolean,
+		$fencing_timeout: Float,
+		$fencing_pause: Float,
+		$tarantool_params: FailoverStateProviderCfgInputTarantool,
+		$etcd2_params: FailoverStateProviderCfgInputEtcd2
+	) {
+	cluster {
+		failover_params(
+			mode: $mode,
+			state_provider: $state_provider,
+			failover_timeout: $failover_timeout,
+			fencing_enabled: $fencing_enabled,
+			fencing_timeout: $fencing_timeout,
+			fencing_pause: $fencing_pause,
+			tarantool_params: $tarantool_params,
+			etcd2_params: $etcd2_params
+		) { mode }
+	}
+}`
+
+var promoteMutation = `mutation promote($replicaset_uuid: String!, $instance_uuid: String!) {
+	cluster {
+		failover_promote(replicaset_uuid: $replicaset_uuid, instance_uuid: $instance_uuid)
+	}
+}`
+
+// GetFailoverParams fetches the failover settings of the cluster
+func (s *BuiltInTopologyService) GetFailoverParams(ctx context.Context) (*FailoverParams, error) {
+	resp := &FailoverParamsQueryResponse{}
+	if err := s.run(ctx, "failover_params", getFailoverParamsQuery, nil, resp); err != nil {
+		return nil, err
+	}
+	if resp.Cluster.FailoverParams == nil {
+		return nil, newOpError("failover_params", nil, "cluster reported no failover params")
+	}
+
+	return resp.Cluster.FailoverParams, nil
+}
+
+// SetFailoverParams changes the failover settings of the cluster, the settings missing from params are kept
+func (s *BuiltInTopologyService) SetFailoverParams(ctx context.Context, params *FailoverParams) error {
+	vars := map[string]interface{}{
+		"mode":            params.Mode,
+		"fencing_enabled": params.FencingEnabled,
+	}
+	if params.StateProvider != "" {
+		vars["state_provider"] = params.StateProvider
+	}
+	if params.FailoverTimeout > 0 {
+		vars["failover_timeout"] = params.FailoverTimeout
+	}
+	if params.FencingTimeout > 0 {
+		vars["fencing_timeout"] = params.FencingTimeout
+	}
+	if params.FencingPause > 0 {
+		vars["fencing_pause"] = params.FencingPause
+	}
+	if params.TarantoolParams != nil {
+		vars["tarantool_params"] = params.TarantoolParams
+	}
+	if params.Etcd2Params != nil {
+		vars["etcd2_params"] = params.Etcd2Params
+	}
+
+	log.Info("setting failover params", "mode", params.Mode, "state_provider", params.StateProvider)
+
+	return s.run(ctx, "failover_params", setFailoverParamsMutation, vars, nil)
+}
+
+// Promote makes the instance the leader of its replicaset, it only works with the stateful failover
+func (s *BuiltInTopologyService) Promote(ctx context.Context, replicasetUUID, instanceUUID string) error {
+	log.Info("promoting instance", "replicaset_uuid", replicasetUUID, "instance_uuid", instanceUUID)
+
+	return s.run(ctx, "failover_promote", promoteMutation, map[string]interface{}{
+		"replicaset_uuid": replicasetUUID,
+		"instance_uuid":   instanceUUID,
+	}, nil)
+}
+
+// FailoverParamsApplied reports whether the actual settings match the desired ones,
+// the settings the desired params leave to Cartridge are not compared
+func FailoverParamsApplied(desired, actual *FailoverParams) bool {
+	if actual == nil {
+		return false
+	}
+	if desired.Mode != actual.Mode || desired.FencingEnabled != actual.FencingEnabled {
+		return false
+	}
+	if desired.FailoverTimeout > 0 && desired.FailoverTimeout != actual.FailoverTimeout {
+		return false
+	}
+	if desired.FencingTimeout > 0 && desired.FencingTimeout != actual.FencingTimeout {
+		return false
+	}
+	if desired.FencingPause > 0 && desired.FencingPause != actual.FencingPause {
+		return false
+	}
+	if desired.Mode != FailoverModeStateful {
+		return true
+	}
+
+	if desired.StateProvider != actual.StateProvider {
+		return false
+	}
+	switch desired.StateProvider {
+	case StateProviderTarantool:
+		return reflect.DeepEqual(desired.TarantoolParams, actual.TarantoolParams)
+	case StateProviderEtcd2:
+		if desired.Etcd2Params == nil || actual.Etcd2Params == nil {
+			return desired.Etcd2Params == actual.Etcd2Params
+		}
+		expected := *desired.Etcd2Params
+		if expected.Prefix == "" {
+			expected.Prefix = actual.Etcd2Params.Prefix
+		}
+		if expected.LockDelay == 0 {
+			expected.LockDelay = actual.Etcd2Params.LockDelay
+		}
+		return reflect.DeepEqual(&expected, actual.Etcd2Params)
+	}
+
+	return true
+}
@@ -0,0 +1,88 @@
+package topology
+
+import (
+	"testing"
+)
+
+func TestFailoverParamsApplied(t *testing.T) {
+	actual := &FailoverParams{
+		Mode:            FailoverModeStateful,
+		StateProvider:   StateProviderEtcd2,
+		FailoverTimeout: 20,
+		FencingEnabled:  true,
+		FencingTimeout:  10,
+		FencingPause:    2,
+		Etcd2Params: &Etcd2ProviderParams{
+			Endpoints: []string{"http://etcd:2379"},
+			Prefix:    "/",
+			LockDelay: 10,
+		},
+	}
+
+	cases := []struct {
+		name    string
+		desired *FailoverParams
+		applied bool
+	}{
+		{
+			name: "defaults are left to Cartridge",
+			desired: &FailoverParams{
+				Mode:           FailoverModeStateful,
+				StateProvider:  StateProviderEtcd2,
+				FencingEnabled: true,
+				Etcd2Params:    &Etcd2ProviderParams{Endpoints: []string{"http://etcd:2379"}},
+			},
+			applied: true,
+		},
+		{
+			name:    "mode drifted",
+			desired: &FailoverParams{Mode: FailoverModeEventual, FencingEnabled: true},
+		},
+		{
+			name: "timeout drifted",
+			desired: &FailoverParams{
+				Mode:            FailoverModeStateful,
+				StateProvider:   StateProviderEtcd2,
+				FailoverTimeout: 30,
+				FencingEnabled:  true,
+				Etcd2Params:     &Etcd2ProviderParams{Endpoints: []string{"http://etcd:2379"}},
+			},
+		},
+		{
+			name: "fencing disabled",
+			desired: &FailoverParams{
+				Mode:          FailoverModeStateful,
+				StateProvider: StateProviderEtcd2,
+				Etcd2Params:   &Etcd2ProviderParams{Endpoints: []string{"http://etcd:2379"}},
+			},
+		},
+		{
+			name: "endpoints changed",
+			desired: &FailoverParams{
+				Mode:           FailoverModeStateful,
+				StateProvider:  StateProviderEtcd2,
+				FencingEnabled: true,
+				Etcd2Params:    &Etcd2ProviderParams{Endpoints: []string{"http://etcd-0:2379", "http://etcd-1:2379"}},
+			},
+		},
+		{
+			name: "state provider changed",
+			desired: &FailoverParams{
+				Mode:            FailoverModeStateful,
+				StateProvider:   StateProviderTarantool,
+				FencingEnabled:  true,
+				TarantoolParams: &TarantoolProviderParams{URI: "stateboard:4401", Password: "secret"},
+			},
+		},
+	}
+
+	for _, c := range cases {
+		if applied := FailoverParamsApplied(c.desired, actual); applied != c.applied {
+			t.Errorf("%s: expected applied %v, got %v", c.name, c.applied, applied)
+		}
+	}
+
+	if FailoverParamsApplied(&FailoverParams{Mode: FailoverModeDisabled}, nil) {
+		t.Errorf("unknown failover params must not be reported applied")
+	}
+}
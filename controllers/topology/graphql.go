@@ -0,0 +1,116 @@
+package topology
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// graphQLRequest is the body of an admin API request
+type graphQLRequest struct {
+	Query     string                 `json:"query"`
+	Variables map[string]interface{} `json:"variables,omitempty"`
+}
+
+// graphQLResponse is the body of an admin API response
+type graphQLResponse struct {
+	Data   json.RawMessage  `json:"data,omitempty"`
+	Errors []*ResponseError `json:"errors,omitempty"`
+}
+
+// nonIdempotentOps are the mutations which must not be sent twice: when the first request was applied
+// but its answer was lost, a repeated one fails or changes the topology again
+var nonIdempotentOps = map[string]bool{
+	"join_server":   true,
+	"edit_topology": true,
+	"expel_server":  true,
+}
+
+// run sends a GraphQL request to the admin API and decodes its data into out.
+// Transient failures are retried with exponential backoff until the retries or the context run out.
+// Operations which are not idempotent are retried only if the request did not reach the admin API,
+// their callers check the topology to find out whether a failed request was applied.
+func (s *BuiltInTopologyService) run(ctx context.Context, op string, query string, vars map[string]interface{}, out interface{}) error {
+	backoff := s.retryBackoff
+	for attempt := 0; ; attempt++ {
+		err := s.do(ctx, op, query, vars, out)
+		if isRefused(err) && op != "self" && s.isUnconfigured(ctx) {
+			return withKind(err, ErrTopologyDown)
+		}
+		if err == nil || !IsRetryable(err) || attempt >= s.maxRetries {
+			return err
+		}
+		if nonIdempotentOps[op] && !isUndelivered(err) {
+			return err
+		}
+
+		log.Info("retrying admin API request", "op", op, "endpoint", s.serviceHost, "attempt", attempt+1, "error", err.Error())
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+func (s *BuiltInTopologyService) do(ctx context.Context, op string, query string, vars map[string]interface{}, out interface{}) error {
+	body, err := json.Marshal(&graphQLRequest{Query: query, Variables: vars})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.serviceHost, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	httpResp, err := s.httpClient.Do(req)
+	if err != nil {
+		// a canceled reconcile must not be repeated
+		return newTransportError(op, err, ctx.Err() == nil)
+	}
+	defer httpResp.Body.Close()
+
+	switch httpResp.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return newStatusError(op, httpResp.StatusCode, httpResp.Status)
+	}
+
+	resp := &graphQLResponse{}
+	if err := json.NewDecoder(httpResp.Body).Decode(resp); err != nil {
+		if httpResp.StatusCode != http.StatusOK {
+			return newStatusError(op, httpResp.StatusCode, httpResp.Status)
+		}
+		return &Error{Op: op, Message: fmt.Sprintf("failed to decode admin API response: %s", err), StatusCode: httpResp.StatusCode, err: err}
+	}
+
+	if len(resp.Errors) > 0 {
+		return newResponseError(op, httpResp.StatusCode, resp.Errors[0])
+	}
+	if httpResp.StatusCode != http.StatusOK {
+		return newStatusError(op, httpResp.StatusCode, httpResp.Status)
+	}
+
+	if out == nil || len(resp.Data) == 0 {
+		return nil
+	}
+
+	if err := json.Unmarshal(resp.Data, out); err != nil {
+		return &Error{Op: op, Message: fmt.Sprintf("failed to decode admin API response: %s", err), StatusCode: httpResp.StatusCode, err: err}
+	}
+
+	return nil
+}
+
+// isUnconfigured reports whether the instance serving the admin API is not a member of the topology yet,
+// such an instance refuses every operation except joining the first instances
+func (s *BuiltInTopologyService) isUnconfigured(ctx context.Context) bool {
+	self, err := s.GetSelf(ctx)
+	return err == nil && self.UUID == ""
+}
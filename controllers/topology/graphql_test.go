@@ -0,0 +1,176 @@
+package topology
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRun_RetriesTransientFailures(t *testing.T) {
+	ctx := context.Background()
+
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprint(w, selfResponse)
+	}))
+	defer server.Close()
+
+	s := NewBuiltInTopologyService(WithTopologyEndpoint(server.URL), WithRetries(3, time.Millisecond))
+	if _, err := s.GetReplicasets(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 3 {
+		t.Fatalf("expected 3 calls, got %d", calls)
+	}
+
+	calls = 0
+	s = NewBuiltInTopologyService(WithTopologyEndpoint(server.URL), WithRetries(1, time.Millisecond))
+	if err := s.BootstrapVshard(ctx); !IsRetryable(err) {
+		t.Fatalf("expected transient error after retries ran out, got %v", err)
+	}
+	if calls != 2 {
+		t.Fatalf("expected 2 calls, got %d", calls)
+	}
+}
+
+func TestRun_ClassifiesCartridgeErrors(t *testing.T) {
+	ctx := context.Background()
+
+	calls := map[string]int{}
+	selfUUID, buckets := "null", 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := &graphQLRequest{}
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			t.Fatal(err)
+		}
+
+		switch {
+		case strings.Contains(req.Query, "self"):
+			fmt.Fprintf(w, `{"data":{"cluster":{"self":{"uri":"localhost:3301","uuid":%s,"alias":""}}}}`, selfUUID)
+		case strings.Contains(req.Query, "serverStat"):
+			fmt.Fprintf(w, `{"data":{"serverStat":[{"uuid":"s0","uri":"localhost:3301","statistics":{"bucketsCount":%d}}]}}`, buckets)
+		case strings.Contains(req.Query, "replicasets {"):
+			fmt.Fprint(w, `{"data":{"replicasets":[{"uuid":"rs0","servers":[{"uuid":"s0"}]}]}}`)
+		default:
+			calls[strings.Fields(req.Query)[1]]++
+			fmt.Fprint(w, `{"errors":[{"message":"refused","extensions":{"io.tarantool.errors.class_name":"Editing cluster topology failed"}}]}`)
+		}
+	}))
+	defer server.Close()
+
+	s := NewBuiltInTopologyService(WithTopologyEndpoint(server.URL), WithRetries(3, time.Millisecond))
+
+	err := s.SetFailoverParams(ctx, &FailoverParams{Mode: FailoverModeEventual})
+	if !IsTopologyDown(err) || IsRetryable(err) {
+		t.Fatalf("expected topology down error from an unconfigured instance, got %v", err)
+	}
+
+	selfUUID = `"s0"`
+	err = s.EditTopology(ctx, &TopologyPatch{Replicasets: []*EditReplicasetInput{{UUID: "rs0", JoinServers: []*JoinServerInput{{UUID: "s0"}}}}})
+	var topologyErr *Error
+	if !IsAlreadyJoined(err) || !errors.As(err, &topologyErr) || topologyErr.Class != "Editing cluster topology failed" {
+		t.Fatalf("expected already joined error, got %v", err)
+	}
+
+	err = s.EditTopology(ctx, &TopologyPatch{Replicasets: []*EditReplicasetInput{{UUID: "rs0", JoinServers: []*JoinServerInput{{UUID: "s1"}}}}})
+	if err == nil || IsAlreadyJoined(err) || IsTopologyDown(err) {
+		t.Fatalf("expected an unclassified refusal, got %v", err)
+	}
+
+	if err := s.BootstrapVshard(ctx); IsAlreadyBootstrapped(err) {
+		t.Fatalf("vshard without buckets is not bootstrapped, got %v", err)
+	}
+	buckets = 100
+	if err := s.BootstrapVshard(ctx); !IsAlreadyBootstrapped(err) {
+		t.Fatalf("expected already bootstrapped error, got %v", err)
+	}
+
+	for op, n := range calls {
+		if n > 2 {
+			t.Fatalf("errors reported by Cartridge must not be retried, %s was called %d times", op, n)
+		}
+	}
+}
+
+func TestRun_DoesNotRepeatMutationsAfterTimeout(t *testing.T) {
+	ctx := context.Background()
+
+	mutations := 0
+	joined := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := &graphQLRequest{}
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			t.Fatal(err)
+		}
+
+		if strings.Contains(req.Query, "edit_topology") {
+			mutations++
+			joined = true
+			w.WriteHeader(http.StatusGatewayTimeout)
+			return
+		}
+
+		servers := ""
+		if joined {
+			servers = `{"uuid":"s0"}`
+		}
+		fmt.Fprintf(w, `{"data":{"replicasets":[{"uuid":"rs0","servers":[%s]}]}}`, servers)
+	}))
+	defer server.Close()
+
+	s := NewBuiltInTopologyService(WithTopologyEndpoint(server.URL), WithRetries(3, time.Millisecond))
+	patch := &TopologyPatch{Replicasets: []*EditReplicasetInput{{UUID: "rs0", JoinServers: []*JoinServerInput{{UUID: "s0"}}}}}
+	if err := s.EditTopology(ctx, patch); err != nil {
+		t.Fatalf("an applied patch must succeed, got %v", err)
+	}
+	if mutations != 1 {
+		t.Fatalf("edit_topology must not be repeated after a timeout, got %d requests", mutations)
+	}
+
+	joined = false
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := &graphQLRequest{}
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			t.Fatal(err)
+		}
+
+		if strings.Contains(req.Query, "edit_topology") {
+			mutations++
+			w.WriteHeader(http.StatusGatewayTimeout)
+			return
+		}
+		fmt.Fprint(w, `{"data":{"replicasets":[{"uuid":"rs0","servers":[]}]}}`)
+	})
+	if err := s.EditTopology(ctx, patch); !IsRetryable(err) {
+		t.Fatalf("a patch which was not applied must fail with a retryable error, got %v", err)
+	}
+	if mutations != 2 {
+		t.Fatalf("edit_topology must not be repeated after a timeout, got %d requests", mutations)
+	}
+}
+
+func TestRun_StopsOnCanceledContext(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	s := NewBuiltInTopologyService(WithTopologyEndpoint(server.URL), WithRetries(5, time.Hour))
+	if _, err := s.GetReplicasets(ctx); err == nil {
+		t.Fatal("expected canceled request to fail")
+	}
+}
@@ -0,0 +1,27 @@
+package topology
+
+// FindServer looks up the instance known by the advertise uri or the alias in the topology
+// and returns it together with its replicaset, nil when there is no such instance
+func FindServer(replicasets []*ReplicasetData, uri, alias string) (*ReplicasetData, *ServerData) {
+	for _, rs := range replicasets {
+		for _, server := range rs.Servers {
+			if server.URI == uri {
+				return rs, server
+			}
+		}
+	}
+
+	if alias == "" {
+		return nil, nil
+	}
+
+	for _, rs := range replicasets {
+		for _, server := range rs.Servers {
+			if server.Alias == alias {
+				return rs, server
+			}
+		}
+	}
+
+	return nil, nil
+}
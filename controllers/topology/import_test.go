@@ -0,0 +1,33 @@
+package topology
+
+import (
+	"testing"
+)
+
+func TestFindServer(t *testing.T) {
+	replicasets := []*ReplicasetData{
+		{UUID: "rs-router", Servers: []*ServerData{{UUID: "r0", URI: "router-0-0.cluster.default.svc.cluster.local:3301", Alias: "router-0-0"}}},
+		{UUID: "rs-storage", Servers: []*ServerData{
+			{UUID: "s0", URI: "storage-0-0.cluster.default.svc.cluster.local:3301", Alias: "storage-0-0"},
+			{UUID: "s1", URI: "10.0.0.5:3301", Alias: "storage-0-1"},
+		}},
+	}
+
+	rs, server := FindServer(replicasets, "storage-0-0.cluster.default.svc.cluster.local:3301", "storage-0-0")
+	if rs == nil || rs.UUID != "rs-storage" || server.UUID != "s0" {
+		t.Fatalf("expected s0 of rs-storage to be found by uri, got %+v %+v", rs, server)
+	}
+
+	rs, server = FindServer(replicasets, "storage-0-1.cluster.default.svc.cluster.local:3301", "storage-0-1")
+	if rs == nil || rs.UUID != "rs-storage" || server.UUID != "s1" {
+		t.Fatalf("expected s1 of rs-storage to be found by alias, got %+v %+v", rs, server)
+	}
+
+	if rs, server := FindServer(replicasets, "storage-0-2.cluster.default.svc.cluster.local:3301", "storage-0-2"); rs != nil || server != nil {
+		t.Fatalf("expected no server, got %+v %+v", rs, server)
+	}
+
+	if rs, server := FindServer(replicasets, "unknown:3301", ""); rs != nil || server != nil {
+		t.Fatalf("expected an empty alias to match nothing, got %+v %+v", rs, server)
+	}
+}
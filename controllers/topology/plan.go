@@ -0,0 +1,282 @@
+package topology
+
+import (
+	"sort"
+
+	"github.com/tarantool/tarantool-operator/controllers/utils"
+)
+
+// DesiredServer is an instance which should be a member of the topology
+type DesiredServer struct {
+	UUID string
+	URI  string
+	// Zone is the zone of the node the instance runs on, empty leaves the zone as is
+	Zone string
+	// ManagedLabels are the names of the labels the operator sets, the other labels of the instance are left alone
+	ManagedLabels []string
+	// Labels are the values of the managed labels, a managed label missing from them is removed
+	Labels map[string]string
+}
+
+// DesiredReplicaset is a replicaset the topology should contain
+type DesiredReplicaset struct {
+	UUID  string
+	Alias string
+	Roles []string
+	// Weight is the vshard weight to set, nil leaves the weight as is
+	Weight *float64
+	// VshardGroup is the vshard group of a storage replicaset, empty when vshard groups are not used
+	VshardGroup string
+	Servers     []*DesiredServer
+}
+
+// TopologyPatch is a set of changes applied by a single edit_topology request
+type TopologyPatch struct {
+	Replicasets []*EditReplicasetInput `json:"replicasets,omitempty"`
+	Servers     []*EditServerInput     `json:"servers,omitempty"`
+}
+
+// EditReplicasetInput creates a replicaset or changes an existing one.
+// Roles replace all roles of the replicaset: an empty list clears them, nil leaves them as they are.
+type EditReplicasetInput struct {
+	UUID        string             `json:"uuid"`
+	Alias       string             `json:"alias,omitempty"`
+	Roles       *[]string          `json:"roles,omitempty"`
+	Weight      *float64           `json:"weight,omitempty"`
+	VshardGroup string             `json:"vshard_group,omitempty"`
+	JoinServers []*JoinServerInput `json:"join_servers,omitempty"`
+}
+
+// JoinServerInput is an instance joined to a replicaset
+type JoinServerInput struct {
+	URI    string   `json:"uri"`
+	UUID   string   `json:"uuid"`
+	Zone   string   `json:"zone,omitempty"`
+	Labels []*Label `json:"labels,omitempty"`
+}
+
+// EditServerInput changes an instance of the topology
+type EditServerInput struct {
+	UUID     string `json:"uuid"`
+	Expelled bool   `json:"expelled,omitempty"`
+	// Zone moves the instance to another zone, empty leaves the zone as is
+	Zone string `json:"zone,omitempty"`
+	// Labels replace all labels of the instance, nil leaves them as they are
+	Labels *[]*Label `json:"labels,omitempty"`
+}
+
+// IsEmpty reports whether the patch changes nothing
+func (p *TopologyPatch) IsEmpty() bool {
+	return len(p.Replicasets) == 0 && len(p.Servers) == 0
+}
+
+// PlanTopology diffs the desired replicasets against the actual topology.
+// Missing replicasets are created and missing instances joined, roles and weights of existing replicasets are edited,
+// and the zones and labels of joined instances are edited when they moved to another node.
+// Instances absent from the desired state are left alone, expelling them is up to the caller.
+// A weight is never set on a new replicaset: Cartridge picks the initial one depending on whether vshard
+// is bootstrapped, and the desired weight is applied by a later patch.
+func PlanTopology(desired []*DesiredReplicaset, actual []*ReplicasetData) *TopologyPatch {
+	actualByUUID := make(map[string]*ReplicasetData)
+	servers := make(map[string]*ServerData)
+	for _, rs := range actual {
+		actualByUUID[rs.UUID] = rs
+		for _, server := range rs.Servers {
+			servers[server.UUID] = server
+		}
+	}
+
+	patch := &TopologyPatch{}
+	for _, want := range desired {
+		edit := &EditReplicasetInput{UUID: want.UUID}
+		for _, server := range want.Servers {
+			have, ok := servers[server.UUID]
+			if !ok {
+				edit.JoinServers = append(edit.JoinServers, &JoinServerInput{
+					URI:    server.URI,
+					UUID:   server.UUID,
+					Zone:   server.Zone,
+					Labels: desiredLabels(nil, server),
+				})
+				continue
+			}
+
+			if placement := planPlacement(have, server); placement != nil {
+				patch.Servers = append(patch.Servers, placement)
+			}
+		}
+
+		have, ok := actualByUUID[want.UUID]
+		if !ok {
+			if len(edit.JoinServers) == 0 {
+				continue
+			}
+
+			edit.Alias = want.Alias
+			edit.Roles = rolesInput(want.Roles)
+			edit.VshardGroup = want.VshardGroup
+			patch.Replicasets = append(patch.Replicasets, edit)
+			continue
+		}
+
+		if !utils.IsRolesEquals(have.Roles, want.Roles) {
+			edit.Roles = rolesInput(want.Roles)
+		}
+		if want.Weight != nil && have.Weight != nil && float64(*have.Weight) != *want.Weight {
+			edit.Weight = want.Weight
+		}
+
+		if edit.Roles != nil || edit.Weight != nil || len(edit.JoinServers) > 0 {
+			patch.Replicasets = append(patch.Replicasets, edit)
+		}
+	}
+
+	return patch
+}
+
+// rolesInput returns the roles to set, never nil so that an empty list clears the roles
+func rolesInput(roles []string) *[]string {
+	input := append([]string{}, roles...)
+	return &input
+}
+
+// planPlacement returns the edit moving the instance to its desired zone and labels, nil when it is there already
+func planPlacement(have *ServerData, want *DesiredServer) *EditServerInput {
+	edit := &EditServerInput{UUID: want.UUID}
+	if want.Zone != "" && want.Zone != have.Zone {
+		edit.Zone = want.Zone
+	}
+
+	labels := desiredLabels(have.Labels, want)
+	if !labelsEqual(have.Labels, labels) {
+		edit.Labels = &labels
+	}
+
+	if edit.Zone == "" && edit.Labels == nil {
+		return nil
+	}
+
+	return edit
+}
+
+// desiredLabels returns the actual labels of the instance with the managed ones replaced by the desired values,
+// sorted by name
+func desiredLabels(actual []*Label, want *DesiredServer) []*Label {
+	managed := make(map[string]bool)
+	for _, name := range want.ManagedLabels {
+		managed[name] = true
+	}
+
+	labels := []*Label{}
+	for _, label := range actual {
+		if !managed[label.Name] {
+			labels = append(labels, label)
+		}
+	}
+	for _, name := range want.ManagedLabels {
+		if value, ok := want.Labels[name]; ok {
+			labels = append(labels, &Label{Name: name, Value: value})
+		}
+	}
+
+	sort.Slice(labels, func(i, j int) bool { return labels[i].Name < labels[j].Name })
+	return labels
+}
+
+func labelsEqual(a, b []*Label) bool {
+	values := make(map[string]string)
+	for _, label := range a {
+		values[label.Name] = label.Value
+	}
+	if len(values) != len(b) {
+		return false
+	}
+
+	for _, label := range b {
+		if value, ok := values[label.Name]; !ok || value != label.Value {
+			return false
+		}
+	}
+
+	return true
+}
+
+// placementApplied reports whether the instance has the zone and the labels the edit sets
+func placementApplied(actual *ServerData, edit *EditServerInput) bool {
+	if edit.Zone != "" && actual.Zone != edit.Zone {
+		return false
+	}
+
+	return edit.Labels == nil || labelsEqual(actual.Labels, *edit.Labels)
+}
+
+// Items splits the patch into patches making a single change each: editing the roles and weight of a replicaset,
+// joining, editing or expelling one instance. A new replicaset is created by the item joining its first instance.
+// Applied one by one in order, the items make the same change as the whole patch.
+func (p *TopologyPatch) Items() []*TopologyPatch {
+	var items []*TopologyPatch
+	for _, edit := range p.Replicasets {
+		first := &EditReplicasetInput{
+			UUID:        edit.UUID,
+			Alias:       edit.Alias,
+			Roles:       edit.Roles,
+			Weight:      edit.Weight,
+			VshardGroup: edit.VshardGroup,
+		}
+
+		joins := edit.JoinServers
+		if edit.Alias != "" && len(joins) > 0 {
+			// a new replicaset can not exist without instances
+			first.JoinServers = joins[:1]
+			joins = joins[1:]
+		}
+		if first.Roles != nil || first.Weight != nil || len(first.JoinServers) > 0 {
+			items = append(items, &TopologyPatch{Replicasets: []*EditReplicasetInput{first}})
+		}
+
+		for _, server := range joins {
+			items = append(items, &TopologyPatch{Replicasets: []*EditReplicasetInput{
+				{UUID: edit.UUID, JoinServers: []*JoinServerInput{server}},
+			}})
+		}
+	}
+
+	for _, server := range p.Servers {
+		items = append(items, &TopologyPatch{Servers: []*EditServerInput{server}})
+	}
+
+	return items
+}
+
+// MergePatches combines the patches into one applied by a single edit_topology request
+func MergePatches(patches ...*TopologyPatch) *TopologyPatch {
+	merged := &TopologyPatch{}
+	byUUID := make(map[string]*EditReplicasetInput)
+	for _, patch := range patches {
+		for _, edit := range patch.Replicasets {
+			into, ok := byUUID[edit.UUID]
+			if !ok {
+				into = &EditReplicasetInput{UUID: edit.UUID}
+				byUUID[edit.UUID] = into
+				merged.Replicasets = append(merged.Replicasets, into)
+			}
+
+			if edit.Alias != "" {
+				into.Alias = edit.Alias
+			}
+			if edit.Roles != nil {
+				into.Roles = edit.Roles
+			}
+			if edit.Weight != nil {
+				into.Weight = edit.Weight
+			}
+			if edit.VshardGroup != "" {
+				into.VshardGroup = edit.VshardGroup
+			}
+			into.JoinServers = append(into.JoinServers, edit.JoinServers...)
+		}
+		merged.Servers = append(merged.Servers, patch.Servers...)
+	}
+
+	return merged
+}
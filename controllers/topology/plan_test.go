@@ -0,0 +1,191 @@
+package topology
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestPlanTopology_CreatesReplicasetsAndJoinsServers(t *testing.T) {
+	weight := 1
+	actual := []*ReplicasetData{
+		{UUID: "rs-storage-0", Roles: []string{"vshard-storage"}, Weight: &weight, Servers: []*ServerData{{UUID: "s0"}}},
+	}
+	desired := []*DesiredReplicaset{
+		{
+			UUID:    "rs-storage-0",
+			Alias:   "storage-0",
+			Roles:   []string{"vshard-storage"},
+			Servers: []*DesiredServer{{UUID: "s0", URI: "storage-0-0:3301"}, {UUID: "s1", URI: "storage-0-1:3301"}},
+		},
+		{
+			UUID:    "rs-router",
+			Alias:   "router-0",
+			Roles:   []string{"router"},
+			Servers: []*DesiredServer{{UUID: "r0", URI: "router-0-0:3301"}},
+		},
+		{UUID: "rs-empty", Alias: "empty-0", Roles: []string{"router"}},
+	}
+
+	patch := PlanTopology(desired, actual)
+	if len(patch.Replicasets) != 2 || len(patch.Servers) != 0 {
+		t.Fatalf("unexpected patch %+v", patch)
+	}
+
+	storage := patch.Replicasets[0]
+	if storage.UUID != "rs-storage-0" || storage.Alias != "" || storage.Roles != nil || storage.Weight != nil {
+		t.Errorf("existing replicaset must only get new servers, got %+v", storage)
+	}
+	if len(storage.JoinServers) != 1 || storage.JoinServers[0].UUID != "s1" || storage.JoinServers[0].URI != "storage-0-1:3301" {
+		t.Errorf("unexpected join servers %+v", storage.JoinServers)
+	}
+
+	router := patch.Replicasets[1]
+	if router.UUID != "rs-router" || router.Alias != "router-0" || router.Roles == nil || len(*router.Roles) != 1 || len(router.JoinServers) != 1 {
+		t.Errorf("unexpected new replicaset %+v", router)
+	}
+}
+
+func TestPlanTopology_EditsRolesAndWeights(t *testing.T) {
+	weight := 1
+	actual := []*ReplicasetData{
+		{UUID: "rs-storage-0", Roles: []string{"vshard-storage"}, Weight: &weight, Servers: []*ServerData{{UUID: "s0"}}},
+		{UUID: "rs-storage-1", Roles: []string{"vshard-storage"}, Weight: &weight, Servers: []*ServerData{{UUID: "s1"}}},
+	}
+
+	same := 1.0
+	drained := 0.0
+	desired := []*DesiredReplicaset{
+		{UUID: "rs-storage-0", Roles: []string{"vshard-storage", "metrics"}, Weight: &same, Servers: []*DesiredServer{{UUID: "s0"}}},
+		{UUID: "rs-storage-1", Roles: []string{"vshard-storage"}, Weight: &drained, Servers: []*DesiredServer{{UUID: "s1"}}},
+	}
+
+	patch := PlanTopology(desired, actual)
+	if len(patch.Replicasets) != 2 {
+		t.Fatalf("unexpected patch %+v", patch)
+	}
+	if patch.Replicasets[0].Roles == nil || len(*patch.Replicasets[0].Roles) != 2 || patch.Replicasets[0].Weight != nil {
+		t.Errorf("expected roles edit only, got %+v", patch.Replicasets[0])
+	}
+	if patch.Replicasets[1].Roles != nil || patch.Replicasets[1].Weight == nil || *patch.Replicasets[1].Weight != 0 {
+		t.Errorf("expected weight edit only, got %+v", patch.Replicasets[1])
+	}
+
+	moved := []*DesiredReplicaset{{UUID: "rs-storage-2", Roles: []string{"vshard-storage"}, Servers: []*DesiredServer{{UUID: "s1"}}}}
+	if patch := PlanTopology(moved, actual); !patch.IsEmpty() {
+		t.Errorf("replicaset without servers to join must not be created, got %+v", patch)
+	}
+}
+
+func TestPlanTopology_EditsZonesAndLabels(t *testing.T) {
+	actual := []*ReplicasetData{
+		{UUID: "rs-storage-0", Roles: []string{"vshard-storage"}, Servers: []*ServerData{
+			{UUID: "s0", Zone: "zone-a", Labels: []*Label{{Name: "rack", Value: "r1"}, {Name: "owner", Value: "dba"}}},
+			{UUID: "s1", Zone: "zone-a", Labels: []*Label{{Name: "rack", Value: "r2"}}},
+			{UUID: "s2"},
+		}},
+	}
+	managed := []string{"rack", "tier"}
+	desired := []*DesiredReplicaset{{
+		UUID:  "rs-storage-0",
+		Roles: []string{"vshard-storage"},
+		Servers: []*DesiredServer{
+			{UUID: "s0", Zone: "zone-b", ManagedLabels: managed, Labels: map[string]string{"rack": "r3", "tier": "gold"}},
+			{UUID: "s1", Zone: "zone-a", ManagedLabels: managed, Labels: map[string]string{"rack": "r2"}},
+			{UUID: "s2", ManagedLabels: managed},
+			{UUID: "s3", URI: "storage-0-3:3301", Zone: "zone-c", ManagedLabels: managed, Labels: map[string]string{"tier": "gold"}},
+		},
+	}}
+
+	patch := PlanTopology(desired, actual)
+	if len(patch.Servers) != 1 {
+		t.Fatalf("expected only the moved instance to be edited, got %+v", patch.Servers)
+	}
+
+	edit := patch.Servers[0]
+	if edit.UUID != "s0" || edit.Zone != "zone-b" || edit.Labels == nil {
+		t.Fatalf("unexpected server edit %+v", edit)
+	}
+	expected := []*Label{{Name: "owner", Value: "dba"}, {Name: "rack", Value: "r3"}, {Name: "tier", Value: "gold"}}
+	if !labelsEqual(*edit.Labels, expected) {
+		t.Errorf("unmanaged labels must be kept and managed ones replaced, got %+v", *edit.Labels)
+	}
+
+	if len(patch.Replicasets) != 1 || len(patch.Replicasets[0].JoinServers) != 1 {
+		t.Fatalf("unexpected patch %+v", patch.Replicasets)
+	}
+	if join := patch.Replicasets[0].JoinServers[0]; join.Zone != "zone-c" || len(join.Labels) != 1 || join.Labels[0].Name != "tier" {
+		t.Errorf("new instance must join with its zone and labels, got %+v", join)
+	}
+
+	if !placementApplied(&ServerData{Zone: "zone-b", Labels: expected}, edit) {
+		t.Error("expected the edit to be applied")
+	}
+	if placementApplied(&ServerData{Zone: "zone-a", Labels: expected}, edit) {
+		t.Error("expected another zone not to match")
+	}
+
+	cleared := []*DesiredReplicaset{{UUID: "rs-storage-0", Roles: []string{"vshard-storage"}, Servers: []*DesiredServer{{UUID: "s1", ManagedLabels: managed}}}}
+	patch = PlanTopology(cleared, actual)
+	if len(patch.Servers) != 1 || patch.Servers[0].Labels == nil || len(*patch.Servers[0].Labels) != 0 || patch.Servers[0].Zone != "" {
+		t.Errorf("expected the last managed label to be removed and the zone kept, got %+v", patch.Servers)
+	}
+}
+
+func TestPlanTopology_SendsEmptyRoles(t *testing.T) {
+	actual := []*ReplicasetData{{UUID: "rs-router", Roles: []string{"router"}, Servers: []*ServerData{{UUID: "r0"}}}}
+	desired := []*DesiredReplicaset{{UUID: "rs-router", Roles: []string{}, Servers: []*DesiredServer{{UUID: "r0"}}}}
+
+	patch := PlanTopology(desired, actual)
+	if len(patch.Replicasets) != 1 || patch.Replicasets[0].Roles == nil || len(*patch.Replicasets[0].Roles) != 0 {
+		t.Fatalf("expected the roles to be cleared, got %+v", patch)
+	}
+	body, err := json.Marshal(patch.Replicasets[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(body), `"roles":[]`) {
+		t.Errorf("empty roles must be sent, got %s", body)
+	}
+}
+
+func TestTopologyPatch_ItemsMergeBack(t *testing.T) {
+	weight := 0.0
+	patch := &TopologyPatch{
+		Replicasets: []*EditReplicasetInput{
+			{
+				UUID:        "rs-router",
+				Alias:       "router-0",
+				Roles:       &[]string{"router"},
+				JoinServers: []*JoinServerInput{{UUID: "r0", URI: "router-0-0:3301"}, {UUID: "r1", URI: "router-0-1:3301"}},
+			},
+			{UUID: "rs-storage-0", Weight: &weight, JoinServers: []*JoinServerInput{{UUID: "s1", URI: "storage-0-1:3301"}}},
+		},
+		Servers: []*EditServerInput{{UUID: "s2", Expelled: true}},
+	}
+
+	items := patch.Items()
+	if len(items) != 5 {
+		t.Fatalf("expected 5 items, got %d", len(items))
+	}
+	if create := items[0].Replicasets[0]; create.Alias != "router-0" || len(create.JoinServers) != 1 || create.JoinServers[0].UUID != "r0" {
+		t.Errorf("new replicaset must be created with its first instance, got %+v", create)
+	}
+	if join := items[1].Replicasets[0]; join.Alias != "" || join.Roles != nil || join.JoinServers[0].UUID != "r1" {
+		t.Errorf("unexpected join item %+v", join)
+	}
+	if edit := items[2].Replicasets[0]; edit.Weight == nil || len(edit.JoinServers) != 0 {
+		t.Errorf("weight must be edited apart from joins, got %+v", edit)
+	}
+
+	merged := MergePatches(items...)
+	if len(merged.Replicasets) != 2 || len(merged.Servers) != 1 {
+		t.Fatalf("unexpected merged patch %+v", merged)
+	}
+	if router := merged.Replicasets[0]; router.Alias != "router-0" || len(router.JoinServers) != 2 {
+		t.Errorf("unexpected merged replicaset %+v", router)
+	}
+	if storage := merged.Replicasets[1]; storage.Weight == nil || len(storage.JoinServers) != 1 {
+		t.Errorf("unexpected merged replicaset %+v", storage)
+	}
+}
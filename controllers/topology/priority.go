@@ -0,0 +1,61 @@
+package topology
+
+import (
+	"sort"
+)
+
+// PriorityCandidate is an instance of a replicaset ordered into the failover priority
+type PriorityCandidate struct {
+	UUID string
+	// Ordinal is the ordinal of the instance pod in its StatefulSet
+	Ordinal int
+	// Zone is the zone of the node the instance pod runs on, empty when it is unknown
+	Zone string
+}
+
+// FailoverPriority orders the instances of a replicaset: the pinned instance goes first,
+// then the instances in the preferred zone and then the others, each group by ordinal
+func FailoverPriority(candidates []PriorityCandidate, preferredZone, pinned string) []string {
+	ordered := make([]PriorityCandidate, len(candidates))
+	copy(ordered, candidates)
+
+	rank := func(c PriorityCandidate) int {
+		switch {
+		case pinned != "" && c.UUID == pinned:
+			return 0
+		case preferredZone != "" && c.Zone == preferredZone:
+			return 1
+		default:
+			return 2
+		}
+	}
+	sort.SliceStable(ordered, func(i, j int) bool {
+		if rank(ordered[i]) != rank(ordered[j]) {
+			return rank(ordered[i]) < rank(ordered[j])
+		}
+		return ordered[i].Ordinal < ordered[j].Ordinal
+	})
+
+	priority := make([]string, 0, len(ordered))
+	for _, c := range ordered {
+		priority = append(priority, c.UUID)
+	}
+
+	return priority
+}
+
+// FailoverPriorityApplied reports whether the servers of the replicaset start with the priority,
+// Cartridge lists them in the failover order
+func FailoverPriorityApplied(replicaset *ReplicasetData, priority []string) bool {
+	if len(replicaset.Servers) < len(priority) {
+		return false
+	}
+
+	for i, uuid := range priority {
+		if replicaset.Servers[i].UUID != uuid {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,47 @@
+package topology
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFailoverPriority(t *testing.T) {
+	candidates := []PriorityCandidate{
+		{UUID: "s2", Ordinal: 2, Zone: "zone-a"},
+		{UUID: "s0", Ordinal: 0, Zone: "zone-b"},
+		{UUID: "s1", Ordinal: 1, Zone: "zone-a"},
+	}
+
+	for _, tc := range []struct {
+		name          string
+		preferredZone string
+		pinned        string
+		expected      []string
+	}{
+		{name: "by ordinal", expected: []string{"s0", "s1", "s2"}},
+		{name: "preferred zone first", preferredZone: "zone-a", expected: []string{"s1", "s2", "s0"}},
+		{name: "unknown zone", preferredZone: "zone-c", expected: []string{"s0", "s1", "s2"}},
+		{name: "pinned master first", preferredZone: "zone-a", pinned: "s0", expected: []string{"s0", "s1", "s2"}},
+		{name: "pinned master outside the replicaset", pinned: "s9", expected: []string{"s0", "s1", "s2"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if priority := FailoverPriority(candidates, tc.preferredZone, tc.pinned); !reflect.DeepEqual(priority, tc.expected) {
+				t.Fatalf("expected %v, got %v", tc.expected, priority)
+			}
+		})
+	}
+}
+
+func TestFailoverPriorityApplied(t *testing.T) {
+	replicaset := &ReplicasetData{Servers: []*ServerData{{UUID: "s1"}, {UUID: "s0"}, {UUID: "s2"}}}
+
+	if !FailoverPriorityApplied(replicaset, []string{"s1", "s0"}) {
+		t.Fatal("expected the prefix of the servers to match")
+	}
+	if FailoverPriorityApplied(replicaset, []string{"s0", "s1"}) {
+		t.Fatal("expected another order not to match")
+	}
+	if FailoverPriorityApplied(replicaset, []string{"s1", "s0", "s2", "s3"}) {
+		t.Fatal("expected a longer priority not to match")
+	}
+}
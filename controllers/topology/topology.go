@@ -1,11 +1,111 @@
 package topology
 
 import (
+	"context"
+	"crypto/tls"
+	"time"
+
 	corev1 "k8s.io/api/core/v1"
 )
 
-// TopologyService .
+// TopologyService manages the Cartridge topology of a single cluster
 type TopologyService interface {
-	Join(p *corev1.Pod) error
-	Expel(p *corev1.Pod) error
+	Join(ctx context.Context, p *corev1.Pod) error
+	Expel(ctx context.Context, pods ...*corev1.Pod) error
+	EditTopology(ctx context.Context, patch *TopologyPatch) error
+
+	GetSelf(ctx context.Context) (*SelfData, error)
+	GetReplicasets(ctx context.Context) ([]*ReplicasetData, error)
+	GetServerStat(ctx context.Context) (ServerStatData, error)
+
+	GetWeight(ctx context.Context, replicasetUUID string) (int, error)
+	SetWeight(ctx context.Context, replicasetUUID string, replicaWeight string) error
+
+	GetReplicasetRolesFromService(ctx context.Context, replicasetUUID string) ([]string, error)
+	SetReplicasetRoles(ctx context.Context, replicasetUUID string, roles []string) error
+	SetFailoverPriority(ctx context.Context, replicasetUUID string, priority []string) error
+
+	GetFailoverParams(ctx context.Context) (*FailoverParams, error)
+	SetFailoverParams(ctx context.Context, params *FailoverParams) error
+	Promote(ctx context.Context, replicasetUUID, instanceUUID string) error
+	BootstrapVshard(ctx context.Context) error
+}
+
+// Factory creates a TopologyService for the cluster described by opts
+type Factory func(opts ...Option) TopologyService
+
+// Options describe the cluster a TopologyService works with
+type Options struct {
+	// Endpoint is the address of the cluster leader admin API
+	Endpoint string
+	// ClusterID is the name of the Cluster resource
+	ClusterID string
+	// ClusterDomainName is the Kubernetes cluster domain used to build advertise URIs
+	ClusterDomainName string
+	// BinaryPort is the Tarantool iproto port
+	BinaryPort int32
+	// VshardGroups is a list of vshard group names
+	VshardGroups []string
+	// Credentials authenticate admin API requests, nil when Cartridge auth is disabled
+	Credentials *Credentials
+	// TLSConfig configures https connections to the admin API
+	TLSConfig *tls.Config
+	// Timeout bounds a single admin API request
+	Timeout time.Duration
+	// MaxRetries is how many times a request failed for a transient reason is repeated
+	MaxRetries int
+	// RetryBackoff is the delay before the first retry, it doubles with every next one
+	RetryBackoff time.Duration
+}
+
+// Option .
+type Option func(o *Options)
+
+// WithTopologyEndpoint .
+func WithTopologyEndpoint(url string) Option {
+	return func(o *Options) {
+		o.Endpoint = url
+	}
+}
+
+// WithClusterID .
+func WithClusterID(id string) Option {
+	return func(o *Options) {
+		o.ClusterID = id
+	}
+}
+
+// WithClusterDomainName .
+func WithClusterDomainName(name string) Option {
+	return func(o *Options) {
+		o.ClusterDomainName = name
+	}
+}
+
+// WithBinaryPort .
+func WithBinaryPort(port int32) Option {
+	return func(o *Options) {
+		o.BinaryPort = port
+	}
+}
+
+// WithVshardGroups .
+func WithVshardGroups(groups []string) Option {
+	return func(o *Options) {
+		o.VshardGroups = groups
+	}
+}
+
+// NewOptions applies opts to the default Options
+func NewOptions(opts ...Option) Options {
+	o := Options{
+		Timeout:      defaultTimeout,
+		MaxRetries:   defaultMaxRetries,
+		RetryBackoff: defaultRetryBackoff,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return o
 }
@@ -0,0 +1,91 @@
+package topology
+
+import (
+	"crypto/tls"
+	"net/http"
+	"time"
+)
+
+const (
+	// defaultTimeout bounds every admin API request
+	defaultTimeout = 5 * time.Second
+	// defaultMaxRetries is how many times a request failed for a transient reason is repeated
+	defaultMaxRetries = 3
+	// defaultRetryBackoff is the delay before the first retry
+	defaultRetryBackoff = 200 * time.Millisecond
+	// probeTimeout is short to notice a dead instance early
+	probeTimeout = 2 * time.Second
+)
+
+// Credentials authenticate the operator in a Cartridge cluster with auth enabled
+type Credentials struct {
+	Username string
+	Password string
+	// Cookie is sent as is in the Cookie header, e.g. lsid=...
+	Cookie string
+}
+
+// authTransport adds credentials to every request sent to the admin API
+type authTransport struct {
+	base        http.RoundTripper
+	credentials *Credentials
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *authTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.credentials == nil {
+		return t.base.RoundTrip(req)
+	}
+
+	req = req.Clone(req.Context())
+	if t.credentials.Username != "" {
+		req.SetBasicAuth(t.credentials.Username, t.credentials.Password)
+	}
+	if t.credentials.Cookie != "" {
+		req.Header.Set("Cookie", t.credentials.Cookie)
+	}
+
+	return t.base.RoundTrip(req)
+}
+
+// newHTTPClient builds the transport every admin API request of a TopologyService goes through
+func newHTTPClient(o Options) *http.Client {
+	base := http.DefaultTransport.(*http.Transport).Clone()
+	if o.TLSConfig != nil {
+		base.TLSClientConfig = o.TLSConfig.Clone()
+	}
+
+	return &http.Client{
+		Timeout:   o.Timeout,
+		Transport: &authTransport{base: base, credentials: o.Credentials},
+	}
+}
+
+// WithCredentials authenticates admin API requests
+func WithCredentials(credentials *Credentials) Option {
+	return func(o *Options) {
+		o.Credentials = credentials
+	}
+}
+
+// WithTLSConfig is used for https admin API endpoints
+func WithTLSConfig(config *tls.Config) Option {
+	return func(o *Options) {
+		o.TLSConfig = config
+	}
+}
+
+// WithTimeout bounds a single admin API request
+func WithTimeout(timeout time.Duration) Option {
+	return func(o *Options) {
+		o.Timeout = timeout
+	}
+}
+
+// WithRetries sets how many times and how soon a request failed for a transient reason is repeated
+func WithRetries(maxRetries int, backoff time.Duration) Option {
+	return func(o *Options) {
+		o.MaxRetries = maxRetries
+		o.RetryBackoff = backoff
+	}
+}
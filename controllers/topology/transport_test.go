@@ -0,0 +1,88 @@
+package topology
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+const selfResponse = `{"data":{"cluster":{"self":{"uri":"storage-0-0:3301","uuid":"s0","alias":"storage-0-0"}}}}`
+
+func TestBuiltIn_SendsCredentials(t *testing.T) {
+	ctx := context.Background()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		username, password, ok := r.BasicAuth()
+		if !ok || username != "admin" || password != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, selfResponse)
+	}))
+	defer server.Close()
+
+	if _, err := NewBuiltInTopologyService(WithTopologyEndpoint(server.URL)).GetSelf(ctx); err == nil {
+		t.Fatal("expected unauthenticated request to fail")
+	}
+
+	s := NewBuiltInTopologyService(
+		WithTopologyEndpoint(server.URL),
+		WithCredentials(&Credentials{Username: "admin", Password: "secret"}),
+	)
+	self, err := s.GetSelf(ctx)
+	if err != nil || self.UUID != "s0" {
+		t.Fatalf("unexpected self %+v: %v", self, err)
+	}
+}
+
+func TestBuiltIn_SendsCookie(t *testing.T) {
+	ctx := context.Background()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cookie, err := r.Cookie("lsid")
+		if err != nil || cookie.Value != "session" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, selfResponse)
+	}))
+	defer server.Close()
+
+	s := NewBuiltInTopologyService(
+		WithTopologyEndpoint(server.URL),
+		WithCredentials(&Credentials{Cookie: "lsid=session"}),
+	)
+	if _, err := s.GetSelf(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBuiltIn_VerifiesServerAndPresentsClientCertificate(t *testing.T) {
+	ctx := context.Background()
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(r.TLS.PeerCertificates) == 0 {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, selfResponse)
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequestClientCert}
+	server.StartTLS()
+	defer server.Close()
+
+	if _, err := NewBuiltInTopologyService(WithTopologyEndpoint(server.URL)).GetSelf(ctx); err == nil {
+		t.Fatal("expected unknown server certificate to be rejected")
+	}
+
+	roots := x509.NewCertPool()
+	roots.AddCert(server.Certificate())
+	s := NewBuiltInTopologyService(
+		WithTopologyEndpoint(server.URL),
+		WithTLSConfig(&tls.Config{RootCAs: roots, Certificates: server.TLS.Certificates}),
+	)
+	if _, err := s.GetSelf(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
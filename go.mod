@@ -3,12 +3,12 @@ module github.com/tarantool/tarantool-operator
 go 1.16
 
 require (
-	github.com/google/uuid v1.1.2 // indirect
-	github.com/machinebox/graphql v0.2.2 // indirect
+	github.com/google/uuid v1.1.2
 	github.com/onsi/ginkgo v1.16.4
 	github.com/onsi/gomega v1.15.0
 	github.com/operator-framework/operator-lib v0.9.0 // indirect
-	k8s.io/api v0.22.3 // indirect
+	github.com/prometheus/client_golang v1.11.0
+	k8s.io/api v0.22.3
 	k8s.io/apimachinery v0.22.3
 	k8s.io/client-go v0.22.1
 	sigs.k8s.io/controller-runtime v0.10.0
@@ -45,6 +45,7 @@ import (
 
 	tarantooliov1alpha1 "github.com/tarantool/tarantool-operator/api/v1alpha1"
 	"github.com/tarantool/tarantool-operator/controllers"
+	"github.com/tarantool/tarantool-operator/controllers/topology"
 	//+kubebuilder:scaffold:imports
 )
 
@@ -91,8 +92,9 @@ func main() {
 	}
 
 	if err = (&controllers.ClusterReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
+		Client:          mgr.GetClient(),
+		Scheme:          mgr.GetScheme(),
+		TopologyFactory: topology.BuiltInFactory,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Cluster")
 		os.Exit(1)
@@ -111,6 +113,20 @@ func main() {
 		setupLog.Error(err, "unable to create controller", "controller", "Role")
 		os.Exit(1)
 	}
+	if os.Getenv("ENABLE_WEBHOOKS") != "false" {
+		if err = (&tarantooliov1alpha1.Cluster{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "Cluster")
+			os.Exit(1)
+		}
+		if err = (&tarantooliov1alpha1.ReplicasetTemplate{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "ReplicasetTemplate")
+			os.Exit(1)
+		}
+		if err = (&tarantooliov1alpha1.Role{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "Role")
+			os.Exit(1)
+		}
+	}
 	//+kubebuilder:scaffold:builder
 
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
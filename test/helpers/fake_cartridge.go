@@ -0,0 +1,901 @@
+package helpers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/tarantool/tarantool-operator/controllers/topology"
+)
+
+// Operations of the Cartridge admin API served by FakeCartridge
+const (
+	FakeOpJoin            = "join_server"
+	FakeOpExpel           = "expel_server"
+	FakeOpEditTopology    = "edit_topology"
+	FakeOpEditReplicaset  = "edit_replicaset"
+	FakeOpBootstrapVshard = "bootstrap_vshard"
+	FakeOpFailoverParams  = "failover_params"
+	FakeOpPromote         = "failover_promote"
+	FakeOpServers         = "servers"
+	FakeOpReplicasets     = "replicasets"
+	FakeOpSelf            = "self"
+)
+
+// errorClasses are the Cartridge error classes the operations of FakeCartridge fail with
+var errorClasses = map[string]string{
+	FakeOpJoin:            "Editing cluster topology failed",
+	FakeOpExpel:           "Editing cluster topology failed",
+	FakeOpEditTopology:    "Editing cluster topology failed",
+	FakeOpEditReplicaset:  "Editing cluster topology failed",
+	FakeOpBootstrapVshard: "Bootstrapping vshard failed",
+	FakeOpFailoverParams:  "Invalid failover params",
+	FakeOpPromote:         "PromoteLeaderError",
+}
+
+// DefaultFakeBucketCount is the number of vshard buckets distributed by FakeCartridge on bootstrap
+const DefaultFakeBucketCount = 3000
+
+// FakeServer is an instance known to FakeCartridge
+type FakeServer struct {
+	UUID           string
+	URI            string
+	Alias          string
+	Status         string
+	ReplicasetUUID string
+	BucketsCount   int
+	Zone           string
+	Labels         map[string]string
+}
+
+// FakeReplicaset is a replicaset known to FakeCartridge
+type FakeReplicaset struct {
+	UUID        string
+	Alias       string
+	Roles       []string
+	Weight      *float64
+	VshardGroup string
+	// Servers is the failover priority list of instance uuids, the first one is the master
+	Servers []string
+}
+
+type fakeFault struct {
+	message string
+	times   int
+}
+
+// FakeCartridge is an in-memory Cartridge cluster serving the GraphQL admin API used by topology.BuiltInTopologyService
+type FakeCartridge struct {
+	mu sync.Mutex
+
+	server *httptest.Server
+
+	replicasets map[string]*FakeReplicaset
+	servers     map[string]*FakeServer
+	// expelled keeps the uuids of expelled instances, Cartridge never lets them join again
+	expelled       map[string]bool
+	bootstrapped   bool
+	failoverParams topology.FailoverParams
+	// leaders are the instances promoted by the stateful failover by replicaset uuid
+	leaders     map[string]string
+	bucketCount int
+
+	faults map[string]*fakeFault
+	calls  map[string]int
+}
+
+// NewFakeCartridge starts a fake Cartridge admin API server, call Close to stop it
+func NewFakeCartridge() *FakeCartridge {
+	f := &FakeCartridge{
+		replicasets: make(map[string]*FakeReplicaset),
+		servers:     make(map[string]*FakeServer),
+		expelled:    make(map[string]bool),
+		leaders:     make(map[string]string),
+		failoverParams: topology.FailoverParams{
+			Mode:            topology.FailoverModeDisabled,
+			FailoverTimeout: 20,
+			FencingTimeout:  10,
+			FencingPause:    2,
+		},
+		bucketCount: DefaultFakeBucketCount,
+		faults:      make(map[string]*fakeFault),
+		calls:       make(map[string]int),
+	}
+	f.server = httptest.NewServer(http.HandlerFunc(f.handle))
+
+	return f
+}
+
+// Close stops the server
+func (f *FakeCartridge) Close() {
+	f.server.Close()
+}
+
+// URL is the admin API endpoint of the fake
+func (f *FakeCartridge) URL() string {
+	return f.server.URL + "/admin/api"
+}
+
+// Factory creates topology clients talking to the fake regardless of the requested endpoint
+func (f *FakeCartridge) Factory() topology.Factory {
+	return func(opts ...topology.Option) topology.TopologyService {
+		return topology.NewBuiltInTopologyService(append(opts, topology.WithTopologyEndpoint(f.URL()))...)
+	}
+}
+
+// InjectFault makes the next times calls of the operation fail with message, times <= 0 fails until ClearFaults
+func (f *FakeCartridge) InjectFault(op string, message string, times int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.faults[op] = &fakeFault{message: message, times: times}
+}
+
+// ClearFaults removes all injected faults
+func (f *FakeCartridge) ClearFaults() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.faults = make(map[string]*fakeFault)
+}
+
+// Calls returns how many times the operation was requested
+func (f *FakeCartridge) Calls(op string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.calls[op]
+}
+
+// SetBucketCount sets the number of buckets distributed on bootstrap
+func (f *FakeCartridge) SetBucketCount(count int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.bucketCount = count
+}
+
+// SetServerStatus changes the status reported for the instance, e.g. "unreachable"
+func (f *FakeCartridge) SetServerStatus(uuid string, status string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if server, ok := f.servers[uuid]; ok {
+		server.Status = status
+	}
+}
+
+// AddReplicaset puts the replicaset and its instances into the topology as if they were joined without the operator,
+// instances holding buckets mark vshard bootstrapped
+func (f *FakeCartridge) AddReplicaset(replicaset FakeReplicaset, servers ...FakeServer) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	rs := f.addReplicaset(replicaset.UUID, replicaset.Alias, replicaset.Roles, replicaset.VshardGroup)
+	if replicaset.Weight != nil {
+		weight := *replicaset.Weight
+		rs.Weight = &weight
+	}
+	for _, server := range servers {
+		f.addServer(rs, server.UUID, server.URI)
+		f.servers[server.UUID].BucketsCount = server.BucketsCount
+		if server.BucketsCount > 0 {
+			f.bootstrapped = true
+		}
+	}
+}
+
+// Replicaset returns a copy of the replicaset
+func (f *FakeCartridge) Replicaset(uuid string) (FakeReplicaset, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	rs, ok := f.replicasets[uuid]
+	if !ok {
+		return FakeReplicaset{}, false
+	}
+
+	return *rs, true
+}
+
+// Server returns a copy of the instance
+func (f *FakeCartridge) Server(uuid string) (FakeServer, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	server, ok := f.servers[uuid]
+	if !ok {
+		return FakeServer{}, false
+	}
+
+	copied := *server
+	copied.Labels = make(map[string]string)
+	for name, value := range server.Labels {
+		copied.Labels[name] = value
+	}
+
+	return copied, true
+}
+
+// IsBootstrapped reports whether vshard was bootstrapped
+func (f *FakeCartridge) IsBootstrapped() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.bootstrapped
+}
+
+// IsFailoverEnabled reports whether failover was enabled
+func (f *FakeCartridge) IsFailoverEnabled() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.failoverParams.Mode != topology.FailoverModeDisabled
+}
+
+// FailoverParams returns a copy of the failover settings
+func (f *FakeCartridge) FailoverParams() topology.FailoverParams {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.failoverParams
+}
+
+// SetFailoverParams changes the failover settings behind the operator, e.g. to make them drift
+func (f *FakeCartridge) SetFailoverParams(params topology.FailoverParams) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.failoverParams = params
+}
+
+type fakeRequest struct {
+	Query     string                 `json:"query"`
+	Variables map[string]interface{} `json:"variables"`
+}
+
+type fakeResponse struct {
+	Data   interface{}               `json:"data,omitempty"`
+	Errors []*topology.ResponseError `json:"errors,omitempty"`
+}
+
+func (f *FakeCartridge) handle(w http.ResponseWriter, r *http.Request) {
+	req := &fakeRequest{}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	op := operation(req.Query)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.calls[op]++
+
+	var (
+		data  interface{}
+		err   error
+		class string
+	)
+	if fault, ok := f.faults[op]; ok {
+		err = fmt.Errorf("%s", fault.message)
+		if fault.times > 0 {
+			fault.times--
+			if fault.times == 0 {
+				delete(f.faults, op)
+			}
+		}
+	} else {
+		switch op {
+		case FakeOpJoin:
+			data, err = f.join(req.Variables)
+		case FakeOpExpel:
+			data, err = f.expelServer(req.Variables)
+		case FakeOpEditTopology:
+			data, err = f.editTopology(req.Variables)
+		case FakeOpEditReplicaset:
+			data, err = f.editReplicaset(req.Variables)
+		case FakeOpBootstrapVshard:
+			data, err = f.bootstrapVshard()
+		case FakeOpFailoverParams:
+			data, err = f.failover(req.Query, req.Variables)
+		case FakeOpPromote:
+			data, err = f.promote(req.Variables)
+		case FakeOpServers:
+			data = f.serverStat()
+		case FakeOpReplicasets:
+			data = f.queryReplicasets(req.Variables)
+		case FakeOpSelf:
+			data = f.self()
+		default:
+			err = fmt.Errorf("unsupported query: %s", req.Query)
+		}
+		class = errorClasses[op]
+	}
+
+	resp := &fakeResponse{Data: data}
+	if err != nil {
+		respErr := &topology.ResponseError{Message: err.Error()}
+		if class != "" {
+			respErr.Extensions = map[string]interface{}{"io.tarantool.errors.class_name": class}
+		}
+		resp.Errors = []*topology.ResponseError{respErr}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func operation(query string) string {
+	switch {
+	case strings.Contains(query, "join_server"):
+		return FakeOpJoin
+	case strings.Contains(query, "expel_server"):
+		return FakeOpExpel
+	case strings.Contains(query, "edit_topology"):
+		return FakeOpEditTopology
+	case strings.Contains(query, "edit_replicaset"):
+		return FakeOpEditReplicaset
+	case strings.Contains(query, "bootstrap_vshard"):
+		return FakeOpBootstrapVshard
+	case strings.Contains(query, "failover_promote"):
+		return FakeOpPromote
+	case strings.Contains(query, "failover_params"):
+		return FakeOpFailoverParams
+	case strings.Contains(query, "serverStat: servers"):
+		return FakeOpServers
+	case strings.Contains(query, "replicasets"):
+		return FakeOpReplicasets
+	case strings.Contains(query, "self"):
+		return FakeOpSelf
+	}
+
+	return ""
+}
+
+func (f *FakeCartridge) join(vars map[string]interface{}) (interface{}, error) {
+	uri, _ := vars["uri"].(string)
+	instanceUUID, _ := vars["instance_uuid"].(string)
+	replicasetUUID, _ := vars["replicaset_uuid"].(string)
+	vshardGroup, _ := vars["vshard_group"].(string)
+
+	if err := f.checkJoin(instanceUUID); err != nil {
+		return nil, err
+	}
+
+	rs, ok := f.replicasets[replicasetUUID]
+	if !ok {
+		rs = f.addReplicaset(replicasetUUID, strings.Split(uri, ".")[0], stringList(vars["roles"]), vshardGroup)
+	}
+	f.addServer(rs, instanceUUID, uri)
+
+	return map[string]interface{}{"joinInstanceResponse": true}, nil
+}
+
+func (f *FakeCartridge) addReplicaset(uuid, alias string, roles []string, vshardGroup string) *FakeReplicaset {
+	rs := &FakeReplicaset{
+		UUID:        uuid,
+		Alias:       alias,
+		Roles:       roles,
+		VshardGroup: vshardGroup,
+	}
+	if isStorage(rs.Roles) {
+		weight := 1.0
+		if f.bootstrapped {
+			weight = 0
+		}
+		rs.Weight = &weight
+	}
+	f.replicasets[uuid] = rs
+
+	return rs
+}
+
+func (f *FakeCartridge) addServer(rs *FakeReplicaset, uuid, uri string) {
+	rs.Servers = append(rs.Servers, uuid)
+	f.servers[uuid] = &FakeServer{
+		UUID:           uuid,
+		URI:            uri,
+		Alias:          strings.Split(uri, ".")[0],
+		Status:         "healthy",
+		ReplicasetUUID: rs.UUID,
+	}
+}
+
+func (f *FakeCartridge) expelServer(vars map[string]interface{}) (interface{}, error) {
+	uuid, _ := vars["uuid"].(string)
+	if err := f.checkExpel(uuid); err != nil {
+		return nil, err
+	}
+	f.expel(uuid)
+
+	return map[string]interface{}{"expelServerResponse": true}, nil
+}
+
+// editTopology checks the whole patch first and applies it only if all of its changes are valid
+func (f *FakeCartridge) editTopology(vars map[string]interface{}) (interface{}, error) {
+	replicasets := objectList(vars["replicasets"])
+	servers := objectList(vars["servers"])
+
+	joining := make(map[string]bool)
+	for _, rs := range replicasets {
+		uuid, _ := rs["uuid"].(string)
+		existing, exists := f.replicasets[uuid]
+
+		joinServers := objectList(rs["join_servers"])
+		if !exists && len(joinServers) == 0 {
+			return nil, fmt.Errorf("Replicaset \"%s\" not in config", uuid)
+		}
+		for _, server := range joinServers {
+			serverUUID, _ := server["uuid"].(string)
+			if joining[serverUUID] {
+				return nil, fmt.Errorf("Server \"%s\" is already joined", serverUUID)
+			}
+			if err := f.checkJoin(serverUUID); err != nil {
+				return nil, err
+			}
+			joining[serverUUID] = true
+		}
+
+		if _, ok := rs["weight"]; ok {
+			roles := stringList(rs["roles"])
+			if _, ok := rs["roles"]; !ok && exists {
+				roles = existing.Roles
+			}
+			if !isStorage(roles) {
+				return nil, fmt.Errorf("replicaset \"%s\" has no vshard-storage role", uuid)
+			}
+		}
+	}
+
+	for _, server := range servers {
+		uuid, _ := server["uuid"].(string)
+		if expelled, _ := server["expelled"].(bool); !expelled {
+			if _, ok := f.servers[uuid]; !ok {
+				return nil, fmt.Errorf("Server \"%s\" not in config", uuid)
+			}
+			continue
+		}
+		if err := f.checkExpel(uuid); err != nil {
+			return nil, err
+		}
+	}
+
+	edited := []map[string]interface{}{}
+	for _, item := range replicasets {
+		uuid, _ := item["uuid"].(string)
+		rs, ok := f.replicasets[uuid]
+		if !ok {
+			alias, _ := item["alias"].(string)
+			vshardGroup, _ := item["vshard_group"].(string)
+			rs = f.addReplicaset(uuid, alias, stringList(item["roles"]), vshardGroup)
+		} else if _, ok := item["roles"]; ok {
+			rs.Roles = stringList(item["roles"])
+		}
+
+		if weight, ok := item["weight"].(float64); ok {
+			rs.Weight = &weight
+		}
+
+		for _, server := range objectList(item["join_servers"]) {
+			serverUUID, _ := server["uuid"].(string)
+			uri, _ := server["uri"].(string)
+			f.addServer(rs, serverUUID, uri)
+			f.placeServer(f.servers[serverUUID], server)
+			edited = append(edited, map[string]interface{}{"uuid": serverUUID})
+		}
+	}
+
+	for _, server := range servers {
+		uuid, _ := server["uuid"].(string)
+		if expelled, _ := server["expelled"].(bool); expelled {
+			f.expel(uuid)
+		} else {
+			f.placeServer(f.servers[uuid], server)
+		}
+		edited = append(edited, map[string]interface{}{"uuid": uuid})
+	}
+	f.rebalance()
+
+	return map[string]interface{}{"cluster": map[string]interface{}{"editTopologyResponse": map[string]interface{}{"servers": edited}}}, nil
+}
+
+// placeServer applies the zone and the labels of a join or an edit, the labels replace the existing ones
+func (f *FakeCartridge) placeServer(server *FakeServer, input map[string]interface{}) {
+	if zone, ok := input["zone"].(string); ok {
+		server.Zone = zone
+	}
+	if _, ok := input["labels"]; ok {
+		server.Labels = make(map[string]string)
+		for _, label := range objectList(input["labels"]) {
+			name, _ := label["name"].(string)
+			value, _ := label["value"].(string)
+			server.Labels[name] = value
+		}
+	}
+}
+
+func (f *FakeCartridge) checkJoin(uuid string) error {
+	if _, ok := f.servers[uuid]; ok {
+		return fmt.Errorf("Server \"%s\" is already joined", uuid)
+	}
+	if f.expelled[uuid] {
+		return fmt.Errorf("Server \"%s\" is expelled and can't be joined again", uuid)
+	}
+
+	return nil
+}
+
+func (f *FakeCartridge) checkExpel(uuid string) error {
+	if uuid == "" {
+		return fmt.Errorf("uuid is required")
+	}
+
+	server, ok := f.servers[uuid]
+	if !ok {
+		return fmt.Errorf("Server \"%s\" not in config", uuid)
+	}
+	rs := f.replicasets[server.ReplicasetUUID]
+	if len(rs.Servers) == 1 && server.BucketsCount > 0 {
+		return fmt.Errorf("Server \"%s\" still stores %d buckets", server.UUID, server.BucketsCount)
+	}
+
+	if len(rs.Servers) > 1 && f.activeMaster(rs) == uuid {
+		return fmt.Errorf("Server \"%s\" is the leader and can't be expelled", uuid)
+	}
+
+	return nil
+}
+
+func (f *FakeCartridge) expel(uuid string) {
+	server := f.servers[uuid]
+	rs := f.replicasets[server.ReplicasetUUID]
+	rs.Servers = remove(rs.Servers, server.UUID)
+	if len(rs.Servers) == 0 {
+		delete(f.replicasets, rs.UUID)
+	}
+	if f.leaders[rs.UUID] == server.UUID {
+		delete(f.leaders, rs.UUID)
+	}
+	delete(f.servers, server.UUID)
+	f.expelled[server.UUID] = true
+}
+
+func (f *FakeCartridge) editReplicaset(vars map[string]interface{}) (interface{}, error) {
+	uuid, _ := vars["uuid"].(string)
+	rs, ok := f.replicasets[uuid]
+	if !ok {
+		return nil, fmt.Errorf("Replicaset \"%s\" not in config", uuid)
+	}
+
+	if roles, ok := vars["roles"]; ok {
+		rs.Roles = stringList(roles)
+	}
+
+	if weight, ok := vars["weight"].(float64); ok {
+		if !isStorage(rs.Roles) {
+			return nil, fmt.Errorf("replicaset \"%s\" has no vshard-storage role", uuid)
+		}
+		rs.Weight = &weight
+		f.rebalance()
+	}
+
+	if priority, ok := vars["failover_priority"]; ok {
+		ordered := stringList(priority)
+		for _, instanceUUID := range ordered {
+			if _, ok := f.servers[instanceUUID]; !ok {
+				return nil, fmt.Errorf("Server \"%s\" not in config", instanceUUID)
+			}
+		}
+		for _, instanceUUID := range rs.Servers {
+			ordered = appendUnique(ordered, instanceUUID)
+		}
+		rs.Servers = ordered
+		f.rebalance()
+	}
+
+	return map[string]interface{}{"editReplicasetResponse": true}, nil
+}
+
+func (f *FakeCartridge) bootstrapVshard() (interface{}, error) {
+	if f.bootstrapped {
+		return nil, fmt.Errorf("Sharding config is already bootstrapped")
+	}
+	if len(f.storages()) == 0 {
+		return nil, fmt.Errorf("Sharding config is empty")
+	}
+
+	f.bootstrapped = true
+	f.rebalance()
+
+	return map[string]interface{}{"bootstrapVshardResponse": true}, nil
+}
+
+func (f *FakeCartridge) failover(query string, vars map[string]interface{}) (interface{}, error) {
+	if strings.HasPrefix(strings.TrimSpace(query), "mutation") {
+		// variables left out keep their values, like in Cartridge
+		params := topology.FailoverParams{}
+		for _, v := range []interface{}{f.failoverParams, vars} {
+			body, err := json.Marshal(v)
+			if err != nil {
+				return nil, err
+			}
+			if err := json.Unmarshal(body, &params); err != nil {
+				return nil, err
+			}
+		}
+
+		switch params.Mode {
+		case topology.FailoverModeDisabled, topology.FailoverModeEventual:
+		case topology.FailoverModeStateful:
+			switch {
+			case params.StateProvider == topology.StateProviderTarantool && params.TarantoolParams == nil,
+				params.StateProvider == topology.StateProviderEtcd2 && params.Etcd2Params == nil:
+				return nil, fmt.Errorf("%s params are missing", params.StateProvider)
+			case params.StateProvider != topology.StateProviderTarantool && params.StateProvider != topology.StateProviderEtcd2:
+				return nil, fmt.Errorf("unknown state_provider %q", params.StateProvider)
+			}
+		default:
+			return nil, fmt.Errorf("unknown failover mode %q", params.Mode)
+		}
+		f.failoverParams = params
+	}
+
+	return map[string]interface{}{"cluster": map[string]interface{}{"failover_params": f.failoverParams}}, nil
+}
+
+func (f *FakeCartridge) serverStat() interface{} {
+	stats := []map[string]interface{}{}
+	for _, uuid := range f.serverUUIDs() {
+		server := f.servers[uuid]
+		stats = append(stats, map[string]interface{}{
+			"uuid": server.UUID,
+			"uri":  server.URI,
+			"statistics": map[string]interface{}{
+				"bucketsCount": server.BucketsCount,
+			},
+		})
+	}
+
+	return map[string]interface{}{"serverStat": stats}
+}
+
+func (f *FakeCartridge) queryReplicasets(vars map[string]interface{}) interface{} {
+	uuid, _ := vars["uuid"].(string)
+
+	replicasets := []map[string]interface{}{}
+	for _, rsUUID := range f.replicasetUUIDs() {
+		if uuid != "" && uuid != rsUUID {
+			continue
+		}
+
+		rs := f.replicasets[rsUUID]
+		servers := []map[string]interface{}{}
+		for _, serverUUID := range rs.Servers {
+			server := f.servers[serverUUID]
+			servers = append(servers, map[string]interface{}{
+				"uuid":   server.UUID,
+				"uri":    server.URI,
+				"alias":  server.Alias,
+				"status": server.Status,
+				"zone":   server.Zone,
+				"labels": serverLabels(server),
+			})
+		}
+
+		var weight interface{}
+		if rs.Weight != nil {
+			weight = *rs.Weight
+		}
+
+		replicasets = append(replicasets, map[string]interface{}{
+			"uuid":          rs.UUID,
+			"alias":         rs.Alias,
+			"roles":         rs.Roles,
+			"weight":        weight,
+			"status":        f.replicasetStatus(rs),
+			"servers":       servers,
+			"master":        map[string]interface{}{"uuid": rs.Servers[0]},
+			"active_master": map[string]interface{}{"uuid": f.activeMaster(rs)},
+		})
+	}
+
+	return map[string]interface{}{"replicasets": replicasets}
+}
+
+// self reports the first joined server as the one answering, the instance is unconfigured until something joins
+func (f *FakeCartridge) self() interface{} {
+	self := map[string]interface{}{"uri": f.server.Listener.Addr().String(), "uuid": nil, "alias": ""}
+	if uuids := f.serverUUIDs(); len(uuids) > 0 {
+		server := f.servers[uuids[0]]
+		self = map[string]interface{}{"uri": server.URI, "uuid": server.UUID, "alias": server.Alias}
+	}
+
+	return map[string]interface{}{"cluster": map[string]interface{}{"self": self}}
+}
+
+func (f *FakeCartridge) replicasetStatus(rs *FakeReplicaset) string {
+	for _, uuid := range rs.Servers {
+		if f.servers[uuid].Status != "healthy" {
+			return "unhealthy"
+		}
+	}
+
+	return "healthy"
+}
+
+func (f *FakeCartridge) promote(vars map[string]interface{}) (interface{}, error) {
+	replicasetUUID, _ := vars["replicaset_uuid"].(string)
+	instanceUUID, _ := vars["instance_uuid"].(string)
+
+	if f.failoverParams.Mode != topology.FailoverModeStateful {
+		return nil, fmt.Errorf("Promotion only works with stateful failover")
+	}
+	server, ok := f.servers[instanceUUID]
+	if !ok || server.ReplicasetUUID != replicasetUUID {
+		return nil, fmt.Errorf("Server %q is not in replicaset %q", instanceUUID, replicasetUUID)
+	}
+	if server.Status != "healthy" {
+		return nil, fmt.Errorf("Server %q is not healthy", instanceUUID)
+	}
+
+	f.leaders[replicasetUUID] = instanceUUID
+
+	return map[string]interface{}{"cluster": map[string]interface{}{"failover_promote": true}}, nil
+}
+
+func (f *FakeCartridge) activeMaster(rs *FakeReplicaset) string {
+	if leader, ok := f.leaders[rs.UUID]; ok && f.failoverParams.Mode == topology.FailoverModeStateful && f.servers[leader].Status == "healthy" {
+		return leader
+	}
+	if f.failoverParams.Mode != topology.FailoverModeDisabled {
+		for _, uuid := range rs.Servers {
+			if f.servers[uuid].Status == "healthy" {
+				return uuid
+			}
+		}
+	}
+
+	return rs.Servers[0]
+}
+
+// rebalance moves buckets instantly so that every storage holds a share proportional to its weight
+func (f *FakeCartridge) rebalance() {
+	for _, server := range f.servers {
+		server.BucketsCount = 0
+	}
+	if !f.bootstrapped {
+		return
+	}
+
+	storages := f.storages()
+	totalWeight := 0.0
+	for _, rs := range storages {
+		totalWeight += *rs.Weight
+	}
+	if totalWeight == 0 {
+		return
+	}
+
+	left := f.bucketCount
+	for i, rs := range storages {
+		buckets := int(float64(f.bucketCount) * *rs.Weight / totalWeight)
+		if i == len(storages)-1 {
+			buckets = left
+		}
+		left -= buckets
+		// replicas report the buckets of their master
+		for _, uuid := range rs.Servers {
+			f.servers[uuid].BucketsCount = buckets
+		}
+	}
+}
+
+func (f *FakeCartridge) storages() []*FakeReplicaset {
+	storages := []*FakeReplicaset{}
+	for _, uuid := range f.replicasetUUIDs() {
+		rs := f.replicasets[uuid]
+		if rs.Weight != nil && *rs.Weight > 0 {
+			storages = append(storages, rs)
+		}
+	}
+
+	return storages
+}
+
+func (f *FakeCartridge) replicasetUUIDs() []string {
+	uuids := []string{}
+	for uuid := range f.replicasets {
+		uuids = append(uuids, uuid)
+	}
+	sort.Strings(uuids)
+
+	return uuids
+}
+
+func (f *FakeCartridge) serverUUIDs() []string {
+	uuids := []string{}
+	for uuid := range f.servers {
+		uuids = append(uuids, uuid)
+	}
+	sort.Strings(uuids)
+
+	return uuids
+}
+
+func isStorage(roles []string) bool {
+	for _, role := range roles {
+		if role == "vshard-storage" {
+			return true
+		}
+	}
+
+	return false
+}
+
+func stringList(v interface{}) []string {
+	items, _ := v.([]interface{})
+	list := []string{}
+	for _, item := range items {
+		if s, ok := item.(string); ok {
+			list = append(list, s)
+		}
+	}
+
+	return list
+}
+
+func objectList(v interface{}) []map[string]interface{} {
+	items, _ := v.([]interface{})
+	list := []map[string]interface{}{}
+	for _, item := range items {
+		if m, ok := item.(map[string]interface{}); ok {
+			list = append(list, m)
+		}
+	}
+
+	return list
+}
+
+func appendUnique(list []string, item string) []string {
+	for _, v := range list {
+		if v == item {
+			return list
+		}
+	}
+
+	return append(list, item)
+}
+
+func remove(list []string, item string) []string {
+	result := []string{}
+	for _, v := range list {
+		if v != item {
+			result = append(result, v)
+		}
+	}
+
+	return result
+}
+
+func serverLabels(server *FakeServer) []map[string]interface{} {
+	names := make([]string, 0, len(server.Labels))
+	for name := range server.Labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	labels := []map[string]interface{}{}
+	for _, name := range names {
+		labels = append(labels, map[string]interface{}{"name": name, "value": server.Labels[name]})
+	}
+
+	return labels
+}